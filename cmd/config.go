@@ -0,0 +1,64 @@
+package main
+
+import (
+	"fmt"
+	"golang-boilerplate/main/config"
+	"os"
+
+	"go.uber.org/zap"
+	"gopkg.in/yaml.v3"
+)
+
+var configCommands = []command{
+	{"print", "config print [-redacted]", "Print the effective configuration as YAML", configPrint},
+	{"validate", "config validate", "Check the configuration without connecting to anything", configValidate},
+}
+
+func configCommand(args []string) error {
+	return dispatch("config", configCommands, args)
+}
+
+func configPrint(args []string) error {
+	fs := newFlags("config print", "config print [-redacted]")
+	redact := fs.Bool("redacted", false, "hide secrets and the passwords in URLs")
+	fs.Parse(args)
+	if fs.NArg() > 0 {
+		usageError(fs, "unexpected arguments %v", fs.Args())
+	}
+
+	if _, err := config.Load(); err != nil {
+		return fmt.Errorf("could not load config: %w", err)
+	}
+	settings := config.Settings()
+	if *redact {
+		settings = config.Redact(settings)
+	}
+
+	enc := yaml.NewEncoder(os.Stdout)
+	enc.SetIndent(2)
+	if err := enc.Encode(settings); err != nil {
+		return fmt.Errorf("could not print config: %w", err)
+	}
+	return enc.Close()
+}
+
+func configValidate(args []string) error {
+	fs := newFlags("config validate", "config validate")
+	fs.Parse(args)
+	if fs.NArg() > 0 {
+		usageError(fs, "unexpected arguments %v", fs.Args())
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("could not load config: %w", err)
+	}
+	if err := cfg.Validate(); err != nil {
+		return fmt.Errorf("invalid configuration:\n%w", err)
+	}
+	if _, err := newRegistry(cfg, nil, zap.NewNop()); err != nil {
+		return fmt.Errorf("invalid configuration:\n%w", err)
+	}
+	fmt.Println("Configuration is valid")
+	return nil
+}
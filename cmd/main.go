@@ -1,44 +1,108 @@
 package main
 
 import (
+	"flag"
+	"fmt"
+	"golang-boilerplate/main/app"
 	"golang-boilerplate/main/config"
-	"golang-boilerplate/main/server"
-	"golang-boilerplate/main/service"
+	"golang-boilerplate/main/module"
+	"golang-boilerplate/main/modules"
 	"log"
 	"os"
-	"os/signal"
-	"syscall"
+	"path/filepath"
+	"strings"
+
+	"go.uber.org/zap"
 )
 
+// command is a subcommand of the CLI, run with the arguments after its name
+type command struct {
+	name    string
+	usage   string
+	summary string
+	run     func(args []string) error
+}
+
+var commands = []command{
+	{"serve", "serve [-migrate]", "Run the HTTP and gRPC servers", serve},
+	{"migrate", "migrate up|down|status|force|create", "Apply, roll back or create database migrations", migrateCommand},
+	{"user", "user create|reset-password", "Manage user accounts", userCommand},
+	{"config", "config print|validate", "Show or check the configuration", configCommand},
+}
+
 func main() {
-	// Load configuration
-	if err := config.LoadConfig(); err != nil {
-		log.Fatalf("Failed to load config: %v", err)
+	log.SetFlags(0)
+	if err := dispatch("", commands, os.Args[1:]); err != nil {
+		log.Fatal(err)
 	}
+}
 
-	// Initialize all services
-	if err := service.InitServices(); err != nil {
-		log.Fatalf("Failed to initialize services: %v", err)
+// dispatch runs the command of cmds named by the first argument. parent is
+// the command the cmds belong to, if any.
+func dispatch(parent string, cmds []command, args []string) error {
+	if len(args) == 0 || args[0] == "help" || args[0] == "-h" || args[0] == "--help" {
+		printUsage(parent, cmds)
+		if len(args) == 0 {
+			os.Exit(2)
+		}
+		return nil
 	}
-	defer service.CloseServices()
-
-	// Create and start server
-	srv := server.NewServer()
-	go func() {
-		if err := srv.Start(); err != nil {
-			log.Fatalf("Failed to start server: %v", err)
+	for _, cmd := range cmds {
+		if cmd.name == args[0] {
+			return cmd.run(args[1:])
 		}
-	}()
+	}
+	fmt.Fprintf(os.Stderr, "unknown command %q\n\n", strings.TrimSpace(parent+" "+args[0]))
+	printUsage(parent, cmds)
+	os.Exit(2)
+	return nil
+}
 
-	// Wait for interrupt signal to gracefully shutdown the server
-	quit := make(chan os.Signal, 1)
-	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
-	<-quit
-	log.Println("Shutting down server...")
+func printUsage(parent string, cmds []command) {
+	fmt.Fprintf(os.Stderr, "usage: %s <command> [arguments]\n\nCommands:\n", strings.TrimSpace(program()+" "+parent))
+	for _, cmd := range cmds {
+		fmt.Fprintf(os.Stderr, "  %-40s %s\n", cmd.usage, cmd.summary)
+	}
+}
+
+func program() string {
+	return filepath.Base(os.Args[0])
+}
 
-	if err := srv.Shutdown(); err != nil {
-		log.Fatalf("Server forced to shutdown: %v", err)
+// newFlags returns the flag set of a command, whose usage line is usage
+func newFlags(name, usage string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "usage: %s %s\n", program(), usage)
+		fs.PrintDefaults()
 	}
+	return fs
+}
+
+// usageError reports wrong arguments the way the flag package reports
+// wrong flags
+func usageError(fs *flag.FlagSet, format string, args ...interface{}) {
+	fmt.Fprintf(fs.Output(), format+"\n", args...)
+	fs.Usage()
+	os.Exit(2)
+}
 
-	log.Println("Server exited")
+// newRegistry composes the feature areas, minus those disabled in
+// modules.disabled. The outbox comes after webhooks, whose dispatcher it
+// feeds. Commands that only need the modules' names and migrations may pass
+// a nil app.
+func newRegistry(cfg *config.Config, a *app.App, logger *zap.Logger) (*module.Registry, error) {
+	webhooks := modules.NewWebhooks(a)
+	mods, err := module.NewRegistry(cfg.Modules, logger,
+		modules.NewAuth(),
+		modules.NewUsers(a),
+		webhooks,
+		modules.NewFlags(a),
+		modules.NewRealtime(a),
+		modules.NewOutbox(a, webhooks),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("could not load modules: %w", err)
+	}
+	return mods, nil
 }
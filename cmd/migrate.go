@@ -0,0 +1,221 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"golang-boilerplate/main/config"
+	"golang-boilerplate/main/module"
+	"golang-boilerplate/main/service"
+	"strconv"
+
+	"go.uber.org/zap"
+)
+
+var migrateCommands = []command{
+	{"up", "migrate up", "Apply the pending core and module migrations", migrateUpCommand},
+	{"down", "migrate down [-steps n] [-module name]", "Roll back the last migrations", migrateDown},
+	{"status", "migrate status", "List the migrations and whether they have run", migrateStatus},
+	{"force", "migrate force [-module name] <version>", "Record a version as current, after fixing a failed migration by hand", migrateForce},
+	{"create", "migrate create <name>", "Write empty up and down files for a new migration", migrateCreate},
+}
+
+func migrateCommand(args []string) error {
+	return dispatch("migrate", migrateCommands, args)
+}
+
+func migrateUpCommand(args []string) error {
+	fs := newFlags("migrate up", "migrate up")
+	fs.Parse(args)
+	if fs.NArg() > 0 {
+		usageError(fs, "unexpected arguments %v", fs.Args())
+	}
+
+	db, mods, err := openMigrations()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	if err := migrateUp(db, mods); err != nil {
+		return err
+	}
+	fmt.Println("Migrations applied")
+	return nil
+}
+
+// migrateUp applies the core migrations, then those of each module
+func migrateUp(db *sql.DB, mods *module.Registry) error {
+	if err := service.RunMigrations(db, service.MigrationsDir); err != nil {
+		return err
+	}
+	if err := mods.Migrate(db); err != nil {
+		return fmt.Errorf("could not run module migrations: %w", err)
+	}
+	return nil
+}
+
+func migrateDown(args []string) error {
+	fs := newFlags("migrate down", "migrate down [-steps n] [-module name]")
+	steps := fs.Int("steps", 1, "number of migrations to roll back")
+	moduleName := fs.String("module", "", "roll back the migrations of this module instead of the core ones")
+	fs.Parse(args)
+	if fs.NArg() > 0 {
+		usageError(fs, "unexpected arguments %v", fs.Args())
+	}
+	if *steps < 1 {
+		usageError(fs, "-steps must be at least 1")
+	}
+
+	db, mods, err := openMigrations()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	m, err := migrator(db, mods, *moduleName)
+	if err != nil {
+		return err
+	}
+	if err := m.Down(*steps); err != nil {
+		return err
+	}
+	return printStatus(migrationSet(*moduleName), m)
+}
+
+func migrateStatus(args []string) error {
+	fs := newFlags("migrate status", "migrate status")
+	fs.Parse(args)
+	if fs.NArg() > 0 {
+		usageError(fs, "unexpected arguments %v", fs.Args())
+	}
+
+	db, mods, err := openMigrations()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	m, err := migrator(db, mods, "")
+	if err != nil {
+		return err
+	}
+	if err := printStatus(migrationSet(""), m); err != nil {
+		return err
+	}
+	for _, mod := range mods.Migrated() {
+		m, err := migrator(db, mods, mod.Name())
+		if err != nil {
+			return err
+		}
+		if err := printStatus(migrationSet(mod.Name()), m); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func migrateForce(args []string) error {
+	fs := newFlags("migrate force", "migrate force [-module name] <version>")
+	moduleName := fs.String("module", "", "force the version of this module's migrations instead of the core ones")
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		usageError(fs, "expected one version, -1 for none")
+	}
+	version, err := strconv.Atoi(fs.Arg(0))
+	if err != nil || version < -1 {
+		usageError(fs, "invalid version %q", fs.Arg(0))
+	}
+
+	db, mods, err := openMigrations()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	m, err := migrator(db, mods, *moduleName)
+	if err != nil {
+		return err
+	}
+	if err := m.Force(version); err != nil {
+		return err
+	}
+	return printStatus(migrationSet(*moduleName), m)
+}
+
+func migrateCreate(args []string) error {
+	fs := newFlags("migrate create", "migrate create <name>")
+	fs.Parse(args)
+	if fs.NArg() == 0 {
+		usageError(fs, "expected a name such as add_phone_to_users")
+	}
+
+	up, down, err := service.CreateMigration(service.MigrationsDir, fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	fmt.Println("created", up)
+	fmt.Println("created", down)
+	return nil
+}
+
+// openMigrations connects to the database and lists the modules, whose
+// migrations are managed next to the core ones
+func openMigrations() (*sql.DB, *module.Registry, error) {
+	cfg, err := config.Load()
+	if err != nil {
+		return nil, nil, fmt.Errorf("could not load config: %w", err)
+	}
+	mods, err := newRegistry(cfg, nil, zap.NewNop())
+	if err != nil {
+		return nil, nil, err
+	}
+	db, err := service.OpenPostgres(cfg.Database)
+	if err != nil {
+		return nil, nil, fmt.Errorf("could not connect to database: %w", err)
+	}
+	return db, mods, nil
+}
+
+// migrator manages the core migrations, or those of the named module
+func migrator(db *sql.DB, mods *module.Registry, moduleName string) (*service.Migrator, error) {
+	if moduleName == "" {
+		return service.NewMigrator(db, service.MigrationsDir)
+	}
+	for _, mod := range mods.Migrated() {
+		if mod.Name() == moduleName {
+			return service.NewModuleMigrator(db, moduleName, mod.Migrations())
+		}
+	}
+	return nil, fmt.Errorf("module %q is unknown or has no migrations", moduleName)
+}
+
+func migrationSet(moduleName string) string {
+	if moduleName == "" {
+		return "core"
+	}
+	return "module " + moduleName
+}
+
+func printStatus(set string, m *service.Migrator) error {
+	status, err := m.Status()
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case status.Dirty:
+		fmt.Printf("%s: version %06d is dirty; fix the schema by hand, then force the version it is at\n", set, status.Version)
+	case status.Version == 0:
+		fmt.Printf("%s: no migrations applied\n", set)
+	default:
+		fmt.Printf("%s: at version %06d\n", set, status.Version)
+	}
+	for _, migration := range status.Migrations {
+		state := "pending"
+		if migration.Applied {
+			state = "applied"
+		}
+		fmt.Printf("  %-8s %06d %s\n", state, migration.Version, migration.Name)
+	}
+	return nil
+}
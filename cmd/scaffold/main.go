@@ -0,0 +1,277 @@
+// Command scaffold generates a CRUD resource: its model, repository,
+// handlers, a module with its own paired migrations and an integration test
+// stub, and adds the repository to handlers.Deps. It never overwrites a
+// file; if any would be, nothing is written.
+//
+// Usage:
+//
+//	go run ./cmd/scaffold [-dir .] [-plural name] <resource> <field:type>...
+//
+// For example:
+//
+//	go run ./cmd/scaffold blog_post title:string body:text published:bool
+package main
+
+import (
+	"bytes"
+	"embed"
+	"errors"
+	"flag"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+)
+
+//go:embed templates
+var templateFS embed.FS
+
+var templates = template.Must(template.New("").ParseFS(templateFS, "templates/*.tmpl"))
+
+// output is a file to generate from a template
+type output struct {
+	path     string
+	template string
+}
+
+func main() {
+	log.SetFlags(0)
+
+	dir := flag.String("dir", ".", "root of the repository")
+	plural := flag.String("plural", "", "plural of the resource, when adding s or es gets it wrong")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: scaffold [flags] <resource> <field:type>...\n\n")
+		fmt.Fprintf(flag.CommandLine.Output(), "Names are snake_case. Field types: %s.\n\n", fieldTypeNames)
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() < 2 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	if err := run(*dir, flag.Arg(0), *plural, flag.Args()[1:]); err != nil {
+		log.Fatalf("scaffold: %v", err)
+	}
+}
+
+func run(dir, name, plural string, specs []string) error {
+	r, err := newResource(name, plural, specs)
+	if err != nil {
+		return err
+	}
+
+	// The module's migrations are its own, so they start at the first version
+	migrations := filepath.Join("main", "modules", "migrations", r.Table)
+	outputs := []output{
+		{filepath.Join("main", "models", r.Name+".go"), "model.go.tmpl"},
+		{filepath.Join("main", "repo", r.Table+".go"), "repo.go.tmpl"},
+		{filepath.Join("main", "handlers", r.Table+".go"), "handler.go.tmpl"},
+		{filepath.Join("main", "modules", r.Table+".go"), "module.go.tmpl"},
+		{filepath.Join(migrations, "000001_create_"+r.Table+".up.sql"), "up.sql.tmpl"},
+		{filepath.Join(migrations, "000001_create_"+r.Table+".down.sql"), "down.sql.tmpl"},
+		{filepath.Join("tests", r.Table+"_test.go"), "test.go.tmpl"},
+	}
+
+	// Everything is checked and rendered before the first file is written,
+	// so a refusal leaves the tree as it was
+	var existing []string
+	for _, out := range outputs {
+		if _, err := os.Stat(filepath.Join(dir, out.path)); err == nil {
+			existing = append(existing, out.path)
+		} else if !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+	}
+	if len(existing) > 0 {
+		return fmt.Errorf("refusing to overwrite %s", strings.Join(existing, ", "))
+	}
+
+	contents := make([][]byte, len(outputs))
+	for i, out := range outputs {
+		if contents[i], err = render(out, r); err != nil {
+			return err
+		}
+		if strings.HasSuffix(out.path, ".go") {
+			if err := checkClashes(filepath.Join(dir, out.path), contents[i]); err != nil {
+				return err
+			}
+		}
+	}
+	depsPath := filepath.Join(dir, "main", "handlers", "handlers.go")
+	deps, err := addDep(depsPath, r)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Join(dir, migrations), 0o755); err != nil {
+		return err
+	}
+	for i, out := range outputs {
+		f, err := os.OpenFile(filepath.Join(dir, out.path), os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+		if err != nil {
+			return err
+		}
+		_, err = f.Write(contents[i])
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			return err
+		}
+		fmt.Println("created", out.path)
+	}
+	if err := os.WriteFile(depsPath, deps, 0o644); err != nil {
+		return err
+	}
+	fmt.Println("updated", filepath.Join("main", "handlers", "handlers.go"))
+
+	fmt.Printf(`
+Next steps:
+  1. Register modules.New%[1]s(a) in cmd/main.go and in newRegistry in
+     tests/harness_test.go
+  2. Run go run ./cmd migrate up to apply the module's migrations, creating
+     the %[2]s table
+  3. Fill in the test stub in tests/%[2]s_test.go
+`, r.PluralType, r.Table)
+	return nil
+}
+
+// addDep returns the source of handlers.go at path with a field for the
+// resource's repository added to Deps, which the module sets
+func addDep(path string, r *Resource) ([]byte, error) {
+	src, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, path, src, parser.ParseComments)
+	if err != nil {
+		return nil, err
+	}
+
+	var deps *ast.StructType
+	ast.Inspect(file, func(n ast.Node) bool {
+		if spec, ok := n.(*ast.TypeSpec); ok && spec.Name.Name == "Deps" {
+			deps, _ = spec.Type.(*ast.StructType)
+		}
+		return deps == nil
+	})
+	if deps == nil {
+		return nil, fmt.Errorf("%s declares no Deps struct", path)
+	}
+	for _, field := range deps.Fields.List {
+		for _, name := range field.Names {
+			if name.Name == r.PluralType {
+				return nil, fmt.Errorf("handlers.Deps has a %s field already", r.PluralType)
+			}
+		}
+	}
+
+	closing := fset.Position(deps.Fields.Closing).Offset
+	field := fmt.Sprintf("\t%s *repo.%sRepo\n", r.PluralType, r.Type)
+	updated := append(append(append([]byte{}, src[:closing]...), field...), src[closing:]...)
+	return format.Source(updated)
+}
+
+// render executes the template of out, formatting Go source
+func render(out output, r *Resource) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := templates.ExecuteTemplate(&buf, out.template, r); err != nil {
+		return nil, fmt.Errorf("could not render %s: %w", out.path, err)
+	}
+	if !strings.HasSuffix(out.path, ".go") {
+		return buf.Bytes(), nil
+	}
+	src, err := format.Source(buf.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("could not format %s: %w", out.path, err)
+	}
+	return src, nil
+}
+
+// checkClashes fails if src, to be written to path, declares a name its
+// package already declares, e.g. a resource that exists under another plural
+func checkClashes(path string, src []byte) error {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, path, src, 0)
+	if err != nil {
+		return err
+	}
+	generated := declarations(file)
+
+	pkgDir := filepath.Dir(path)
+	entries, err := os.ReadDir(pkgDir)
+	if err != nil {
+		return fmt.Errorf("could not read package %s: %w", pkgDir, err)
+	}
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".go") {
+			continue
+		}
+		existing, err := parser.ParseFile(fset, filepath.Join(pkgDir, entry.Name()), nil, parser.SkipObjectResolution)
+		if err != nil {
+			return err
+		}
+		if existing.Name.Name != file.Name.Name {
+			continue
+		}
+		for name := range declarations(existing) {
+			if generated[name] {
+				return fmt.Errorf("%s would declare %s, which %s declares already", path, name, filepath.Join(pkgDir, entry.Name()))
+			}
+		}
+	}
+	return nil
+}
+
+// declarations returns the names a file declares at package level
+func declarations(file *ast.File) map[string]bool {
+	names := make(map[string]bool)
+	for _, decl := range file.Decls {
+		switch decl := decl.(type) {
+		case *ast.FuncDecl:
+			switch {
+			case decl.Recv != nil:
+				// Methods clash with those of the same type
+				names[receiverType(decl)+"."+decl.Name.Name] = true
+			case decl.Name.Name != "init":
+				names[decl.Name.Name] = true
+			}
+		case *ast.GenDecl:
+			for _, spec := range decl.Specs {
+				switch spec := spec.(type) {
+				case *ast.TypeSpec:
+					names[spec.Name.Name] = true
+				case *ast.ValueSpec:
+					for _, name := range spec.Names {
+						if name.Name != "_" {
+							names[name.Name] = true
+						}
+					}
+				}
+			}
+		}
+	}
+	return names
+}
+
+// receiverType names the type a method is declared on
+func receiverType(decl *ast.FuncDecl) string {
+	typ := decl.Recv.List[0].Type
+	if star, ok := typ.(*ast.StarExpr); ok {
+		typ = star.X
+	}
+	if ident, ok := typ.(*ast.Ident); ok {
+		return ident.Name
+	}
+	return ""
+}
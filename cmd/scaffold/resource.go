@@ -0,0 +1,242 @@
+package main
+
+import (
+	"fmt"
+	"go/token"
+	"regexp"
+	"strings"
+)
+
+// identifier is the form of resource and field names: snake_case, which is
+// also how they appear in JSON, tables and columns
+var identifier = regexp.MustCompile(`^[a-z][a-z0-9_]*$`)
+
+// initialisms are written in capitals in Go names, as golint expects
+var initialisms = map[string]string{
+	"api": "API", "html": "HTML", "http": "HTTP", "id": "ID", "ip": "IP",
+	"json": "JSON", "sql": "SQL", "uri": "URI", "url": "URL", "uuid": "UUID",
+}
+
+// reservedVars may not be the variable names of a resource: the generated
+// code already uses them for packages, parameters and locals
+var reservedVars = map[string]bool{
+	"c": true, "conn": true, "context": true, "ctx": true, "done": true,
+	"err": true, "errors": true, "gin": true, "h": true, "http": true,
+	"i18n": true, "id": true, "log": true, "m": true, "models": true,
+	"now": true, "page": true, "params": true, "query": true, "r": true,
+	"repo": true, "req": true, "row": true, "rows": true, "sql": true,
+	"time": true,
+}
+
+// sqlReserved are the Postgres reserved words, which cannot name a table or
+// column without quoting
+var sqlReserved = map[string]bool{}
+
+func init() {
+	for _, word := range strings.Fields(`all analyse analyze and any array as asc
+		asymmetric both case cast check collate column constraint create
+		current_catalog current_date current_role current_time current_timestamp
+		current_user default deferrable desc distinct do else end except false
+		fetch for foreign from grant group having in initially intersect into
+		lateral leading limit localtime localtimestamp not null offset on only
+		or order placing primary references returning select session_user some
+		symmetric system_user table then to trailing true union unique user
+		using variadic when where window with`) {
+		sqlReserved[word] = true
+	}
+}
+
+// fieldType is how a field type given on the command line is declared in
+// Go, in the request body and in the migration
+type fieldType struct {
+	Type    string
+	Binding string
+	SQL     string
+	Sample  string // a valid value, as a Go expression
+}
+
+var fieldTypes = map[string]fieldType{
+	"string": {Type: "string", Binding: "required,max=255", SQL: "VARCHAR(255) NOT NULL", Sample: `"example"`},
+	"text":   {Type: "string", Binding: "required", SQL: "TEXT NOT NULL", Sample: `"example"`},
+	"int":    {Type: "int", SQL: "INTEGER NOT NULL DEFAULT 0", Sample: "1"},
+	"int64":  {Type: "int64", SQL: "BIGINT NOT NULL DEFAULT 0", Sample: "1"},
+	"float":  {Type: "float64", SQL: "DOUBLE PRECISION NOT NULL DEFAULT 0", Sample: "1.5"},
+	"bool":   {Type: "bool", SQL: "BOOLEAN NOT NULL DEFAULT FALSE", Sample: "true"},
+	"time":   {Type: "time.Time", Binding: "required", SQL: "TIMESTAMP NOT NULL", Sample: "time.Now().UTC().Truncate(time.Second)"},
+}
+
+// fieldTypeNames lists the field types for usage messages
+const fieldTypeNames = "string, text, int, int64, float, bool, time"
+
+// Resource is everything the templates need to know about the resource
+type Resource struct {
+	Name       string // blog_post
+	Table      string // blog_posts, also the module name
+	Path       string // blog-posts
+	Type       string // BlogPost
+	PluralType string // BlogPosts
+	Var        string // blogPost
+	PluralVar  string // blogPosts
+	Label      string // blog post
+	Labels     string // blog posts
+	Fields     []Field
+}
+
+// Field is a column of the resource besides id, version, created_at and
+// updated_at
+type Field struct {
+	Name string // published_at
+	Go   string // PublishedAt
+	fieldType
+}
+
+// HasTime reports whether a field is a time.Time, which the request body
+// and test stub then have to import
+func (r *Resource) HasTime() bool {
+	for _, f := range r.Fields {
+		if f.Type == "time.Time" {
+			return true
+		}
+	}
+	return false
+}
+
+// Columns lists the columns of the fields, in order
+func (r *Resource) Columns() string {
+	names := make([]string, len(r.Fields))
+	for i, f := range r.Fields {
+		names[i] = f.Name
+	}
+	return strings.Join(names, ", ")
+}
+
+// InsertParams lists the parameters of an insert: one per field, then
+// created_at and updated_at
+func (r *Resource) InsertParams() string {
+	params := make([]string, len(r.Fields)+2)
+	for i := range params {
+		params[i] = fmt.Sprintf("$%d", i+1)
+	}
+	return strings.Join(params, ", ")
+}
+
+// UpdateSet assigns each field and updated_at a parameter, leaving the next
+// one, IDParam, for the id
+func (r *Resource) UpdateSet() string {
+	sets := make([]string, len(r.Fields)+1)
+	for i, f := range r.Fields {
+		sets[i] = fmt.Sprintf("%s = $%d", f.Name, i+1)
+	}
+	sets[len(r.Fields)] = fmt.Sprintf("updated_at = $%d", len(r.Fields)+1)
+	return strings.Join(sets, ", ")
+}
+
+// IDParam is the parameter of the id in an update
+func (r *Resource) IDParam() string {
+	return fmt.Sprintf("$%d", len(r.Fields)+2)
+}
+
+// VersionParam is the parameter of the expected version in an update,
+// after IDParam
+func (r *Resource) VersionParam() string {
+	return fmt.Sprintf("$%d", len(r.Fields)+3)
+}
+
+// newResource validates the resource name, the plural (empty to derive it)
+// and the field specs, each name:type
+func newResource(name, plural string, specs []string) (*Resource, error) {
+	if !identifier.MatchString(name) {
+		return nil, fmt.Errorf("invalid resource name %q: must be snake_case", name)
+	}
+	if plural == "" {
+		plural = pluralize(name)
+	}
+	if !identifier.MatchString(plural) {
+		return nil, fmt.Errorf("invalid plural %q: must be snake_case", plural)
+	}
+	if plural == name {
+		return nil, fmt.Errorf("the plural of %q must differ from it, set it with -plural", name)
+	}
+	if sqlReserved[plural] {
+		return nil, fmt.Errorf("table name %q is reserved in SQL", plural)
+	}
+
+	r := &Resource{
+		Name:       name,
+		Table:      plural,
+		Path:       strings.ReplaceAll(plural, "_", "-"),
+		Type:       goName(name),
+		PluralType: goName(plural),
+		Var:        varName(name),
+		PluralVar:  varName(plural),
+		Label:      strings.ReplaceAll(name, "_", " "),
+		Labels:     strings.ReplaceAll(plural, "_", " "),
+	}
+	for _, v := range []string{r.Var, r.PluralVar} {
+		if token.IsKeyword(v) || reservedVars[v] {
+			return nil, fmt.Errorf("resource %q would be held in a variable named %q, which is reserved", name, v)
+		}
+	}
+
+	if len(specs) == 0 {
+		return nil, fmt.Errorf("no fields given")
+	}
+	seen := map[string]bool{"id": true, "version": true, "created_at": true, "updated_at": true}
+	for _, spec := range specs {
+		fieldName, typeName, ok := strings.Cut(spec, ":")
+		if !ok {
+			return nil, fmt.Errorf("invalid field %q: want name:type", spec)
+		}
+		if !identifier.MatchString(fieldName) {
+			return nil, fmt.Errorf("invalid field name %q: must be snake_case", fieldName)
+		}
+		if seen[fieldName] {
+			return nil, fmt.Errorf("field %q is given twice or generated already", fieldName)
+		}
+		if sqlReserved[fieldName] {
+			return nil, fmt.Errorf("field name %q is reserved in SQL", fieldName)
+		}
+		typ, ok := fieldTypes[typeName]
+		if !ok {
+			return nil, fmt.Errorf("unknown type %q of field %q: want one of %s", typeName, fieldName, fieldTypeNames)
+		}
+		seen[fieldName] = true
+		r.Fields = append(r.Fields, Field{Name: fieldName, Go: goName(fieldName), fieldType: typ})
+	}
+	return r, nil
+}
+
+// goName turns snake_case into an exported Go name
+func goName(name string) string {
+	var b strings.Builder
+	for _, word := range strings.Split(name, "_") {
+		if word == "" {
+			continue
+		}
+		if initialism, ok := initialisms[word]; ok {
+			b.WriteString(initialism)
+			continue
+		}
+		b.WriteString(strings.ToUpper(word[:1]) + word[1:])
+	}
+	return b.String()
+}
+
+// varName turns snake_case into an unexported Go name
+func varName(name string) string {
+	first, rest, _ := strings.Cut(name, "_")
+	return first + goName(rest)
+}
+
+// pluralize applies the regular English plural rules, which -plural
+// overrides for the rest
+func pluralize(name string) string {
+	switch {
+	case strings.HasSuffix(name, "y") && len(name) > 1 && !strings.ContainsRune("aeiou", rune(name[len(name)-2])):
+		return name[:len(name)-1] + "ies"
+	case strings.HasSuffix(name, "s"), strings.HasSuffix(name, "x"), strings.HasSuffix(name, "z"),
+		strings.HasSuffix(name, "ch"), strings.HasSuffix(name, "sh"):
+		return name + "es"
+	}
+	return name + "s"
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"golang-boilerplate/main/app"
+	"golang-boilerplate/main/config"
+	"golang-boilerplate/main/rpc"
+	"golang-boilerplate/main/server"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// serve runs the servers until SIGINT or SIGTERM. Migrations are left to
+// migrate up, e.g. in a Job that runs before a deployment rolls out, unless
+// -migrate asks for them.
+func serve(args []string) error {
+	fs := newFlags("serve", "serve [-migrate]")
+	migrateFirst := fs.Bool("migrate", false, "apply pending migrations before starting, for development")
+	fs.Parse(args)
+	if fs.NArg() > 0 {
+		usageError(fs, "unexpected arguments %v", fs.Args())
+	}
+
+	// Load configuration
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("could not load config: %w", err)
+	}
+
+	// Connect to the database, Redis and blob storage and wire the services
+	a, err := app.Open(cfg)
+	if err != nil {
+		return fmt.Errorf("could not initialize services: %w", err)
+	}
+	defer a.Close()
+
+	mods, err := newRegistry(cfg, a, a.Logger)
+	if err != nil {
+		return err
+	}
+	if *migrateFirst {
+		if err := migrateUp(a.DB, mods); err != nil {
+			return err
+		}
+	}
+	if err := mods.RegisterMetrics(prometheus.DefaultRegisterer); err != nil {
+		return fmt.Errorf("could not register module metrics: %w", err)
+	}
+
+	// Start the background workers of the modules
+	if err := mods.Start(context.Background()); err != nil {
+		return fmt.Errorf("could not start modules: %w", err)
+	}
+
+	// Create and start server
+	srv := server.NewServer(a, mods)
+	go func() {
+		if err := srv.Start(); err != nil {
+			log.Fatalf("Failed to start server: %v", err)
+		}
+	}()
+
+	// Start the gRPC server next to the HTTP server
+	var grpcSrv *rpc.Server
+	if cfg.GRPC.Enabled {
+		addr := fmt.Sprintf("%s:%s", cfg.Server.Host, cfg.GRPC.Port)
+		grpcSrv = rpc.NewServer(addr, a.Auth, a.UserService, a.Tracer, a.Logger)
+		go func() {
+			if err := grpcSrv.Start(); err != nil {
+				log.Fatalf("Failed to start gRPC server: %v", err)
+			}
+		}()
+	}
+
+	// Wait for interrupt signal to gracefully shutdown the server
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+	<-quit
+	log.Println("Shutting down server...")
+
+	if grpcSrv != nil {
+		grpcSrv.Shutdown()
+	}
+
+	// Close realtime connections first, they would otherwise hold the HTTP
+	// shutdown open until its timeout
+	mods.StopEarly()
+
+	if err := srv.Shutdown(); err != nil {
+		return fmt.Errorf("server forced to shutdown: %w", err)
+	}
+
+	mods.Stop()
+
+	log.Println("Server exited")
+	return nil
+}
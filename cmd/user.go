@@ -0,0 +1,158 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"golang-boilerplate/main/app"
+	"golang-boilerplate/main/config"
+	"golang-boilerplate/main/core"
+	"golang-boilerplate/main/models"
+	"golang-boilerplate/main/service"
+	"golang-boilerplate/main/validation"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+	"go.uber.org/zap"
+	"golang.org/x/term"
+)
+
+var userCommands = []command{
+	{"create", "user create [-admin] [-tenant name] -username name -email address", "Create an account, prompting for its password", userCreate},
+	{"reset-password", "user reset-password <username>", "Set a new password, prompting for it", userResetPassword},
+}
+
+func userCommand(args []string) error {
+	return dispatch("user", userCommands, args)
+}
+
+func userCreate(args []string) error {
+	fs := newFlags("user create", "user create [-admin] [-tenant name] -username name -email address")
+	admin := fs.Bool("admin", false, "give the account the admin role")
+	tenant := fs.String("tenant", "", "tenant the account belongs to, which feature flags can target")
+	username := fs.String("username", "", "username of the account")
+	email := fs.String("email", "", "email address of the account")
+	fs.Parse(args)
+	if fs.NArg() > 0 {
+		usageError(fs, "unexpected arguments %v", fs.Args())
+	}
+	if *username == "" || *email == "" {
+		usageError(fs, "-username and -email are required")
+	}
+
+	password, err := readPassword()
+	if err != nil {
+		return err
+	}
+
+	a, err := openUsers()
+	if err != nil {
+		return err
+	}
+	defer a.Close()
+
+	role := models.RoleUser
+	if *admin {
+		role = models.RoleAdmin
+	}
+	user, err := a.Auth.CreateUser(context.Background(), core.RegisterInput{
+		Username: *username,
+		Email:    *email,
+		Password: password,
+	}, role, *tenant)
+	if err != nil {
+		return userError(err)
+	}
+	fmt.Printf("Created %s %s with id %d\n", user.Role, user.Username, user.ID)
+	return nil
+}
+
+func userResetPassword(args []string) error {
+	fs := newFlags("user reset-password", "user reset-password <username>")
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		usageError(fs, "expected one username")
+	}
+
+	password, err := readPassword()
+	if err != nil {
+		return err
+	}
+
+	a, err := openUsers()
+	if err != nil {
+		return err
+	}
+	defer a.Close()
+
+	if err := a.Auth.ResetPassword(context.Background(), fs.Arg(0), password); err != nil {
+		return userError(err)
+	}
+	fmt.Printf("Password of %s reset\n", fs.Arg(0))
+	return nil
+}
+
+// openUsers wires the services over the database alone, which is all the
+// user commands need
+func openUsers() (*app.App, error) {
+	cfg, err := config.Load()
+	if err != nil {
+		return nil, fmt.Errorf("could not load config: %w", err)
+	}
+	db, err := service.OpenPostgres(cfg.Database)
+	if err != nil {
+		return nil, fmt.Errorf("could not connect to database: %w", err)
+	}
+	return app.New(cfg, zap.NewNop(), db, nil, nil), nil
+}
+
+// readPassword prompts for a password twice on a terminal, without echoing
+// it, or reads the first line of stdin, e.g. from a secret piped in
+func readPassword() (string, error) {
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+		if err != nil && !(errors.Is(err, io.EOF) && line != "") {
+			return "", fmt.Errorf("could not read password from stdin: %w", err)
+		}
+		return strings.TrimRight(line, "\r\n"), nil
+	}
+
+	prompt := func(label string) (string, error) {
+		fmt.Fprint(os.Stderr, label)
+		password, err := term.ReadPassword(fd)
+		fmt.Fprintln(os.Stderr)
+		if err != nil {
+			return "", fmt.Errorf("could not read password: %w", err)
+		}
+		return string(password), nil
+	}
+	password, err := prompt("Password: ")
+	if err != nil {
+		return "", err
+	}
+	repeated, err := prompt("Repeat password: ")
+	if err != nil {
+		return "", err
+	}
+	if password != repeated {
+		return "", errors.New("passwords do not match")
+	}
+	return password, nil
+}
+
+// userError spells out validation failures the way the API would
+func userError(err error) error {
+	var validationErrs validator.ValidationErrors
+	if !errors.As(err, &validationErrs) {
+		return err
+	}
+	messages := make([]string, 0, len(validationErrs))
+	for _, fieldErr := range validation.Errors(err, "en") {
+		messages = append(messages, fieldErr.Field+": "+fieldErr.Message)
+	}
+	return errors.New(strings.Join(messages, "\n"))
+}
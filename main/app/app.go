@@ -0,0 +1,146 @@
+package app
+
+import (
+	"database/sql"
+	"fmt"
+	"io"
+
+	"golang-boilerplate/main/config"
+	"golang-boilerplate/main/core"
+	"golang-boilerplate/main/flags"
+	"golang-boilerplate/main/realtime"
+	"golang-boilerplate/main/repo"
+	"golang-boilerplate/main/service"
+	"golang-boilerplate/pkg/blob"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/redis/go-redis/v9"
+	jaegercfg "github.com/uber/jaeger-client-go/config"
+	"go.uber.org/zap"
+)
+
+// App holds the configuration, connections and the services shared by the
+// modules. It is built once at startup and handed to whatever needs it;
+// nothing in the application is reachable through package-level state.
+// Services only one module uses, such as the webhook dispatcher, are built
+// by that module; the flag store is here because every handler reads it,
+// though only the flags module keeps it in sync.
+type App struct {
+	Config *config.Config
+	Logger *zap.Logger
+	Tracer opentracing.Tracer
+
+	DB    *sql.DB
+	Redis *redis.Client
+	Blobs blob.Store
+
+	Users repo.UserRepository
+	Tx    *repo.UnitOfWork
+
+	// Events carries the changes the services publish to the modules
+	// subscribed to them
+	Events      *core.Events
+	Auth        *core.AuthService
+	UserService *core.UserService
+	Avatars     *core.AvatarService
+
+	Hub   *realtime.Hub
+	Flags *flags.Store
+
+	closers []io.Closer
+}
+
+// New wires the services on top of existing connections. Tests use it to
+// build an App around their own database, Redis and blob store; rdb may be
+// nil to keep everything in process, and db nil to keep users in memory,
+// which leaves the units of work and the modules storing their own data
+// unusable.
+func New(cfg *config.Config, logger *zap.Logger, db *sql.DB, rdb *redis.Client, blobs blob.Store) *App {
+	a := &App{
+		Config: cfg,
+		Logger: logger,
+		Tracer: opentracing.NoopTracer{},
+		DB:     db,
+		Redis:  rdb,
+		Blobs:  blobs,
+	}
+
+	timeout := cfg.Database.QueryTimeout
+	if db != nil {
+		a.Users = repo.NewUserRepo(db, timeout)
+	} else {
+		a.Users = repo.NewMemoryUserRepo()
+	}
+	a.Tx = repo.NewUnitOfWork(db, cfg.Database, logger)
+
+	a.Events = &core.Events{}
+	a.Auth = core.NewAuthService(a.Users, cfg.JWT.Secret)
+	a.UserService = core.NewUserService(a.Users, a.Events)
+	a.Avatars = core.NewAvatarService(a.Users, blobs, a.Events, cfg.Avatars, cfg.Storage.URLTTL)
+
+	a.Hub = realtime.NewHub(logger)
+	a.Flags = flags.NewStore(a.UserService, logger)
+	return a
+}
+
+// Open connects to everything the configuration points at and wires the
+// services. It leaves the schema alone; migrations are applied separately.
+func Open(cfg *config.Config) (*App, error) {
+	logger, err := zap.NewProduction()
+	if err != nil {
+		return nil, fmt.Errorf("could not create logger: %w", err)
+	}
+
+	db, err := service.OpenPostgres(cfg.Database)
+	if err != nil {
+		return nil, fmt.Errorf("could not connect to database: %w", err)
+	}
+
+	rdb := service.NewRedis(cfg.Redis)
+
+	blobs, err := service.NewBlobStore(cfg.Storage, cfg.JWT.Secret)
+	if err != nil {
+		db.Close()
+		rdb.Close()
+		return nil, fmt.Errorf("could not create blob store: %w", err)
+	}
+
+	a := New(cfg, logger, db, rdb, blobs)
+
+	tracer, closer, err := newTracer()
+	if err != nil {
+		logger.Error("failed to create tracer", zap.Error(err))
+	} else {
+		a.Tracer = tracer
+		a.closers = append(a.closers, closer)
+	}
+	return a, nil
+}
+
+func newTracer() (opentracing.Tracer, io.Closer, error) {
+	cfg := jaegercfg.Configuration{
+		ServiceName: "golang-boilerplate",
+		Sampler: &jaegercfg.SamplerConfig{
+			Type:  "const",
+			Param: 1,
+		},
+		Reporter: &jaegercfg.ReporterConfig{
+			LogSpans: true,
+		},
+	}
+	return cfg.NewTracer()
+}
+
+// Close releases the connections
+func (a *App) Close() {
+	if a.DB != nil {
+		a.DB.Close()
+	}
+	if a.Redis != nil {
+		a.Redis.Close()
+	}
+	for _, closer := range a.closers {
+		closer.Close()
+	}
+	a.Logger.Sync()
+}
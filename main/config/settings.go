@@ -0,0 +1,72 @@
+package config
+
+import (
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// redacted replaces secrets in printed settings
+const redacted = "[REDACTED]"
+
+// secretKeys are the setting names, or name suffixes, that hold secrets
+var secretKeys = []string{"secret", "password", "token", "key"}
+
+// Settings returns the effective configuration after Load, keyed as in
+// config.yaml, with durations and times written the way they would be there
+func Settings() map[string]interface{} {
+	return normalize(viper.AllSettings()).(map[string]interface{})
+}
+
+func normalize(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for key, value := range v {
+			v[key] = normalize(value)
+		}
+	case time.Duration:
+		return v.String()
+	case time.Time:
+		return v.Format(time.RFC3339)
+	}
+	return v
+}
+
+// Redact replaces the secrets in settings, and the passwords of URLs, so
+// they can be shared. Empty secrets are kept, to show that they are unset.
+func Redact(settings map[string]interface{}) map[string]interface{} {
+	for key, value := range settings {
+		switch value := value.(type) {
+		case map[string]interface{}:
+			Redact(value)
+		case string:
+			if value != "" && isSecret(key) {
+				settings[key] = redacted
+			} else {
+				settings[key] = redactURL(value)
+			}
+		}
+	}
+	return settings
+}
+
+func isSecret(key string) bool {
+	for _, secret := range secretKeys {
+		if key == secret || strings.HasSuffix(key, "_"+secret) {
+			return true
+		}
+	}
+	return false
+}
+
+// redactURL hides the password of a URL such as a database connection
+// string, leaving anything else as it is
+func redactURL(value string) string {
+	u, err := url.Parse(value)
+	if err != nil || u.User == nil {
+		return value
+	}
+	return u.Redacted()
+}
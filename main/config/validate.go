@@ -0,0 +1,95 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+)
+
+// defaultJWTSecret is the placeholder secret Load falls back to
+const defaultJWTSecret = "your-secret-key"
+
+// Validate checks the configuration for values that would fail or misbehave
+// at runtime, reporting every problem at once. Module names are checked by
+// the module registry, which knows which modules exist.
+func (c *Config) Validate() error {
+	var errs []error
+	check := func(ok bool, format string, args ...interface{}) {
+		if !ok {
+			errs = append(errs, fmt.Errorf(format, args...))
+		}
+	}
+
+	check(validPort(c.Server.Port), "server.port: invalid port %q", c.Server.Port)
+	if c.GRPC.Enabled {
+		check(validPort(c.GRPC.Port), "grpc.port: invalid port %q", c.GRPC.Port)
+	}
+
+	for _, origin := range c.CORS.AllowedOrigins {
+		check(origin == "*" || validOrigin(origin),
+			"cors.allowed_origins: %q must be * or a scheme and host such as https://app.example.com", origin)
+	}
+
+	u, err := url.Parse(c.Database.URL)
+	check(err == nil && (u.Scheme == "postgres" || u.Scheme == "postgresql"),
+		"database.url: must be a postgres:// URL")
+	check(c.Database.QueryTimeout >= 0, "database.query_timeout: must not be negative")
+	check(c.Database.TxMaxAttempts >= 1, "database.tx_max_attempts: must be at least 1")
+	check(c.Database.TxInitialBackoff <= c.Database.TxMaxBackoff,
+		"database.tx_initial_backoff: must not exceed database.tx_max_backoff")
+	check(c.Redis.URL != "", "redis.url: must be set")
+
+	check(c.JWT.Secret != "" && c.JWT.Secret != defaultJWTSecret, "jwt.secret: must be set to a secret of your own")
+	check(c.Idempotency.TTL > 0, "idempotency.ttl: must be positive")
+
+	// The local and memory drivers sign their download URLs, with a key
+	// that must not also sign tokens
+	signingSecret := func() {
+		check(c.Storage.SigningSecret != "" && c.Storage.SigningSecret != c.JWT.Secret,
+			"storage.signing_secret: must be set to a secret other than jwt.secret for the %s driver", c.Storage.Driver)
+	}
+	switch c.Storage.Driver {
+	case "local":
+		check(c.Storage.LocalPath != "", "storage.local_path: must be set for the local driver")
+		signingSecret()
+	case "s3":
+		check(c.Storage.S3.Bucket != "", "storage.s3.bucket: must be set for the s3 driver")
+	case "memory":
+		signingSecret()
+	default:
+		check(false, "storage.driver: unknown driver %q, want local, s3 or memory", c.Storage.Driver)
+	}
+	check(c.Storage.URLTTL > 0, "storage.url_ttl: must be positive")
+
+	check(c.Imports.Workers >= 1, "imports.workers: must be at least 1")
+	check(c.Imports.BatchSize >= 1, "imports.batch_size: must be at least 1")
+	check(c.Webhooks.Workers >= 1, "webhooks.workers: must be at least 1")
+	check(c.Webhooks.MaxAttempts >= 1, "webhooks.max_attempts: must be at least 1")
+	check(c.Webhooks.InitialBackoff <= c.Webhooks.MaxBackoff,
+		"webhooks.initial_backoff: must not exceed webhooks.max_backoff")
+
+	switch c.Outbox.Sink {
+	case "redis", "log", "memory", "":
+	default:
+		check(false, "outbox.sink: unknown sink %q, want redis, log or memory", c.Outbox.Sink)
+	}
+	check(c.Outbox.PollInterval > 0, "outbox.poll_interval: must be positive")
+	check(c.Outbox.BatchSize >= 1, "outbox.batch_size: must be at least 1")
+	check(c.Flags.RefreshInterval > 0, "flags.refresh_interval: must be positive")
+
+	return errors.Join(errs...)
+}
+
+func validPort(port string) bool {
+	n, err := strconv.Atoi(port)
+	return err == nil && n > 0 && n < 65536
+}
+
+// validOrigin reports whether origin is in the form browsers send, e.g.
+// https://app.example.com:8443, without a path
+func validOrigin(origin string) bool {
+	u, err := url.Parse(origin)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != "" &&
+		u.Path == "" && u.RawQuery == "" && u.User == nil
+}
@@ -0,0 +1,168 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"golang-boilerplate/main/models"
+	"golang-boilerplate/main/repo"
+	_ "golang-boilerplate/main/validation" // registers the custom binding rules
+	"time"
+
+	"github.com/gin-gonic/gin/binding"
+	"github.com/golang-jwt/jwt/v5"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// tokenTTL is how long issued JWTs stay valid
+const tokenTTL = 24 * time.Hour
+
+// RegisterInput is the data needed to create an account. The binding tags
+// apply the same rules regardless of which transport the input came from.
+type RegisterInput struct {
+	Username string `json:"username" binding:"required,username"`
+	Email    string `json:"email" binding:"required,email_address"`
+	Password string `json:"password" binding:"required,password"`
+}
+
+// AuthService implements registration, login and token verification.
+// Tokens are signed with secret.
+type AuthService struct {
+	users  repo.UserRepository
+	secret []byte
+}
+
+func NewAuthService(users repo.UserRepository, secret string) *AuthService {
+	return &AuthService{users: users, secret: []byte(secret)}
+}
+
+// Register validates the input and creates a user with a hashed password.
+// Validation failures are returned as the validator's errors.
+func (s *AuthService) Register(ctx context.Context, input RegisterInput) (*models.User, error) {
+	return s.CreateUser(ctx, input, models.RoleUser, "")
+}
+
+// CreateUser is Register with a role and tenant of the caller's choosing,
+// for operators setting up administrators and the accounts of a tenant.
+// The tenant may be empty.
+func (s *AuthService) CreateUser(ctx context.Context, input RegisterInput, role, tenant string) (*models.User, error) {
+	if err := binding.Validator.ValidateStruct(input); err != nil {
+		return nil, err
+	}
+
+	hashedPassword, err := HashPassword(input.Password)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	user := &models.User{
+		Username:  input.Username,
+		Email:     input.Email,
+		Password:  hashedPassword,
+		Role:      role,
+		Tenant:    tenant,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+
+	if err := s.users.CreateUser(ctx, user); err != nil {
+		if errors.Is(err, repo.ErrConflict) {
+			return nil, ErrUserExists
+		}
+		return nil, fmt.Errorf("could not create user: %w", err)
+	}
+
+	// user.registered is published from the outbox CreateUser wrote to
+	return user, nil
+}
+
+// passwordInput validates a password on its own
+type passwordInput struct {
+	Password string `json:"password" binding:"required,password"`
+}
+
+// ResetPassword replaces the password of the user with username, for
+// operators helping someone locked out. Validation failures are returned as
+// the validator's errors.
+func (s *AuthService) ResetPassword(ctx context.Context, username, password string) error {
+	if err := binding.Validator.ValidateStruct(passwordInput{Password: password}); err != nil {
+		return err
+	}
+
+	user, err := s.users.GetUserByUsername(ctx, username)
+	if err != nil {
+		if errors.Is(err, repo.ErrNotFound) {
+			return ErrUserNotFound
+		}
+		return fmt.Errorf("could not load user: %w", err)
+	}
+
+	if user.Password, err = HashPassword(password); err != nil {
+		return err
+	}
+	user.UpdatedAt = time.Now()
+	return mapWriteError(s.users.UpdateUser(ctx, user, user.Version))
+}
+
+// Login checks the credentials and returns a signed JWT
+func (s *AuthService) Login(ctx context.Context, username, password string) (string, error) {
+	user, err := s.users.GetUserByUsername(ctx, username)
+	if err != nil {
+		if errors.Is(err, repo.ErrNotFound) {
+			return "", ErrInvalidCredentials
+		}
+		return "", fmt.Errorf("could not load user: %w", err)
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password)); err != nil {
+		return "", ErrInvalidCredentials
+	}
+
+	return s.IssueToken(user.ID)
+}
+
+// HashPassword hashes a password for storage
+func HashPassword(password string) (string, error) {
+	hashed, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", fmt.Errorf("could not hash password: %w", err)
+	}
+	return string(hashed), nil
+}
+
+// IssueToken signs a JWT for the user
+func (s *AuthService) IssueToken(userID uint) (string, error) {
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"user_id": userID,
+		"exp":     time.Now().Add(tokenTTL).Unix(),
+	})
+
+	return token.SignedString(s.secret)
+}
+
+// ParseToken verifies a JWT and returns the user ID it was issued for
+func (s *AuthService) ParseToken(tokenString string) (uint, error) {
+	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("unexpected signing method %v", token.Header["alg"])
+		}
+		return s.secret, nil
+	})
+	if err != nil || !token.Valid {
+		return 0, ErrInvalidToken
+	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok {
+		return 0, ErrInvalidToken
+	}
+
+	// JSON numbers decode as float64
+	userID, ok := claims["user_id"].(float64)
+	if !ok || userID <= 0 {
+		return 0, ErrInvalidToken
+	}
+
+	return uint(userID), nil
+}
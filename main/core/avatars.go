@@ -0,0 +1,177 @@
+package core
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"golang-boilerplate/main/config"
+	"golang-boilerplate/main/models"
+	"golang-boilerplate/main/repo"
+	"golang-boilerplate/pkg/blob"
+	"golang-boilerplate/pkg/imaging"
+	"log"
+	"strings"
+	"time"
+)
+
+// AvatarVariant is one of the square sizes avatars are stored in
+type AvatarVariant struct {
+	Name string
+	Size int
+}
+
+// AvatarVariants are generated for every upload; avatar_url points at medium
+var AvatarVariants = []AvatarVariant{
+	{Name: "small", Size: 64},
+	{Name: "medium", Size: 256},
+	{Name: "large", Size: 512},
+}
+
+const defaultAvatarVariant = "medium"
+
+var extensions = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+}
+
+// AvatarService stores profile pictures in a blob store. Signed URLs to
+// them expire after urlTTL.
+type AvatarService struct {
+	users  repo.UserRepository
+	blobs  blob.Store
+	events EventPublisher
+	cfg    config.AvatarsConfig
+	urlTTL time.Duration
+}
+
+func NewAvatarService(users repo.UserRepository, blobs blob.Store, events EventPublisher, cfg config.AvatarsConfig, urlTTL time.Duration) *AvatarService {
+	return &AvatarService{users: users, blobs: blobs, events: events, cfg: cfg, urlTTL: urlTTL}
+}
+
+// SetAvatar decodes the uploaded image, stores a resized copy for each
+// variant and points the user at them. The previous avatar is deleted.
+// Users may only change their own avatar.
+func (s *AvatarService) SetAvatar(ctx context.Context, actorID, id uint, data []byte) (*models.User, error) {
+	if actorID != id {
+		return nil, ErrForbidden
+	}
+
+	img, err := imaging.Decode(data, s.cfg.MaxPixels)
+	switch {
+	case errors.Is(err, imaging.ErrTooManyPixels):
+		return nil, ErrImageTooLarge
+	case err != nil:
+		return nil, ErrUnsupportedImage
+	}
+
+	// All variants share one format, so the key can carry the extension
+	contentType := imaging.ContentTypeFor(img)
+	key := fmt.Sprintf("avatars/%d/%s%s", id, randomHex(8), extensions[contentType])
+
+	var stored []string
+	for _, variant := range AvatarVariants {
+		encoded, err := imaging.Encode(imaging.Square(img, variant.Size), contentType)
+		if err != nil {
+			s.deleteBlobs(ctx, stored)
+			return nil, fmt.Errorf("could not encode avatar: %w", err)
+		}
+
+		variantKey := avatarVariantKey(key, variant.Name)
+		if err := s.blobs.Put(ctx, variantKey, bytes.NewReader(encoded), int64(len(encoded)), contentType); err != nil {
+			s.deleteBlobs(ctx, stored)
+			return nil, fmt.Errorf("could not store avatar: %w", err)
+		}
+		stored = append(stored, variantKey)
+	}
+
+	user, err := s.replaceKey(ctx, id, key)
+	if err != nil {
+		s.deleteBlobs(ctx, stored)
+		return nil, err
+	}
+	return user, nil
+}
+
+// RemoveAvatar clears the user's avatar and deletes its blobs
+func (s *AvatarService) RemoveAvatar(ctx context.Context, actorID, id uint) (*models.User, error) {
+	if actorID != id {
+		return nil, ErrForbidden
+	}
+	return s.replaceKey(ctx, id, "")
+}
+
+func (s *AvatarService) replaceKey(ctx context.Context, id uint, key string) (*models.User, error) {
+	user := &models.User{ID: id, AvatarKey: key, UpdatedAt: time.Now()}
+	previous, err := s.users.SetAvatarKey(ctx, user)
+	if err != nil {
+		return nil, mapWriteError(err)
+	}
+
+	if previous != "" {
+		s.deleteBlobs(ctx, avatarKeys(previous))
+	}
+
+	updated, err := NewUserService(s.users, s.events).GetUser(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	publishEvent(ctx, s.events, models.EventUserUpdated, models.NewUserEventData(updated))
+	return updated, nil
+}
+
+// Sign sets user.AvatarURL to a signed URL for the default variant and
+// returns signed URLs for every variant, keyed by variant name. Users
+// without an avatar get an empty map.
+func (s *AvatarService) Sign(ctx context.Context, user *models.User) (map[string]string, error) {
+	urls := make(map[string]string, len(AvatarVariants))
+	if user.AvatarKey == "" {
+		return urls, nil
+	}
+
+	for _, variant := range AvatarVariants {
+		url, err := s.blobs.SignedURL(ctx, avatarVariantKey(user.AvatarKey, variant.Name), s.urlTTL)
+		if err != nil {
+			return nil, fmt.Errorf("could not sign avatar url: %w", err)
+		}
+		urls[variant.Name] = url
+	}
+
+	user.AvatarURL = urls[defaultAvatarVariant]
+	return urls, nil
+}
+
+// deleteBlobs removes blobs on a best-effort basis; leftovers only cost storage
+func (s *AvatarService) deleteBlobs(ctx context.Context, keys []string) {
+	for _, key := range keys {
+		if err := s.blobs.Delete(ctx, key); err != nil {
+			log.Printf("could not delete blob %s: %v", key, err)
+		}
+	}
+}
+
+// avatarVariantKey turns avatars/1/abc.jpg into avatars/1/abc-small.jpg
+func avatarVariantKey(key, variant string) string {
+	dot := strings.LastIndex(key, ".")
+	if dot < 0 {
+		return key + "-" + variant
+	}
+	return key[:dot] + "-" + variant + key[dot:]
+}
+
+func avatarKeys(key string) []string {
+	keys := make([]string, len(AvatarVariants))
+	for i, variant := range AvatarVariants {
+		keys[i] = avatarVariantKey(key, variant.Name)
+	}
+	return keys
+}
+
+func randomHex(n int) string {
+	b := make([]byte, n)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,169 @@
+package core
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"image/png"
+	"slices"
+	"strings"
+	"testing"
+
+	"golang-boilerplate/main/config"
+	"golang-boilerplate/main/models"
+	"golang-boilerplate/main/repo"
+	"golang-boilerplate/pkg/blob"
+)
+
+// newAvatarService returns a service over in-memory stores, with a user
+func newAvatarService(t *testing.T, maxPixels int) (*AvatarService, *blob.MemoryStore, *models.User) {
+	t.Helper()
+	users := repo.NewMemoryUserRepo()
+	user := &models.User{Username: "alice", Email: "alice@example.com", Role: models.RoleUser}
+	if err := users.CreateUser(t.Context(), user); err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	blobs := blob.NewMemoryStore(blob.NewSigner("secret", "/blobs"))
+	cfg := config.AvatarsConfig{MaxUploadMB: 1, MaxPixels: maxPixels}
+	return NewAvatarService(users, blobs, nil, cfg, 0), blobs, user
+}
+
+func pngImage(t *testing.T, w, h int, alpha uint8) []byte {
+	t.Helper()
+	img := image.NewNRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			img.Set(x, y, color.NRGBA{R: uint8(x), G: uint8(y), B: 200, A: alpha})
+		}
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatalf("could not encode PNG: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func TestSetAvatar(t *testing.T) {
+	s, blobs, user := newAvatarService(t, 1_000_000)
+
+	updated, err := s.SetAvatar(t.Context(), user.ID, user.ID, pngImage(t, 600, 400, 255))
+	if err != nil {
+		t.Fatalf("SetAvatar: %v", err)
+	}
+	if !strings.HasPrefix(updated.AvatarKey, "avatars/1/") || !strings.HasSuffix(updated.AvatarKey, ".jpg") {
+		t.Fatalf("got key %q, want a JPEG below avatars/1/", updated.AvatarKey)
+	}
+
+	keys := blobs.Keys()
+	slices.Sort(keys)
+	want := avatarKeys(updated.AvatarKey)
+	slices.Sort(want)
+	if !slices.Equal(keys, want) {
+		t.Fatalf("got blobs %v, want one per variant %v", keys, want)
+	}
+}
+
+func TestSetAvatarRejected(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    func(t *testing.T) []byte
+		wantErr error
+	}{
+		{"Text", func(*testing.T) []byte { return []byte("not an image at all") }, ErrUnsupportedImage},
+		{"SVG", func(*testing.T) []byte {
+			return []byte(`<svg xmlns="http://www.w3.org/2000/svg" width="10" height="10"/>`)
+		}, ErrUnsupportedImage},
+		{"HTML", func(*testing.T) []byte { return []byte("<html><script>alert(1)</script></html>") }, ErrUnsupportedImage},
+		{"OverPixelCap", func(t *testing.T) []byte { return pngImage(t, 101, 100, 255) }, ErrImageTooLarge},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, blobs, user := newAvatarService(t, 100*100)
+
+			_, err := s.SetAvatar(t.Context(), user.ID, user.ID, tt.data(t))
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("got error %v, want %v", err, tt.wantErr)
+			}
+			if keys := blobs.Keys(); len(keys) > 0 {
+				t.Fatalf("rejected upload stored %v", keys)
+			}
+		})
+	}
+}
+
+func TestSetAvatarAtPixelCap(t *testing.T) {
+	s, _, user := newAvatarService(t, 100*100)
+	if _, err := s.SetAvatar(t.Context(), user.ID, user.ID, pngImage(t, 100, 100, 255)); err != nil {
+		t.Fatalf("SetAvatar: %v", err)
+	}
+}
+
+func TestSetAvatarOfOtherUser(t *testing.T) {
+	s, blobs, user := newAvatarService(t, 1_000_000)
+
+	_, err := s.SetAvatar(t.Context(), user.ID+1, user.ID, pngImage(t, 10, 10, 255))
+	if !errors.Is(err, ErrForbidden) {
+		t.Fatalf("got error %v, want ErrForbidden", err)
+	}
+	if keys := blobs.Keys(); len(keys) > 0 {
+		t.Fatalf("forbidden upload stored %v", keys)
+	}
+}
+
+func TestReplacedAvatarDeleted(t *testing.T) {
+	s, blobs, user := newAvatarService(t, 1_000_000)
+
+	first, err := s.SetAvatar(t.Context(), user.ID, user.ID, pngImage(t, 50, 50, 255))
+	if err != nil {
+		t.Fatalf("first SetAvatar: %v", err)
+	}
+	second, err := s.SetAvatar(t.Context(), user.ID, user.ID, pngImage(t, 50, 50, 100))
+	if err != nil {
+		t.Fatalf("second SetAvatar: %v", err)
+	}
+	if second.AvatarKey == first.AvatarKey || !strings.HasSuffix(second.AvatarKey, ".png") {
+		t.Fatalf("got key %q after %q, want a new PNG key", second.AvatarKey, first.AvatarKey)
+	}
+
+	for _, key := range blobs.Keys() {
+		if !strings.HasPrefix(key, strings.TrimSuffix(second.AvatarKey, ".png")) {
+			t.Fatalf("blob %s of the replaced avatar was kept", key)
+		}
+	}
+	if got := len(blobs.Keys()); got != len(AvatarVariants) {
+		t.Fatalf("got %d blobs, want %d", got, len(AvatarVariants))
+	}
+
+	removed, err := s.RemoveAvatar(t.Context(), user.ID, user.ID)
+	if err != nil {
+		t.Fatalf("RemoveAvatar: %v", err)
+	}
+	if removed.AvatarKey != "" {
+		t.Fatalf("got key %q after removal, want none", removed.AvatarKey)
+	}
+	if keys := blobs.Keys(); len(keys) > 0 {
+		t.Fatalf("removed avatar left %v", keys)
+	}
+}
+
+func TestSign(t *testing.T) {
+	s, _, user := newAvatarService(t, 1_000_000)
+
+	urls, err := s.Sign(t.Context(), user)
+	if err != nil || len(urls) != 0 || user.AvatarURL != "" {
+		t.Fatalf("got %v, %q, %v for a user without avatar, want nothing", urls, user.AvatarURL, err)
+	}
+
+	user.AvatarKey = "avatars/1/abc.jpg"
+	urls, err = s.Sign(t.Context(), user)
+	if err != nil {
+		t.Fatalf("Sign: %v", err)
+	}
+	if len(urls) != len(AvatarVariants) || !strings.HasPrefix(urls["small"], "/blobs/avatars/1/abc-small.jpg?") {
+		t.Fatalf("got %v, want a signed URL per variant", urls)
+	}
+	if user.AvatarURL != urls[defaultAvatarVariant] {
+		t.Fatalf("got avatar URL %q, want the %s variant", user.AvatarURL, defaultAvatarVariant)
+	}
+}
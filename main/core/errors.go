@@ -0,0 +1,14 @@
+package core
+
+import "errors"
+
+var (
+	ErrInvalidCredentials = errors.New("invalid credentials")
+	ErrInvalidToken       = errors.New("invalid token")
+	ErrUserExists         = errors.New("user already exists")
+	ErrUserNotFound       = errors.New("user not found")
+	ErrForbidden          = errors.New("forbidden")
+	ErrVersionMismatch    = errors.New("version mismatch")
+	ErrUnsupportedImage   = errors.New("unsupported image")
+	ErrImageTooLarge      = errors.New("image dimensions too large")
+)
@@ -0,0 +1,56 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"golang-boilerplate/main/webhooks"
+	"log"
+	"sync"
+)
+
+// EventPublisher notifies subscribers of changes, e.g. *webhooks.Dispatcher
+type EventPublisher interface {
+	Publish(ctx context.Context, eventType string, data interface{}) error
+}
+
+// Events hands published events to the publishers subscribed to it. The
+// services publish to it, and the modules that deliver events, such as
+// webhooks, subscribe their own publishers. The zero value has none.
+type Events struct {
+	mu         sync.RWMutex
+	publishers []EventPublisher
+}
+
+// Subscribe adds a publisher that every later event is handed to
+func (e *Events) Subscribe(p EventPublisher) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.publishers = append(e.publishers, p)
+}
+
+// Publish hands the event to every subscriber, returning their errors
+// joined
+func (e *Events) Publish(ctx context.Context, eventType string, data interface{}) error {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	var errs []error
+	for _, p := range e.publishers {
+		if err := p.Publish(ctx, eventType, data); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// publishEvent notifies subscribers of a change that has already been
+// saved, so failures are logged rather than returned
+func publishEvent(ctx context.Context, events EventPublisher, eventType string, data interface{}) {
+	if events == nil {
+		return
+	}
+	err := events.Publish(ctx, eventType, data)
+	if err != nil && !errors.Is(err, webhooks.ErrNotRunning) {
+		log.Printf("could not publish %s event: %v", eventType, err)
+	}
+}
@@ -0,0 +1,41 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"golang-boilerplate/main/webhooks"
+)
+
+// recorder is a publisher that keeps the event types it is given
+type recorder struct {
+	types []string
+	err   error
+}
+
+func (r *recorder) Publish(_ context.Context, eventType string, _ interface{}) error {
+	r.types = append(r.types, eventType)
+	return r.err
+}
+
+func TestEvents(t *testing.T) {
+	var events Events
+	if err := events.Publish(t.Context(), "user.updated", nil); err != nil {
+		t.Fatalf("got error %v without subscribers", err)
+	}
+
+	first, second := &recorder{}, &recorder{err: webhooks.ErrNotRunning}
+	events.Subscribe(first)
+	events.Subscribe(second)
+
+	err := events.Publish(t.Context(), "user.deleted", nil)
+	if !errors.Is(err, webhooks.ErrNotRunning) {
+		t.Fatalf("got error %v, want %v", err, webhooks.ErrNotRunning)
+	}
+	for _, r := range []*recorder{first, second} {
+		if len(r.types) != 1 || r.types[0] != "user.deleted" {
+			t.Fatalf("got events %v, want [user.deleted]", r.types)
+		}
+	}
+}
@@ -0,0 +1,132 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"golang-boilerplate/main/models"
+	"golang-boilerplate/main/repo"
+	"time"
+
+	"github.com/gin-gonic/gin/binding"
+)
+
+type userIDKey struct{}
+
+// WithUserID returns a context carrying the authenticated user's ID
+func WithUserID(ctx context.Context, userID uint) context.Context {
+	return context.WithValue(ctx, userIDKey{}, userID)
+}
+
+// UserIDFromContext returns the authenticated user's ID, if any
+func UserIDFromContext(ctx context.Context) (uint, bool) {
+	userID, ok := ctx.Value(userIDKey{}).(uint)
+	return userID, ok
+}
+
+// UserService implements access to user accounts. Changes are published to
+// events, which may be nil.
+type UserService struct {
+	users  repo.UserRepository
+	events EventPublisher
+}
+
+func NewUserService(users repo.UserRepository, events EventPublisher) *UserService {
+	return &UserService{users: users, events: events}
+}
+
+// GetUser loads a user by ID
+func (s *UserService) GetUser(ctx context.Context, id uint) (*models.User, error) {
+	user, err := s.users.GetUserByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, repo.ErrNotFound) {
+			return nil, ErrUserNotFound
+		}
+		return nil, fmt.Errorf("could not load user: %w", err)
+	}
+	return user, nil
+}
+
+// GetUsers loads several users in one query, keyed by ID. IDs that do not
+// exist are absent from the result.
+func (s *UserService) GetUsers(ctx context.Context, ids []uint) (map[uint]*models.User, error) {
+	users, err := s.users.GetUsersByIDs(ctx, ids)
+	if err != nil {
+		return nil, fmt.Errorf("could not load users: %w", err)
+	}
+
+	byID := make(map[uint]*models.User, len(users))
+	for _, user := range users {
+		byID[user.ID] = user
+	}
+	return byID, nil
+}
+
+// UpdateUserInput lists the fields a user may change. Nil fields are left as they are.
+type UpdateUserInput struct {
+	Username *string `json:"username" binding:"omitempty,username"`
+	Email    *string `json:"email" binding:"omitempty,email_address"`
+}
+
+// UpdateUser applies the changes if the user is still at expectedVersion.
+// Users may only modify their own account.
+func (s *UserService) UpdateUser(ctx context.Context, actorID, id uint, expectedVersion int, input UpdateUserInput) (*models.User, error) {
+	if actorID != id {
+		return nil, ErrForbidden
+	}
+	if err := binding.Validator.ValidateStruct(input); err != nil {
+		return nil, err
+	}
+
+	user, err := s.GetUser(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if user.Version != expectedVersion {
+		return nil, ErrVersionMismatch
+	}
+
+	if input.Username != nil {
+		user.Username = *input.Username
+	}
+	if input.Email != nil {
+		user.Email = *input.Email
+	}
+	user.UpdatedAt = time.Now()
+
+	if err := s.users.UpdateUser(ctx, user, expectedVersion); err != nil {
+		return nil, mapWriteError(err)
+	}
+
+	publishEvent(ctx, s.events, models.EventUserUpdated, models.NewUserEventData(user))
+	return user, nil
+}
+
+// DeleteUser removes the account if it is still at expectedVersion.
+// Users may only delete their own account.
+func (s *UserService) DeleteUser(ctx context.Context, actorID, id uint, expectedVersion int) error {
+	if actorID != id {
+		return ErrForbidden
+	}
+	if err := s.users.DeleteUser(ctx, id, expectedVersion); err != nil {
+		return mapWriteError(err)
+	}
+
+	publishEvent(ctx, s.events, models.EventUserDeleted, models.UserEventData{ID: id})
+	return nil
+}
+
+func mapWriteError(err error) error {
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, repo.ErrNotFound):
+		return ErrUserNotFound
+	case errors.Is(err, repo.ErrVersionConflict):
+		return ErrVersionMismatch
+	case errors.Is(err, repo.ErrConflict):
+		return ErrUserExists
+	default:
+		return fmt.Errorf("could not save user: %w", err)
+	}
+}
@@ -0,0 +1,69 @@
+package flags
+
+import (
+	"strconv"
+
+	"golang-boilerplate/main/core"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// userKey caches the caller's role and tenant in the gin context, so a
+// request that evaluates several targeted flags loads the user once
+const userKey = "flags.user"
+
+// EvaluateRequest decides a flag for the caller. Every attribute comes from
+// the authenticated user, never from what the request claims: the user ID
+// from AuthMiddleware, and the role and tenant from the user record, which
+// is only loaded when the flag has a rule on either. Anonymous requests
+// match no rules and are outside percentage rollouts. A nil store has every
+// flag off.
+func (s *Store) EvaluateRequest(c *gin.Context, key string) Evaluation {
+	if s == nil {
+		return Evaluation{Key: key, Reason: ReasonNotFound}
+	}
+	flag, ok := s.Get(key)
+	if !ok {
+		return Evaluation{Key: key, Reason: ReasonNotFound}
+	}
+
+	var p Principal
+	if userID, ok := core.UserIDFromContext(c.Request.Context()); ok {
+		p.UserID = strconv.FormatUint(uint64(userID), 10)
+		if flag.TargetsAttribute(AttributeRole) || flag.TargetsAttribute(AttributeTenant) {
+			loaded := s.user(c, userID)
+			p.Role, p.Tenant = loaded.Role, loaded.Tenant
+		}
+	}
+	return flag.Evaluate(p)
+}
+
+// Enabled reports whether a flag is on for the caller
+func (s *Store) Enabled(c *gin.Context, key string) bool {
+	return s.EvaluateRequest(c, key).Enabled
+}
+
+// VariantOf returns the caller's variant of a variant flag, or "" when the
+// flag is off
+func (s *Store) VariantOf(c *gin.Context, key string) string {
+	return s.EvaluateRequest(c, key).Variant
+}
+
+// user loads the caller's role and tenant, treating a failure as having
+// neither so that a flag check never fails the request
+func (s *Store) user(c *gin.Context, userID uint) Principal {
+	if cached, ok := c.Get(userKey); ok {
+		return cached.(Principal)
+	}
+
+	var p Principal
+	user, err := s.users.GetUser(c.Request.Context(), userID)
+	if err != nil {
+		s.logger.Warn("could not load user for flag evaluation", zap.Uint("user_id", userID), zap.Error(err))
+	} else {
+		p.Role, p.Tenant = user.Role, user.Tenant
+	}
+	c.Set(userKey, p)
+	return p
+}
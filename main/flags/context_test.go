@@ -0,0 +1,21 @@
+package flags
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestNilStore(t *testing.T) {
+	var s *Store
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest("GET", "/", nil)
+
+	if s.Enabled(c, "beta") || s.VariantOf(c, "beta") != "" {
+		t.Fatal("got a flag on from a nil store")
+	}
+	if got := s.Evaluate("beta", Principal{UserID: "1"}); got.Enabled || got.Reason != ReasonNotFound {
+		t.Fatalf("got %+v, want the flag off as not found", got)
+	}
+}
@@ -0,0 +1,223 @@
+package flags
+
+import (
+	"hash/fnv"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Kind decides what a flag evaluates to
+type Kind string
+
+const (
+	// KindBoolean flags are on or off for everyone not matched by a rule
+	KindBoolean Kind = "boolean"
+	// KindPercentage flags are on for a stable share of users
+	KindPercentage Kind = "percentage"
+	// KindVariant flags assign each user one of several weighted variants
+	KindVariant Kind = "variant"
+)
+
+// Attributes rules can target
+const (
+	AttributeUserID = "user_id"
+	AttributeRole   = "role"
+	AttributeTenant = "tenant"
+)
+
+// Reasons an evaluation came out the way it did
+const (
+	ReasonNotFound = "not_found"
+	ReasonDisabled = "disabled"
+	ReasonRule     = "rule"
+	ReasonDefault  = "default"
+	ReasonRollout  = "rollout"
+)
+
+// Flag is a feature flag. A disabled flag is off for everyone, rules
+// included; otherwise the first matching rule wins and everyone else gets
+// the flag's default for its kind.
+type Flag struct {
+	Key         string    `json:"key"`
+	Description string    `json:"description,omitempty"`
+	Kind        Kind      `json:"kind"`
+	Enabled     bool      `json:"enabled"`
+	Percentage  int       `json:"percentage,omitempty"` // percentage flags: share of users, 0 to 100
+	Variants    []Variant `json:"variants,omitempty"`   // variant flags: weights add up to 100
+	Rules       []Rule    `json:"rules,omitempty"`
+	Version     int       `json:"version"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// Variant is one arm of a variant flag
+type Variant struct {
+	Name   string `json:"name"`
+	Weight int    `json:"weight"`
+}
+
+// Rule overrides the outcome for principals whose attribute is one of values
+type Rule struct {
+	Attribute string   `json:"attribute"`
+	Values    []string `json:"values"`
+	Enabled   bool     `json:"enabled"`           // outcome for boolean and percentage flags
+	Variant   string   `json:"variant,omitempty"` // outcome for variant flags
+}
+
+// Principal is who a flag is evaluated for. Any attribute may be empty;
+// percentage and variant flags treat a principal without a user ID as
+// outside the rollout.
+type Principal struct {
+	UserID string `json:"user_id,omitempty"`
+	Role   string `json:"role,omitempty"`
+	Tenant string `json:"tenant,omitempty"`
+}
+
+// Evaluation is the outcome of a flag for a principal
+type Evaluation struct {
+	Key     string `json:"key"`
+	Enabled bool   `json:"enabled"`
+	Variant string `json:"variant,omitempty"`
+	Reason  string `json:"reason"`
+}
+
+// ValidationError reports a flag that is well formed but inconsistent, in
+// the same field and rule terms as request validation
+type ValidationError struct {
+	Field string
+	Rule  string
+	Param string
+}
+
+func (e *ValidationError) Error() string {
+	return "invalid flag: " + e.Field + " failed " + e.Rule
+}
+
+// Validate checks what the request binding cannot: variant weights and
+// the variants rules refer to
+func (f *Flag) Validate() error {
+	if f.Kind != KindVariant {
+		return nil
+	}
+
+	if len(f.Variants) == 0 {
+		return &ValidationError{Field: "variants", Rule: "required"}
+	}
+
+	names := make([]string, 0, len(f.Variants))
+	total := 0
+	for i, v := range f.Variants {
+		for _, name := range names {
+			if name == v.Name {
+				return &ValidationError{Field: "variants[" + strconv.Itoa(i) + "].name", Rule: "unique"}
+			}
+		}
+		names = append(names, v.Name)
+		total += v.Weight
+	}
+	if total != 100 {
+		return &ValidationError{Field: "variants", Rule: "weights", Param: "100"}
+	}
+
+	for i, r := range f.Rules {
+		if !f.hasVariant(r.Variant) {
+			return &ValidationError{Field: "rules[" + strconv.Itoa(i) + "].variant", Rule: "oneof", Param: strings.Join(names, " ")}
+		}
+	}
+	return nil
+}
+
+// Evaluate decides the flag for a principal
+func (f *Flag) Evaluate(p Principal) Evaluation {
+	eval := Evaluation{Key: f.Key, Reason: ReasonDisabled}
+	if !f.Enabled {
+		return eval
+	}
+
+	for _, r := range f.Rules {
+		if r.matches(p) {
+			eval.Enabled, eval.Reason = r.Enabled, ReasonRule
+			if f.Kind == KindVariant {
+				eval.Enabled, eval.Variant = true, r.Variant
+			}
+			return eval
+		}
+	}
+
+	switch f.Kind {
+	case KindPercentage:
+		eval.Reason = ReasonRollout
+		eval.Enabled = p.UserID != "" && f.bucket(p.UserID) < f.Percentage
+	case KindVariant:
+		eval.Reason = ReasonRollout
+		if p.UserID == "" {
+			eval.Reason = ReasonDefault
+			eval.Enabled, eval.Variant = true, f.Variants[0].Name
+			return eval
+		}
+		bucket, cumulative := f.bucket(p.UserID), 0
+		for _, v := range f.Variants {
+			cumulative += v.Weight
+			if bucket < cumulative {
+				eval.Enabled, eval.Variant = true, v.Name
+				break
+			}
+		}
+	default:
+		eval.Enabled, eval.Reason = true, ReasonDefault
+	}
+	return eval
+}
+
+// TargetsAttribute reports whether any rule looks at the attribute, so
+// callers can skip loading attributes nobody needs
+func (f *Flag) TargetsAttribute(attribute string) bool {
+	for _, r := range f.Rules {
+		if r.Attribute == attribute {
+			return true
+		}
+	}
+	return false
+}
+
+func (f *Flag) hasVariant(name string) bool {
+	for _, v := range f.Variants {
+		if v.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// bucket places a user in 0-99. It is salted with the flag key so the same
+// users are not always first into every rollout, and it is stable so a
+// user keeps their outcome as the percentage grows.
+func (f *Flag) bucket(userID string) int {
+	h := fnv.New32a()
+	h.Write([]byte(f.Key))
+	h.Write([]byte{':'})
+	h.Write([]byte(userID))
+	return int(h.Sum32() % 100)
+}
+
+func (r *Rule) matches(p Principal) bool {
+	var value string
+	switch r.Attribute {
+	case AttributeUserID:
+		value = p.UserID
+	case AttributeRole:
+		value = p.Role
+	case AttributeTenant:
+		value = p.Tenant
+	}
+	if value == "" {
+		return false
+	}
+
+	for _, v := range r.Values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,296 @@
+package flags
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"golang-boilerplate/main/core"
+
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+const (
+	// flagsKey is the Redis hash of flag key to JSON-encoded flag
+	flagsKey = "flags"
+	// changesChannel carries the key of every flag that was written or deleted
+	changesChannel = "flags:changed"
+	// maxWriteAttempts bounds retries when another writer changes the flags
+	// between reading the current version and writing the new one
+	maxWriteAttempts = 3
+)
+
+// ErrNotFound is returned for a flag that does not exist
+var ErrNotFound = errors.New("flag not found")
+
+// Store keeps flags in Redis and serves evaluations from a local copy. Every
+// write is announced on a pub/sub channel so other replicas reload the flag,
+// and the whole set is reloaded periodically in case a notification was
+// missed.
+type Store struct {
+	mu     sync.RWMutex
+	flags  map[string]*Flag
+	redis  *redis.Client
+	pubsub *redis.PubSub
+	stop   chan struct{}
+	done   chan struct{}
+	users  *core.UserService
+	logger *zap.Logger
+}
+
+// NewStore creates an empty store. Users are loaded to evaluate flags with
+// role rules for a request.
+func NewStore(users *core.UserService, logger *zap.Logger) *Store {
+	return &Store{
+		flags:  make(map[string]*Flag),
+		stop:   make(chan struct{}),
+		done:   make(chan struct{}),
+		users:  users,
+		logger: logger,
+	}
+}
+
+// Start loads the flags and follows changes. Until it is called, or if rdb
+// is nil, flags only live in this process.
+func (s *Store) Start(ctx context.Context, rdb *redis.Client, refreshInterval time.Duration) error {
+	if rdb == nil {
+		return nil
+	}
+
+	s.redis = rdb
+	s.pubsub = s.redis.Subscribe(ctx, changesChannel)
+	if _, err := s.pubsub.Receive(ctx); err != nil {
+		return fmt.Errorf("could not subscribe to flag changes: %w", err)
+	}
+	if err := s.reload(ctx); err != nil {
+		s.pubsub.Close()
+		return fmt.Errorf("could not load flags: %w", err)
+	}
+
+	go s.run(refreshInterval)
+	return nil
+}
+
+// Stop stops following changes. The local copy keeps serving evaluations.
+func (s *Store) Stop() {
+	if s.pubsub == nil {
+		return
+	}
+	close(s.stop)
+	s.pubsub.Close()
+	<-s.done
+}
+
+func (s *Store) run(refreshInterval time.Duration) {
+	defer close(s.done)
+
+	ticker := time.NewTicker(refreshInterval)
+	defer ticker.Stop()
+
+	changes := s.pubsub.Channel()
+	for {
+		select {
+		case <-s.stop:
+			return
+		case msg, ok := <-changes:
+			if !ok {
+				return
+			}
+			if err := s.reloadFlag(context.Background(), msg.Payload); err != nil {
+				s.logger.Warn("could not reload flag", zap.String("key", msg.Payload), zap.Error(err))
+			}
+		case <-ticker.C:
+			if err := s.reload(context.Background()); err != nil {
+				s.logger.Warn("could not refresh flags", zap.Error(err))
+			}
+		}
+	}
+}
+
+// reload replaces the local copy with every flag in Redis
+func (s *Store) reload(ctx context.Context) error {
+	values, err := s.redis.HGetAll(ctx, flagsKey).Result()
+	if err != nil {
+		return err
+	}
+
+	flags := make(map[string]*Flag, len(values))
+	for key, value := range values {
+		flag, err := decode(value)
+		if err != nil {
+			s.logger.Warn("skipping malformed flag", zap.String("key", key), zap.Error(err))
+			continue
+		}
+		flags[key] = flag
+	}
+
+	s.mu.Lock()
+	s.flags = flags
+	s.mu.Unlock()
+	return nil
+}
+
+// reloadFlag refreshes one flag in the local copy, dropping it if it was
+// deleted
+func (s *Store) reloadFlag(ctx context.Context, key string) error {
+	value, err := s.redis.HGet(ctx, flagsKey, key).Result()
+	if errors.Is(err, redis.Nil) {
+		s.cache(key, nil)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	flag, err := decode(value)
+	if err != nil {
+		return err
+	}
+	s.cache(key, flag)
+	return nil
+}
+
+func (s *Store) cache(key string, flag *Flag) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if flag == nil {
+		delete(s.flags, key)
+	} else {
+		s.flags[key] = flag
+	}
+}
+
+// Get returns a flag from the local copy. The flag is shared and must not be
+// modified.
+func (s *Store) Get(key string) (*Flag, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	flag, ok := s.flags[key]
+	return flag, ok
+}
+
+// List returns every flag, ordered by key
+func (s *Store) List() []*Flag {
+	s.mu.RLock()
+	flags := make([]*Flag, 0, len(s.flags))
+	for _, flag := range s.flags {
+		flags = append(flags, flag)
+	}
+	s.mu.RUnlock()
+
+	sort.Slice(flags, func(i, j int) bool { return flags[i].Key < flags[j].Key })
+	return flags
+}
+
+// Put creates or replaces a flag, setting its version and update time
+func (s *Store) Put(ctx context.Context, flag *Flag) error {
+	if err := flag.Validate(); err != nil {
+		return err
+	}
+	flag.UpdatedAt = time.Now().UTC()
+
+	if s.redis == nil {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		flag.Version = 1
+		if current, ok := s.flags[flag.Key]; ok {
+			flag.Version = current.Version + 1
+		}
+		s.flags[flag.Key] = flag
+		return nil
+	}
+
+	// Bump the version against what is in Redis, not the local copy, so
+	// concurrent writers on different replicas cannot reuse a version
+	write := func(tx *redis.Tx) error {
+		flag.Version = 1
+		value, err := tx.HGet(ctx, flagsKey, flag.Key).Result()
+		switch {
+		case err == nil:
+			if current, err := decode(value); err == nil {
+				flag.Version = current.Version + 1
+			}
+		case !errors.Is(err, redis.Nil):
+			return err
+		}
+
+		encoded, err := json.Marshal(flag)
+		if err != nil {
+			return err
+		}
+		_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			pipe.HSet(ctx, flagsKey, flag.Key, encoded)
+			pipe.Publish(ctx, changesChannel, flag.Key)
+			return nil
+		})
+		return err
+	}
+
+	var err error
+	for attempt := 0; attempt < maxWriteAttempts; attempt++ {
+		if err = s.redis.Watch(ctx, write, flagsKey); !errors.Is(err, redis.TxFailedErr) {
+			break
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	s.cache(flag.Key, flag)
+	return nil
+}
+
+// Delete removes a flag
+func (s *Store) Delete(ctx context.Context, key string) error {
+	if s.redis == nil {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if _, ok := s.flags[key]; !ok {
+			return ErrNotFound
+		}
+		delete(s.flags, key)
+		return nil
+	}
+
+	var deleted *redis.IntCmd
+	_, err := s.redis.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		deleted = pipe.HDel(ctx, flagsKey, key)
+		pipe.Publish(ctx, changesChannel, key)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	s.cache(key, nil)
+	if deleted.Val() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// Evaluate decides a flag for a principal. Unknown flags, and every flag of
+// a nil store, are off.
+func (s *Store) Evaluate(key string, p Principal) Evaluation {
+	if s == nil {
+		return Evaluation{Key: key, Reason: ReasonNotFound}
+	}
+	flag, ok := s.Get(key)
+	if !ok {
+		return Evaluation{Key: key, Reason: ReasonNotFound}
+	}
+	return flag.Evaluate(p)
+}
+
+func decode(value string) (*Flag, error) {
+	var flag Flag
+	if err := json.Unmarshal([]byte(value), &flag); err != nil {
+		return nil, err
+	}
+	return &flag, nil
+}
@@ -0,0 +1,90 @@
+package gql
+
+import (
+	"fmt"
+
+	"github.com/vektah/gqlparser/v2/ast"
+	"github.com/vektah/gqlparser/v2/parser"
+)
+
+// complexity estimates the cost of an operation before it runs. Every field
+// costs one, and the selections under a field that takes a list of IDs are
+// multiplied by the number of IDs requested.
+func complexity(query, operationName string, variables map[string]interface{}) (int, error) {
+	doc, err := parser.ParseQuery(&ast.Source{Input: query})
+	if err != nil {
+		return 0, err
+	}
+
+	var op *ast.OperationDefinition
+	if operationName != "" {
+		op = doc.Operations.ForName(operationName)
+	} else if len(doc.Operations) == 1 {
+		op = doc.Operations[0]
+	}
+	if op == nil {
+		// Let the executor report the missing or ambiguous operation
+		return 0, nil
+	}
+
+	c := &costCounter{doc: doc, variables: variables, visiting: make(map[string]bool)}
+	return c.selectionSet(op.SelectionSet)
+}
+
+type costCounter struct {
+	doc       *ast.QueryDocument
+	variables map[string]interface{}
+	visiting  map[string]bool
+}
+
+func (c *costCounter) selectionSet(set ast.SelectionSet) (int, error) {
+	total := 0
+	for _, selection := range set {
+		var cost int
+		var err error
+
+		switch sel := selection.(type) {
+		case *ast.Field:
+			cost, err = c.selectionSet(sel.SelectionSet)
+			cost = 1 + cost*c.multiplier(sel)
+		case *ast.InlineFragment:
+			cost, err = c.selectionSet(sel.SelectionSet)
+		case *ast.FragmentSpread:
+			fragment := c.doc.Fragments.ForName(sel.Name)
+			if fragment == nil {
+				continue
+			}
+			if c.visiting[sel.Name] {
+				return 0, fmt.Errorf("fragment %q is cyclic", sel.Name)
+			}
+			c.visiting[sel.Name] = true
+			cost, err = c.selectionSet(fragment.SelectionSet)
+			delete(c.visiting, sel.Name)
+		}
+
+		if err != nil {
+			return 0, err
+		}
+		total += cost
+	}
+	return total, nil
+}
+
+// multiplier is the number of items a field resolves to
+func (c *costCounter) multiplier(field *ast.Field) int {
+	for _, arg := range field.Arguments {
+		if arg.Value == nil {
+			continue
+		}
+
+		switch arg.Value.Kind {
+		case ast.ListValue:
+			return max(len(arg.Value.Children), 1)
+		case ast.Variable:
+			if list, ok := c.variables[arg.Value.Raw].([]interface{}); ok {
+				return max(len(list), 1)
+			}
+		}
+	}
+	return 1
+}
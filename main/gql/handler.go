@@ -0,0 +1,54 @@
+package gql
+
+import (
+	_ "embed"
+	"golang-boilerplate/main/config"
+	"golang-boilerplate/main/core"
+	"golang-boilerplate/main/i18n"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/graph-gophers/graphql-go"
+	gqlerrors "github.com/graph-gophers/graphql-go/errors"
+)
+
+//go:embed schema.graphql
+var schemaSDL string
+
+type request struct {
+	Query         string                 `json:"query" binding:"required"`
+	OperationName string                 `json:"operationName"`
+	Variables     map[string]interface{} `json:"variables"`
+}
+
+// Handler executes GraphQL queries. It is mounted behind AuthMiddleware and
+// resolves the caller from the principal that middleware stores.
+func Handler(users *core.UserService, cfg config.GraphQLConfig) gin.HandlerFunc {
+	schema := graphql.MustParseSchema(schemaSDL, &rootResolver{},
+		graphql.MaxDepth(cfg.MaxDepth),
+	)
+	maxComplexity := cfg.MaxComplexity
+
+	return func(c *gin.Context) {
+		var req request
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, i18n.Error(c, "graphql.invalid_request"))
+			return
+		}
+
+		cost, err := complexity(req.Query, req.OperationName, req.Variables)
+		if err != nil {
+			c.JSON(http.StatusOK, &graphql.Response{Errors: []*gqlerrors.QueryError{gqlerrors.Errorf("%s", err)}})
+			return
+		}
+		if maxComplexity > 0 && cost > maxComplexity {
+			c.JSON(http.StatusOK, &graphql.Response{Errors: []*gqlerrors.QueryError{
+				gqlerrors.Errorf("query complexity %d exceeds the limit of %d", cost, maxComplexity),
+			}})
+			return
+		}
+
+		ctx := withLoaders(c.Request.Context(), newLoaders(users))
+		c.JSON(http.StatusOK, schema.Exec(ctx, req.Query, req.OperationName, req.Variables))
+	}
+}
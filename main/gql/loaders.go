@@ -0,0 +1,41 @@
+package gql
+
+import (
+	"context"
+	"golang-boilerplate/main/core"
+	"golang-boilerplate/main/models"
+
+	"github.com/graph-gophers/dataloader/v7"
+)
+
+type loadersKey struct{}
+
+// loaders batch the lookups made while resolving a single request, so a
+// query touching N users issues one query instead of N
+type loaders struct {
+	users *dataloader.Loader[uint, *models.User]
+}
+
+func newLoaders(users *core.UserService) *loaders {
+	batchUsers := func(ctx context.Context, ids []uint) []*dataloader.Result[*models.User] {
+		results := make([]*dataloader.Result[*models.User], len(ids))
+
+		byID, err := users.GetUsers(ctx, ids)
+		for i, id := range ids {
+			results[i] = &dataloader.Result[*models.User]{Data: byID[id], Error: err}
+		}
+		return results
+	}
+
+	return &loaders{
+		users: dataloader.NewBatchedLoader(batchUsers),
+	}
+}
+
+func withLoaders(ctx context.Context, l *loaders) context.Context {
+	return context.WithValue(ctx, loadersKey{}, l)
+}
+
+func loadersFrom(ctx context.Context) *loaders {
+	return ctx.Value(loadersKey{}).(*loaders)
+}
@@ -0,0 +1,103 @@
+package gql
+
+import (
+	"context"
+	"errors"
+	"golang-boilerplate/main/core"
+	"golang-boilerplate/main/models"
+	"strconv"
+	"time"
+
+	"github.com/graph-gophers/graphql-go"
+)
+
+var errUnauthenticated = errors.New("user not authenticated")
+
+type rootResolver struct{}
+
+func (r *rootResolver) Me(ctx context.Context) (*userResolver, error) {
+	userID, ok := core.UserIDFromContext(ctx)
+	if !ok {
+		return nil, errUnauthenticated
+	}
+
+	user, err := loadersFrom(ctx).users.Load(ctx, userID)()
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, core.ErrUserNotFound
+	}
+	return &userResolver{user: user}, nil
+}
+
+func (r *rootResolver) User(ctx context.Context, args struct{ ID graphql.ID }) (*userResolver, error) {
+	id, err := parseID(args.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := loadersFrom(ctx).users.Load(ctx, id)()
+	if err != nil || user == nil {
+		return nil, err
+	}
+	return &userResolver{user: user}, nil
+}
+
+func (r *rootResolver) Users(ctx context.Context, args struct{ IDs []graphql.ID }) ([]*userResolver, error) {
+	ids := make([]uint, len(args.IDs))
+	for i, raw := range args.IDs {
+		id, err := parseID(raw)
+		if err != nil {
+			return nil, err
+		}
+		ids[i] = id
+	}
+
+	users, errs := loadersFrom(ctx).users.LoadMany(ctx, ids)()
+	resolvers := make([]*userResolver, len(users))
+	for i, user := range users {
+		if errs != nil && errs[i] != nil {
+			return nil, errs[i]
+		}
+		if user != nil {
+			resolvers[i] = &userResolver{user: user}
+		}
+	}
+	return resolvers, nil
+}
+
+type userResolver struct {
+	user *models.User
+}
+
+func (r *userResolver) ID() graphql.ID {
+	return graphql.ID(strconv.FormatUint(uint64(r.user.ID), 10))
+}
+
+func (r *userResolver) Username() string {
+	return r.user.Username
+}
+
+func (r *userResolver) Email(ctx context.Context) *string {
+	if viewer, ok := core.UserIDFromContext(ctx); !ok || viewer != r.user.ID {
+		return nil
+	}
+	return &r.user.Email
+}
+
+func (r *userResolver) CreatedAt() string {
+	return r.user.CreatedAt.Format(time.RFC3339)
+}
+
+func (r *userResolver) UpdatedAt() string {
+	return r.user.UpdatedAt.Format(time.RFC3339)
+}
+
+func parseID(id graphql.ID) (uint, error) {
+	n, err := strconv.ParseUint(string(id), 10, 64)
+	if err != nil || n == 0 {
+		return 0, errors.New("invalid id: " + string(id))
+	}
+	return uint(n), nil
+}
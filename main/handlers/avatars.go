@@ -0,0 +1,116 @@
+package handlers
+
+import (
+	"errors"
+	"golang-boilerplate/main/core"
+	"golang-boilerplate/main/i18n"
+	"golang-boilerplate/main/negotiate"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// avatarField is the multipart form field holding the image
+const avatarField = "avatar"
+
+var (
+	errAvatarMissing  = errors.New("avatar field missing")
+	errAvatarTooLarge = errors.New("avatar too large")
+)
+
+// UploadAvatarHandler replaces your avatar with the image sent in the
+// "avatar" field of a multipart/form-data body. The image type is sniffed
+// from its contents; JPEG, PNG, GIF and WebP are accepted.
+func (h *Handler) UploadAvatarHandler(c *gin.Context) {
+	var params UserParams
+	if err := c.ShouldBindUri(&params); err != nil {
+		respondValidationError(c, err)
+		return
+	}
+
+	maxMB := h.Config.Avatars.MaxUploadMB
+	data, err := readAvatar(c, maxMB<<20)
+	switch {
+	case errors.Is(err, errAvatarTooLarge):
+		respondWithArgs(c, http.StatusRequestEntityTooLarge, "avatar.too_large", map[string]string{
+			"max": strconv.FormatInt(maxMB, 10),
+		})
+		return
+	case err != nil:
+		negotiate.Respond(c, http.StatusBadRequest, i18n.Error(c, "avatar.missing_file"))
+		return
+	}
+
+	ctx := c.Request.Context()
+	actorID, _ := core.UserIDFromContext(ctx)
+	user, err := h.Avatars.SetAvatar(ctx, actorID, params.ID, data)
+	if err != nil {
+		respondAvatarError(c, err)
+		return
+	}
+
+	h.respondUserUpdated(c, user, actorID)
+}
+
+// DeleteAvatarHandler removes your avatar
+func (h *Handler) DeleteAvatarHandler(c *gin.Context) {
+	var params UserParams
+	if err := c.ShouldBindUri(&params); err != nil {
+		respondValidationError(c, err)
+		return
+	}
+
+	ctx := c.Request.Context()
+	actorID, _ := core.UserIDFromContext(ctx)
+	user, err := h.Avatars.RemoveAvatar(ctx, actorID, params.ID)
+	if err != nil {
+		respondUserError(c, err)
+		return
+	}
+
+	h.respondUserUpdated(c, user, actorID)
+}
+
+// readAvatar streams the multipart body and returns the avatar part, reading
+// at most maxBytes of it. Other parts are skipped without being buffered.
+func readAvatar(c *gin.Context, maxBytes int64) ([]byte, error) {
+	reader, err := c.Request.MultipartReader()
+	if err != nil {
+		return nil, errAvatarMissing
+	}
+
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			return nil, errAvatarMissing
+		}
+		if err != nil {
+			return nil, err
+		}
+		if part.FormName() != avatarField {
+			continue
+		}
+
+		data, err := io.ReadAll(io.LimitReader(part, maxBytes+1))
+		if err != nil {
+			return nil, err
+		}
+		if int64(len(data)) > maxBytes {
+			return nil, errAvatarTooLarge
+		}
+		return data, nil
+	}
+}
+
+func respondAvatarError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, core.ErrUnsupportedImage):
+		negotiate.Respond(c, http.StatusUnsupportedMediaType, i18n.Error(c, "avatar.unsupported_type"))
+	case errors.Is(err, core.ErrImageTooLarge):
+		negotiate.Respond(c, http.StatusUnprocessableEntity, i18n.Error(c, "avatar.too_many_pixels"))
+	default:
+		respondUserError(c, err)
+	}
+}
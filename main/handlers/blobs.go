@@ -0,0 +1,51 @@
+package handlers
+
+import (
+	"errors"
+	"golang-boilerplate/main/i18n"
+	"golang-boilerplate/pkg/blob"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// BlobHandler serves objects from stores whose signed URLs point back at the
+// application. The expires and signature query parameters are checked
+// before anything is read.
+func (h *Handler) BlobHandler(c *gin.Context) {
+	key := strings.TrimPrefix(c.Param("key"), "/")
+	expires := c.Query("expires")
+
+	verifier, ok := h.Blobs.(blob.URLVerifier)
+	if !ok || !verifier.VerifyURL(key, expires, c.Query("signature")) {
+		c.JSON(http.StatusForbidden, i18n.Error(c, "blob.link_invalid"))
+		return
+	}
+
+	reader, info, err := h.Blobs.Get(c.Request.Context(), key)
+	if err != nil {
+		if errors.Is(err, blob.ErrNotFound) {
+			c.JSON(http.StatusNotFound, i18n.Error(c, "blob.not_found"))
+			return
+		}
+		log.Printf("could not read blob %s: %v", key, err)
+		c.JSON(http.StatusInternalServerError, i18n.Error(c, "internal.error"))
+		return
+	}
+	defer reader.Close()
+
+	// Caches may keep the object until the link expires, but no longer
+	expiresAt, _ := strconv.ParseInt(expires, 10, 64)
+	maxAge := time.Until(time.Unix(expiresAt, 0)) / time.Second
+	c.Header("Cache-Control", "private, max-age="+strconv.FormatInt(int64(maxAge), 10))
+
+	contentType := info.ContentType
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	c.DataFromReader(http.StatusOK, info.Size, contentType, reader, nil)
+}
@@ -0,0 +1,163 @@
+package handlers
+
+import (
+	"errors"
+	"golang-boilerplate/main/flags"
+	"golang-boilerplate/main/i18n"
+	"golang-boilerplate/main/negotiate"
+	"golang-boilerplate/main/validation"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// FlagParams identifies a flag in the path
+type FlagParams struct {
+	Key string `uri:"key" binding:"required,flag_key"`
+}
+
+// PutFlagRequest creates or replaces a flag. Percentage is used by
+// percentage flags and variants by variant flags; rules apply to every kind.
+type PutFlagRequest struct {
+	Description string               `json:"description" binding:"max=255"`
+	Kind        string               `json:"kind" binding:"required,oneof=boolean percentage variant"`
+	Enabled     bool                 `json:"enabled"`
+	Percentage  int                  `json:"percentage" binding:"min=0,max=100"`
+	Variants    []FlagVariantRequest `json:"variants" binding:"max=20,dive"`
+	Rules       []FlagRuleRequest    `json:"rules" binding:"max=50,dive"`
+}
+
+// FlagVariantRequest is one arm of a variant flag
+type FlagVariantRequest struct {
+	Name   string `json:"name" binding:"required,flag_key"`
+	Weight int    `json:"weight" binding:"min=0,max=100"`
+}
+
+// FlagRuleRequest targets principals by user ID, role or tenant
+type FlagRuleRequest struct {
+	Attribute string   `json:"attribute" binding:"required,oneof=user_id role tenant"`
+	Values    []string `json:"values" binding:"required,min=1,max=1000,dive,required,max=255"`
+	Enabled   bool     `json:"enabled"`
+	Variant   string   `json:"variant"`
+}
+
+// EvaluateFlagRequest is the principal to evaluate a flag for
+type EvaluateFlagRequest struct {
+	UserID string `json:"user_id" binding:"max=255"`
+	Role   string `json:"role" binding:"max=32"`
+	Tenant string `json:"tenant" binding:"max=255"`
+}
+
+// ListFlagsHandler lists every flag
+func (h *Handler) ListFlagsHandler(c *gin.Context) {
+	negotiate.Respond(c, http.StatusOK, h.Flags.List())
+}
+
+// GetFlagHandler returns a flag
+func (h *Handler) GetFlagHandler(c *gin.Context) {
+	var params FlagParams
+	if err := c.ShouldBindUri(&params); err != nil {
+		respondValidationError(c, err)
+		return
+	}
+
+	flag, ok := h.Flags.Get(params.Key)
+	if !ok {
+		negotiate.Respond(c, http.StatusNotFound, i18n.Error(c, "flag.not_found"))
+		return
+	}
+	negotiate.Respond(c, http.StatusOK, flag)
+}
+
+// PutFlagHandler creates or replaces a flag. Every replica picks the change
+// up within moments.
+func (h *Handler) PutFlagHandler(c *gin.Context) {
+	var params FlagParams
+	if err := c.ShouldBindUri(&params); err != nil {
+		respondValidationError(c, err)
+		return
+	}
+
+	var req PutFlagRequest
+	if !bind(c, &req) {
+		return
+	}
+
+	flag := &flags.Flag{
+		Key:         params.Key,
+		Description: req.Description,
+		Kind:        flags.Kind(req.Kind),
+		Enabled:     req.Enabled,
+		Percentage:  req.Percentage,
+	}
+	for _, v := range req.Variants {
+		flag.Variants = append(flag.Variants, flags.Variant{Name: v.Name, Weight: v.Weight})
+	}
+	for _, r := range req.Rules {
+		flag.Rules = append(flag.Rules, flags.Rule{Attribute: r.Attribute, Values: r.Values, Enabled: r.Enabled, Variant: r.Variant})
+	}
+
+	if err := h.Flags.Put(c.Request.Context(), flag); err != nil {
+		respondFlagError(c, err)
+		return
+	}
+	negotiate.Respond(c, http.StatusOK, flag)
+}
+
+// DeleteFlagHandler removes a flag. Evaluations of it are off from then on.
+func (h *Handler) DeleteFlagHandler(c *gin.Context) {
+	var params FlagParams
+	if err := c.ShouldBindUri(&params); err != nil {
+		respondValidationError(c, err)
+		return
+	}
+
+	if err := h.Flags.Delete(c.Request.Context(), params.Key); err != nil {
+		respondFlagError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// EvaluateFlagHandler shows what a flag decides for a principal, so admins
+// can check targeting before rolling it out
+func (h *Handler) EvaluateFlagHandler(c *gin.Context) {
+	var params FlagParams
+	if err := c.ShouldBindUri(&params); err != nil {
+		respondValidationError(c, err)
+		return
+	}
+
+	var req EvaluateFlagRequest
+	if !bind(c, &req) {
+		return
+	}
+
+	if _, ok := h.Flags.Get(params.Key); !ok {
+		negotiate.Respond(c, http.StatusNotFound, i18n.Error(c, "flag.not_found"))
+		return
+	}
+	negotiate.Respond(c, http.StatusOK, h.Flags.Evaluate(params.Key, flags.Principal{
+		UserID: req.UserID,
+		Role:   req.Role,
+		Tenant: req.Tenant,
+	}))
+}
+
+func respondFlagError(c *gin.Context, err error) {
+	var invalid *flags.ValidationError
+	switch {
+	case errors.As(err, &invalid):
+		negotiate.Respond(c, http.StatusBadRequest, ValidationErrorResponse{
+			Error:   i18n.Message(c, "validation.failed"),
+			Code:    "validation.failed",
+			Details: []validation.FieldError{validation.NewFieldError(i18n.Locale(c), invalid.Field, invalid.Rule, invalid.Param)},
+		})
+	case errors.Is(err, flags.ErrNotFound):
+		negotiate.Respond(c, http.StatusNotFound, i18n.Error(c, "flag.not_found"))
+	default:
+		log.Printf("flag request failed: %v", err)
+		negotiate.Respond(c, http.StatusInternalServerError, i18n.Error(c, "internal.error"))
+	}
+}
@@ -2,153 +2,310 @@ package handlers
 
 import (
 	"context"
+	"database/sql"
+	"errors"
 	"golang-boilerplate/main/config"
-	"golang-boilerplate/main/models"
+	"golang-boilerplate/main/core"
+	"golang-boilerplate/main/flags"
+	"golang-boilerplate/main/i18n"
+	"golang-boilerplate/main/imports"
+	"golang-boilerplate/main/negotiate"
+	"golang-boilerplate/main/realtime"
 	"golang-boilerplate/main/repo"
-	"golang-boilerplate/main/service"
+	"golang-boilerplate/main/rpc/pb"
+	"golang-boilerplate/main/validation"
+	"golang-boilerplate/main/webhooks"
+	"golang-boilerplate/pkg/blob"
+	"log"
 	"net/http"
 	"time"
 
 	"github.com/gin-gonic/gin"
-	"github.com/golang-jwt/jwt/v5"
-	"golang.org/x/crypto/bcrypt"
+	"github.com/redis/go-redis/v9"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/wrapperspb"
 )
 
-var userRepo = repo.NewUserRepo()
+// Deps are the services the handlers use
+type Deps struct {
+	Config      *config.Config
+	DB          *sql.DB
+	Redis       *redis.Client
+	Blobs       blob.Store
+	Users       repo.UserRepository
+	Auth        *core.AuthService
+	UserService *core.UserService
+	Avatars     *core.AvatarService
+	Hub         *realtime.Hub
+	// Flags decides feature flags for the caller; with the flags module
+	// disabled every flag is off
+	Flags *flags.Store
+	// HealthChecks are reported by the health endpoint next to the
+	// database and Redis, by name
+	HealthChecks map[string]func(context.Context) error
+
+	// The services of a single module, set by that module on its own copy
+	// of the handlers with With
+	Webhooks   *repo.WebhookRepo
+	Dispatcher *webhooks.Dispatcher
+	Importer   *imports.Importer
+}
+
+// Handler serves the HTTP API. Its methods are gin handlers.
+type Handler struct {
+	Deps
+}
+
+// New creates the handlers for the given services
+func New(deps Deps) *Handler {
+	return &Handler{Deps: deps}
+}
+
+// With returns a copy of the handlers whose services are changed by set,
+// which is how a module adds the services it builds itself
+func (h *Handler) With(set func(deps *Deps)) *Handler {
+	deps := h.Deps
+	set(&deps)
+	return New(deps)
+}
+
+// RegisterRequest is the body of a registration request
+type RegisterRequest struct {
+	Username string `json:"username" binding:"required,username"`
+	Email    string `json:"email" binding:"required,email_address"`
+	Password string `json:"password" binding:"required,password"`
+}
+
+// LoginRequest is the body of a login request
+type LoginRequest struct {
+	Username string `json:"username" binding:"required"`
+	Password string `json:"password" binding:"required"`
+}
+
+func (r *RegisterRequest) NewProto() proto.Message {
+	return &pb.RegisterRequest{}
+}
+
+func (r *RegisterRequest) FromProto(msg proto.Message) {
+	m := msg.(*pb.RegisterRequest)
+	*r = RegisterRequest{Username: m.GetUsername(), Email: m.GetEmail(), Password: m.GetPassword()}
+}
+
+func (r *LoginRequest) NewProto() proto.Message {
+	return &pb.LoginRequest{}
+}
+
+func (r *LoginRequest) FromProto(msg proto.Message) {
+	m := msg.(*pb.LoginRequest)
+	*r = LoginRequest{Username: m.GetUsername(), Password: m.GetPassword()}
+}
+
+// TokenResponse carries a signed JWT
+type TokenResponse struct {
+	Token string `json:"token"`
+}
+
+func (r TokenResponse) Proto() proto.Message {
+	return &pb.LoginResponse{Token: r.Token}
+}
+
+// MessageResponse is a plain acknowledgement
+type MessageResponse struct {
+	Message string `json:"message"`
+}
+
+// Proto sends the message as a google.protobuf.StringValue
+func (r MessageResponse) Proto() proto.Message {
+	return wrapperspb.String(r.Message)
+}
+
+// HealthResponse reports the status of the service and its dependencies
+type HealthResponse struct {
+	Status    string            `json:"status"`
+	Service   string            `json:"service"`
+	Timestamp string            `json:"timestamp"`
+	Checks    map[string]string `json:"checks"`
+}
+
+// ProtectedResponse is returned by the example protected route
+type ProtectedResponse struct {
+	Message string      `json:"message"`
+	UserID  interface{} `json:"user_id"`
+}
+
+// ErrorResponse is the body of every error response
+type ErrorResponse struct {
+	Error string `json:"error"`
+	Code  string `json:"code"`
+}
+
+// ValidationErrorResponse lists the fields that failed validation
+type ValidationErrorResponse struct {
+	Error   string                  `json:"error"`
+	Code    string                  `json:"code"`
+	Details []validation.FieldError `json:"details"`
+}
 
 // HealthHandler returns a 200 OK response if the service is healthy
-func HealthHandler(c *gin.Context) {
-	checks := checkServices()
-	health := map[string]interface{}{
-		"status":    "ok",
-		"service":   "golang-boilerplate",
-		"timestamp": time.Now().Format(time.RFC3339),
-		"checks":    checks,
+func (h *Handler) HealthHandler(c *gin.Context) {
+	checks := h.checkServices(c.Request.Context())
+	health := HealthResponse{
+		Status:    "ok",
+		Service:   "golang-boilerplate",
+		Timestamp: time.Now().Format(time.RFC3339),
+		Checks:    checks,
 	}
 
 	status := http.StatusOK
 	for _, check := range checks {
 		if check == "error" {
 			status = http.StatusServiceUnavailable
-			health["status"] = "degraded"
+			health.Status = "degraded"
 			break
 		}
 	}
 
-	c.JSON(status, health)
+	negotiate.Respond(c, status, health)
 }
 
-func checkServices() map[string]string {
+func (h *Handler) checkServices(ctx context.Context) map[string]string {
 	checks := make(map[string]string)
 
 	// Check database
-	if service.DB == nil {
+	if h.DB == nil {
 		checks["database"] = "not initialized"
-	} else if err := service.DB.Ping(); err != nil {
+	} else if err := h.DB.Ping(); err != nil {
 		checks["database"] = "error"
 	} else {
 		checks["database"] = "ok"
 	}
 
 	// Check Redis
-	if service.RedisClient == nil {
+	if h.Redis == nil {
 		checks["redis"] = "not initialized"
-	} else if err := service.RedisClient.Ping(context.Background()).Err(); err != nil {
+	} else if err := h.Redis.Ping(context.Background()).Err(); err != nil {
 		checks["redis"] = "error"
 	} else {
 		checks["redis"] = "ok"
 	}
 
+	for name, check := range h.HealthChecks {
+		if err := check(ctx); err != nil {
+			log.Printf("health check %s failed: %v", name, err)
+			checks[name] = "error"
+		} else {
+			checks[name] = "ok"
+		}
+	}
+
 	return checks
 }
 
 // PingHandler returns a simple pong response
-func PingHandler(c *gin.Context) {
-	c.JSON(http.StatusOK, gin.H{
-		"message": "pong",
-	})
+func (h *Handler) PingHandler(c *gin.Context) {
+	negotiate.Respond(c, http.StatusOK, MessageResponse{Message: "pong"})
 }
 
 // RegisterHandler handles user registration
-func RegisterHandler(c *gin.Context) {
-	var req struct {
-		Username string `json:"username" binding:"required"`
-		Password string `json:"password" binding:"required"`
-	}
-
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
-		return
-	}
-
-	// Hash password
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to hash password"})
+func (h *Handler) RegisterHandler(c *gin.Context) {
+	var req RegisterRequest
+	if !bind(c, &req) {
 		return
 	}
 
-	user := &models.User{
-		Username:  req.Username,
-		Password:  string(hashedPassword),
-		CreatedAt: time.Now(),
-		UpdatedAt: time.Now(),
-	}
-
-	if err := userRepo.CreateUser(user); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create user"})
+	if _, err := h.Auth.Register(c.Request.Context(), core.RegisterInput(req)); err != nil {
+		switch {
+		case errors.Is(err, core.ErrUserExists):
+			negotiate.Respond(c, http.StatusConflict, i18n.Error(c, "user.exists"))
+		case canceled(err):
+			respondCanceled(c, err)
+		default:
+			negotiate.Respond(c, http.StatusInternalServerError, i18n.Error(c, "user.create_failed"))
+		}
 		return
 	}
 
-	c.JSON(http.StatusCreated, gin.H{"message": "User registered successfully"})
+	negotiate.Respond(c, http.StatusCreated, MessageResponse{Message: i18n.Message(c, "user.registered")})
 }
 
 // LoginHandler handles user login
-func LoginHandler(c *gin.Context) {
-	var req struct {
-		Username string `json:"username" binding:"required"`
-		Password string `json:"password" binding:"required"`
-	}
-
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+func (h *Handler) LoginHandler(c *gin.Context) {
+	var req LoginRequest
+	if !bind(c, &req) {
 		return
 	}
 
-	user, err := userRepo.GetUserByUsername(req.Username)
+	token, err := h.Auth.Login(c.Request.Context(), req.Username, req.Password)
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid credentials"})
+		switch {
+		case errors.Is(err, core.ErrInvalidCredentials):
+			negotiate.Respond(c, http.StatusUnauthorized, i18n.Error(c, "auth.invalid_credentials"))
+		case canceled(err):
+			respondCanceled(c, err)
+		default:
+			negotiate.Respond(c, http.StatusInternalServerError, i18n.Error(c, "auth.token_failed"))
+		}
 		return
 	}
 
-	// Check password
-	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(req.Password)); err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid credentials"})
+	negotiate.Respond(c, http.StatusOK, TokenResponse{Token: token})
+}
+
+// ProtectedHandler is an example protected route
+func (h *Handler) ProtectedHandler(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		negotiate.Respond(c, http.StatusUnauthorized, i18n.Error(c, "auth.unauthenticated"))
 		return
 	}
 
-	// Generate JWT token
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
-		"user_id": user.ID,
-		"exp":     time.Now().Add(time.Hour * 24).Unix(),
-	})
+	negotiate.Respond(c, http.StatusOK, ProtectedResponse{Message: i18n.Message(c, "protected.welcome"), UserID: userID})
+}
 
-	tokenString, err := token.SignedString([]byte(config.AppConfig.JWT.Secret))
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
-		return
-	}
+// statusClientClosedRequest is recorded for requests the client gave up on
+// before the response was ready. Nobody receives it.
+const statusClientClosedRequest = 499
 
-	c.JSON(http.StatusOK, gin.H{"token": tokenString})
+// canceled reports whether err comes from a query that was abandoned
+// because the request was cancelled or ran out of time
+func canceled(err error) bool {
+	var canceledErr *repo.CanceledError
+	return errors.As(err, &canceledErr)
 }
 
-// ProtectedHandler is an example protected route
-func ProtectedHandler(c *gin.Context) {
-	userID, exists := c.Get("user_id")
-	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+// respondCanceled answers a request whose query was abandoned: 504 if the
+// query timed out, 499 if the client went away
+func respondCanceled(c *gin.Context, err error) {
+	var canceledErr *repo.CanceledError
+	if errors.As(err, &canceledErr) && canceledErr.Timeout() {
+		log.Printf("query timed out: %v", err)
+		negotiate.Respond(c, http.StatusGatewayTimeout, i18n.Error(c, "request.timeout"))
 		return
 	}
+	c.AbortWithStatus(statusClientClosedRequest)
+}
 
-	c.JSON(http.StatusOK, gin.H{"message": "Welcome to protected route", "user_id": userID})
+// bind decodes and validates the request body in any format negotiate
+// supports, writing the error response if that fails
+func bind(c *gin.Context, obj interface{}) bool {
+	err := negotiate.Bind(c, obj)
+	if err == nil {
+		return true
+	}
+
+	if errors.Is(err, negotiate.ErrUnsupportedMediaType) {
+		negotiate.Respond(c, http.StatusUnsupportedMediaType, i18n.Error(c, "request.unsupported_media_type"))
+	} else {
+		respondValidationError(c, err)
+	}
+	return false
+}
+
+// respondValidationError writes a 400 with one entry per failed field rule
+func respondValidationError(c *gin.Context, err error) {
+	negotiate.Respond(c, http.StatusBadRequest, ValidationErrorResponse{
+		Error:   i18n.Message(c, "validation.failed"),
+		Code:    "validation.failed",
+		Details: validation.Errors(err, i18n.Locale(c)),
+	})
 }
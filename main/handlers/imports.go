@@ -0,0 +1,92 @@
+package handlers
+
+import (
+	"errors"
+	"golang-boilerplate/main/core"
+	"golang-boilerplate/main/i18n"
+	"golang-boilerplate/main/imports"
+	"golang-boilerplate/main/negotiate"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ImportParams identifies an import job in the path
+type ImportParams struct {
+	ID string `uri:"id" binding:"required,hexadecimal,len=32"`
+}
+
+// ImportUsersHandler starts a bulk import from a CSV or NDJSON upload sent
+// as the raw request body. CSV files need a header with username, email and
+// password columns; NDJSON lines are objects with the same fields. The
+// response is 202 with the job, whose status is polled at the Location.
+func (h *Handler) ImportUsersHandler(c *gin.Context) {
+	format, err := imports.FormatFromContentType(c.ContentType())
+	if err != nil {
+		c.JSON(http.StatusUnsupportedMediaType, i18n.Error(c, "import.unsupported_format"))
+		return
+	}
+
+	maxMB := h.Config.Imports.MaxUploadMB
+	body := http.MaxBytesReader(c.Writer, c.Request.Body, maxMB<<20)
+
+	ctx := c.Request.Context()
+	actorID, _ := core.UserIDFromContext(ctx)
+	job, err := h.Importer.Submit(ctx, format, body, actorID, i18n.Locale(c))
+
+	var headerErr *imports.HeaderError
+	var maxBytesErr *http.MaxBytesError
+	switch {
+	case err == nil:
+	case errors.As(err, &headerErr):
+		respondWithArgs(c, http.StatusBadRequest, "import.invalid_header", map[string]string{
+			"columns": strings.Join(headerErr.Missing, ", "),
+		})
+		return
+	case errors.As(err, &maxBytesErr):
+		respondWithArgs(c, http.StatusRequestEntityTooLarge, "import.too_large", map[string]string{
+			"max": strconv.FormatInt(maxMB, 10),
+		})
+		return
+	case errors.Is(err, imports.ErrNotRunning):
+		c.JSON(http.StatusServiceUnavailable, i18n.Error(c, "service.unavailable"))
+		return
+	default:
+		log.Printf("could not start user import: %v", err)
+		c.JSON(http.StatusInternalServerError, i18n.Error(c, "internal.error"))
+		return
+	}
+
+	c.Header("Location", strings.TrimSuffix(c.Request.URL.Path, "/")+"/"+job.ID)
+	c.JSON(http.StatusAccepted, job)
+}
+
+// ImportJobHandler reports the progress and row errors of an import
+func (h *Handler) ImportJobHandler(c *gin.Context) {
+	var params ImportParams
+	if err := c.ShouldBindUri(&params); err != nil {
+		respondValidationError(c, err)
+		return
+	}
+
+	job, err := h.Importer.Job(c.Request.Context(), params.ID)
+	if err != nil {
+		if errors.Is(err, imports.ErrJobNotFound) {
+			c.JSON(http.StatusNotFound, i18n.Error(c, "import.job_not_found"))
+			return
+		}
+		log.Printf("could not load import job: %v", err)
+		c.JSON(http.StatusInternalServerError, i18n.Error(c, "internal.error"))
+		return
+	}
+
+	c.JSON(http.StatusOK, job)
+}
+
+// respondWithArgs writes an error whose message has placeholders
+func respondWithArgs(c *gin.Context, status int, code string, args map[string]string) {
+	negotiate.Respond(c, status, ErrorResponse{Error: i18n.T(i18n.Locale(c), code, args), Code: code})
+}
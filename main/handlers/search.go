@@ -0,0 +1,77 @@
+package handlers
+
+import (
+	"golang-boilerplate/main/i18n"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SearchUsersQuery is a search over usernames and emails. Each word matches
+// as a prefix; misspellings and partial words are matched fuzzily.
+type SearchUsersQuery struct {
+	Q      string `form:"q" binding:"required,min=2,max=100"`
+	Limit  int    `form:"limit" binding:"omitempty,min=1,max=100"`
+	Offset int    `form:"offset" binding:"omitempty,min=0"`
+}
+
+// UserSearchResult is a matched user. Unlike UserResponse it always includes
+// the email, since only admins can search.
+type UserSearchResult struct {
+	UserResponse
+	Email string  `json:"email"`
+	Role  string  `json:"role"`
+	Rank  float64 `json:"rank"`
+	// Highlights holds HTML-escaped field values with the matched parts
+	// wrapped in <mark>, for the fields that matched literally
+	Highlights map[string]string `json:"highlights,omitempty"`
+}
+
+// SearchUsersResponse is a page of search results, best matches first
+type SearchUsersResponse struct {
+	Results []UserSearchResult `json:"results"`
+	Total   int                `json:"total"`
+	Limit   int                `json:"limit"`
+	Offset  int                `json:"offset"`
+}
+
+// SearchUsersHandler finds users by partial username or email
+func (h *Handler) SearchUsersHandler(c *gin.Context) {
+	var query SearchUsersQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		respondValidationError(c, err)
+		return
+	}
+	if query.Limit == 0 {
+		query.Limit = defaultPageSize
+	}
+
+	hits, total, err := h.Users.SearchUsers(c.Request.Context(), query.Q, query.Limit, query.Offset)
+	if canceled(err) {
+		respondCanceled(c, err)
+		return
+	}
+	if err != nil {
+		log.Printf("user search failed: %v", err)
+		c.JSON(http.StatusInternalServerError, i18n.Error(c, "internal.error"))
+		return
+	}
+
+	resp := SearchUsersResponse{
+		Results: make([]UserSearchResult, 0, len(hits)),
+		Total:   total,
+		Limit:   query.Limit,
+		Offset:  query.Offset,
+	}
+	for _, hit := range hits {
+		resp.Results = append(resp.Results, UserSearchResult{
+			UserResponse: h.newUserResponse(c.Request.Context(), hit.User, 0),
+			Email:        hit.User.Email,
+			Role:         hit.User.Role,
+			Rank:         hit.Rank,
+			Highlights:   hit.Highlights,
+		})
+	}
+	c.JSON(http.StatusOK, resp)
+}
@@ -0,0 +1,215 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"golang-boilerplate/main/core"
+	"golang-boilerplate/main/i18n"
+	"golang-boilerplate/main/models"
+	"golang-boilerplate/main/negotiate"
+	"golang-boilerplate/main/rpc/pb"
+	"golang-boilerplate/pkg/utils"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"google.golang.org/protobuf/proto"
+)
+
+// UserParams identifies a user in the path
+type UserParams struct {
+	ID uint `uri:"id" binding:"required,min=1"`
+}
+
+// UpdateUserRequest is the body of a user update; omitted fields are unchanged
+type UpdateUserRequest struct {
+	Username *string `json:"username" binding:"omitempty,username"`
+	Email    *string `json:"email" binding:"omitempty,email_address"`
+}
+
+// UserResponse is the public view of a user. Email is only included for the
+// user themselves.
+type UserResponse struct {
+	ID       uint   `json:"id"`
+	Username string `json:"username"`
+	Email    string `json:"email,omitempty"`
+	// AvatarURL and AvatarURLs are signed and expire after storage.url_ttl
+	AvatarURL  string            `json:"avatar_url,omitempty"`
+	AvatarURLs map[string]string `json:"avatar_urls,omitempty"`
+	CreatedAt  string            `json:"created_at"`
+	UpdatedAt  string            `json:"updated_at"`
+
+	createdAt, updatedAt time.Time
+}
+
+// Proto converts the response to the message the gRPC API uses for users,
+// which has no avatar fields
+func (r UserResponse) Proto() proto.Message {
+	return &pb.User{
+		Id:        uint64(r.ID),
+		Username:  r.Username,
+		Email:     r.Email,
+		CreatedAt: r.createdAt.Unix(),
+		UpdatedAt: r.updatedAt.Unix(),
+	}
+}
+
+func (h *Handler) newUserResponse(ctx context.Context, user *models.User, viewerID uint) UserResponse {
+	resp := UserResponse{
+		ID:        user.ID,
+		Username:  user.Username,
+		CreatedAt: user.CreatedAt.Format(time.RFC3339),
+		UpdatedAt: user.UpdatedAt.Format(time.RFC3339),
+		createdAt: user.CreatedAt,
+		updatedAt: user.UpdatedAt,
+	}
+	if viewerID == user.ID {
+		resp.Email = user.Email
+	}
+
+	urls, err := h.Avatars.Sign(ctx, user)
+	if err != nil {
+		log.Printf("could not sign avatar urls: %v", err)
+	} else if len(urls) > 0 {
+		resp.AvatarURL = user.AvatarURL
+		resp.AvatarURLs = urls
+	}
+	return resp
+}
+
+// userETag identifies a version of a user resource
+func userETag(user *models.User) string {
+	return utils.WeakETag(fmt.Sprintf("user-%d-v%d", user.ID, user.Version))
+}
+
+// GetUserHandler returns a user with its ETag. ETagMiddleware answers
+// If-None-Match from the header set here.
+func (h *Handler) GetUserHandler(c *gin.Context) {
+	var params UserParams
+	if err := c.ShouldBindUri(&params); err != nil {
+		respondValidationError(c, err)
+		return
+	}
+
+	ctx := c.Request.Context()
+	user, err := h.UserService.GetUser(ctx, params.ID)
+	if err != nil {
+		respondUserError(c, err)
+		return
+	}
+
+	viewerID, _ := core.UserIDFromContext(ctx)
+	c.Header("ETag", userETag(user))
+	negotiate.Respond(c, http.StatusOK, h.newUserResponse(ctx, user, viewerID))
+}
+
+// UpdateUserHandler applies a partial update. The request must carry the
+// ETag it last saw in If-Match; a stale one gets 412 instead of silently
+// overwriting someone else's change.
+func (h *Handler) UpdateUserHandler(c *gin.Context) {
+	var params UserParams
+	if err := c.ShouldBindUri(&params); err != nil {
+		respondValidationError(c, err)
+		return
+	}
+
+	var req UpdateUserRequest
+	if !bind(c, &req) {
+		return
+	}
+
+	current, ok := h.checkIfMatch(c, params.ID)
+	if !ok {
+		return
+	}
+
+	ctx := c.Request.Context()
+	actorID, _ := core.UserIDFromContext(ctx)
+	user, err := h.UserService.UpdateUser(ctx, actorID, params.ID, current.Version, core.UpdateUserInput(req))
+	if err != nil {
+		respondUserError(c, err)
+		return
+	}
+
+	h.respondUserUpdated(c, user, actorID)
+}
+
+// respondUserUpdated notifies the user's other sessions of the change and
+// returns the new representation with its ETag
+func (h *Handler) respondUserUpdated(c *gin.Context, user *models.User, actorID uint) {
+	ctx := c.Request.Context()
+	resp := h.newUserResponse(ctx, user, actorID)
+	if err := h.Hub.Publish(ctx, user.ID, "profile.updated", resp); err != nil {
+		log.Printf("could not publish profile update: %v", err)
+	}
+
+	c.Header("ETag", userETag(user))
+	negotiate.Respond(c, http.StatusOK, resp)
+}
+
+// DeleteUserHandler deletes a user, guarded by If-Match like updates
+func (h *Handler) DeleteUserHandler(c *gin.Context) {
+	var params UserParams
+	if err := c.ShouldBindUri(&params); err != nil {
+		respondValidationError(c, err)
+		return
+	}
+
+	current, ok := h.checkIfMatch(c, params.ID)
+	if !ok {
+		return
+	}
+
+	ctx := c.Request.Context()
+	actorID, _ := core.UserIDFromContext(ctx)
+	if err := h.UserService.DeleteUser(ctx, actorID, params.ID, current.Version); err != nil {
+		respondUserError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// checkIfMatch loads the current user and verifies the If-Match header
+// against it, writing 428 or 412 when the precondition is missing or stale
+func (h *Handler) checkIfMatch(c *gin.Context, id uint) (*models.User, bool) {
+	ifMatch := c.GetHeader("If-Match")
+	if ifMatch == "" {
+		negotiate.Respond(c, http.StatusPreconditionRequired, i18n.Error(c, "precondition.required"))
+		return nil, false
+	}
+
+	current, err := h.UserService.GetUser(c.Request.Context(), id)
+	if err != nil {
+		respondUserError(c, err)
+		return nil, false
+	}
+
+	if !utils.ETagMatches(ifMatch, userETag(current)) {
+		c.Header("ETag", userETag(current))
+		negotiate.Respond(c, http.StatusPreconditionFailed, i18n.Error(c, "precondition.failed"))
+		return nil, false
+	}
+
+	return current, true
+}
+
+func respondUserError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, core.ErrUserNotFound):
+		negotiate.Respond(c, http.StatusNotFound, i18n.Error(c, "user.not_found"))
+	case errors.Is(err, core.ErrForbidden):
+		negotiate.Respond(c, http.StatusForbidden, i18n.Error(c, "auth.forbidden"))
+	case errors.Is(err, core.ErrVersionMismatch):
+		negotiate.Respond(c, http.StatusPreconditionFailed, i18n.Error(c, "precondition.failed"))
+	case errors.Is(err, core.ErrUserExists):
+		negotiate.Respond(c, http.StatusConflict, i18n.Error(c, "user.exists"))
+	case canceled(err):
+		respondCanceled(c, err)
+	default:
+		log.Printf("user request failed: %v", err)
+		negotiate.Respond(c, http.StatusInternalServerError, i18n.Error(c, "internal.error"))
+	}
+}
@@ -0,0 +1,256 @@
+package handlers
+
+import (
+	"errors"
+	"golang-boilerplate/main/i18n"
+	"golang-boilerplate/main/models"
+	"golang-boilerplate/main/repo"
+	"golang-boilerplate/main/webhooks"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// defaultPageSize is used when a list request has no limit
+const defaultPageSize = 20
+
+// CreateWebhookRequest registers an endpoint for some event types. A signing
+// secret is generated when none is given.
+type CreateWebhookRequest struct {
+	URL        string   `json:"url" binding:"required,http_url,max=2048"`
+	EventTypes []string `json:"event_types" binding:"required,min=1,dive,oneof=user.registered user.updated user.deleted"`
+	Secret     string   `json:"secret" binding:"omitempty,min=16,max=255"`
+}
+
+// UpdateWebhookRequest enables or disables a subscription. Enabling it
+// clears the failure count that may have disabled it.
+type UpdateWebhookRequest struct {
+	Enabled *bool `json:"enabled" binding:"required"`
+}
+
+// CreateWebhookResponse is the only response that includes the secret
+type CreateWebhookResponse struct {
+	models.WebhookSubscription
+	Secret string `json:"secret"`
+}
+
+// WebhookParams identifies a subscription in the path
+type WebhookParams struct {
+	ID uint `uri:"id" binding:"required,min=1"`
+}
+
+// DeliveryParams identifies a delivery of a subscription in the path
+type DeliveryParams struct {
+	ID         uint  `uri:"id" binding:"required,min=1"`
+	DeliveryID int64 `uri:"delivery_id" binding:"required,min=1"`
+}
+
+// PageQuery selects a page of a list
+type PageQuery struct {
+	Limit  int `form:"limit" binding:"omitempty,min=1,max=100"`
+	Offset int `form:"offset" binding:"omitempty,min=0"`
+}
+
+// DeliveryResponse is a delivery with the log of its attempts
+type DeliveryResponse struct {
+	models.WebhookDelivery
+	AttemptLog []*models.WebhookAttempt `json:"attempt_log"`
+}
+
+// CreateWebhookHandler subscribes an endpoint to events
+func (h *Handler) CreateWebhookHandler(c *gin.Context) {
+	var req CreateWebhookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondValidationError(c, err)
+		return
+	}
+
+	secret := req.Secret
+	if secret == "" {
+		secret = webhooks.NewSecret()
+	}
+
+	now := time.Now()
+	sub := &models.WebhookSubscription{
+		URL:        req.URL,
+		Secret:     secret,
+		EventTypes: req.EventTypes,
+		CreatedAt:  now,
+		UpdatedAt:  now,
+	}
+	if err := h.Webhooks.CreateSubscription(c.Request.Context(), sub); err != nil {
+		respondWebhookError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, CreateWebhookResponse{WebhookSubscription: *sub, Secret: secret})
+}
+
+// ListWebhooksHandler lists all subscriptions
+func (h *Handler) ListWebhooksHandler(c *gin.Context) {
+	subs, err := h.Webhooks.ListSubscriptions(c.Request.Context())
+	if err != nil {
+		respondWebhookError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, subs)
+}
+
+// GetWebhookHandler returns a subscription
+func (h *Handler) GetWebhookHandler(c *gin.Context) {
+	var params WebhookParams
+	if err := c.ShouldBindUri(&params); err != nil {
+		respondValidationError(c, err)
+		return
+	}
+
+	sub, err := h.Webhooks.GetSubscription(c.Request.Context(), params.ID)
+	if err != nil {
+		respondWebhookError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, sub)
+}
+
+// UpdateWebhookHandler enables or disables a subscription
+func (h *Handler) UpdateWebhookHandler(c *gin.Context) {
+	var params WebhookParams
+	if err := c.ShouldBindUri(&params); err != nil {
+		respondValidationError(c, err)
+		return
+	}
+
+	var req UpdateWebhookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondValidationError(c, err)
+		return
+	}
+
+	if err := h.Webhooks.SetSubscriptionEnabled(c.Request.Context(), params.ID, *req.Enabled); err != nil {
+		respondWebhookError(c, err)
+		return
+	}
+
+	sub, err := h.Webhooks.GetSubscription(c.Request.Context(), params.ID)
+	if err != nil {
+		respondWebhookError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, sub)
+}
+
+// DeleteWebhookHandler removes a subscription and its delivery log
+func (h *Handler) DeleteWebhookHandler(c *gin.Context) {
+	var params WebhookParams
+	if err := c.ShouldBindUri(&params); err != nil {
+		respondValidationError(c, err)
+		return
+	}
+
+	if err := h.Webhooks.DeleteSubscription(c.Request.Context(), params.ID); err != nil {
+		respondWebhookError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// ListDeliveriesHandler lists a subscription's deliveries, newest first
+func (h *Handler) ListDeliveriesHandler(c *gin.Context) {
+	var params WebhookParams
+	if err := c.ShouldBindUri(&params); err != nil {
+		respondValidationError(c, err)
+		return
+	}
+
+	var page PageQuery
+	if err := c.ShouldBindQuery(&page); err != nil {
+		respondValidationError(c, err)
+		return
+	}
+	if page.Limit == 0 {
+		page.Limit = defaultPageSize
+	}
+
+	if _, err := h.Webhooks.GetSubscription(c.Request.Context(), params.ID); err != nil {
+		respondWebhookError(c, err)
+		return
+	}
+
+	deliveries, err := h.Webhooks.ListDeliveries(c.Request.Context(), params.ID, page.Limit, page.Offset)
+	if err != nil {
+		respondWebhookError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, deliveries)
+}
+
+// GetDeliveryHandler returns a delivery with every attempt made
+func (h *Handler) GetDeliveryHandler(c *gin.Context) {
+	delivery, ok := h.loadDelivery(c)
+	if !ok {
+		return
+	}
+
+	attempts, err := h.Webhooks.ListAttempts(c.Request.Context(), delivery.ID)
+	if err != nil {
+		respondWebhookError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, DeliveryResponse{WebhookDelivery: *delivery, AttemptLog: attempts})
+}
+
+// RedeliverHandler sends a delivery again, with a fresh round of retries.
+// The payload and event ID are unchanged so receivers can deduplicate.
+func (h *Handler) RedeliverHandler(c *gin.Context) {
+	delivery, ok := h.loadDelivery(c)
+	if !ok {
+		return
+	}
+
+	delivery, err := h.Dispatcher.Redeliver(c.Request.Context(), delivery.ID)
+	if err != nil {
+		respondWebhookError(c, err)
+		return
+	}
+	c.JSON(http.StatusAccepted, delivery)
+}
+
+// loadDelivery binds the path and loads the delivery, checking it belongs to
+// the subscription in the path
+func (h *Handler) loadDelivery(c *gin.Context) (*models.WebhookDelivery, bool) {
+	var params DeliveryParams
+	if err := c.ShouldBindUri(&params); err != nil {
+		respondValidationError(c, err)
+		return nil, false
+	}
+
+	delivery, err := h.Webhooks.GetDelivery(c.Request.Context(), params.DeliveryID)
+	if err == nil && delivery.SubscriptionID != params.ID {
+		err = repo.ErrNotFound
+	}
+	if err != nil {
+		if errors.Is(err, repo.ErrNotFound) {
+			c.JSON(http.StatusNotFound, i18n.Error(c, "webhook.delivery_not_found"))
+		} else {
+			respondWebhookError(c, err)
+		}
+		return nil, false
+	}
+	return delivery, true
+}
+
+func respondWebhookError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, repo.ErrNotFound):
+		c.JSON(http.StatusNotFound, i18n.Error(c, "webhook.not_found"))
+	case errors.Is(err, webhooks.ErrNotRunning):
+		c.JSON(http.StatusServiceUnavailable, i18n.Error(c, "service.unavailable"))
+	case canceled(err):
+		respondCanceled(c, err)
+	default:
+		log.Printf("webhook request failed: %v", err)
+		c.JSON(http.StatusInternalServerError, i18n.Error(c, "internal.error"))
+	}
+}
@@ -0,0 +1,156 @@
+package i18n
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/text/language"
+)
+
+// DefaultLocale is the last entry of every fallback chain
+const DefaultLocale = "en"
+
+// ContextKey is the gin context key holding the negotiated locale
+const ContextKey = "locale"
+
+//go:embed locales/*.json
+var localeFiles embed.FS
+
+var (
+	catalogs = make(map[string]map[string]string)
+	matcher  language.Matcher
+	locales  []string
+)
+
+func init() {
+	if err := load(); err != nil {
+		panic(err)
+	}
+}
+
+// load reads every embedded catalog. Catalogs are named after their locale,
+// e.g. locales/pt-BR.json.
+func load() error {
+	entries, err := localeFiles.ReadDir("locales")
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		data, err := localeFiles.ReadFile(path.Join("locales", entry.Name()))
+		if err != nil {
+			return err
+		}
+
+		var messages map[string]string
+		if err := json.Unmarshal(data, &messages); err != nil {
+			return fmt.Errorf("could not parse catalog %s: %w", entry.Name(), err)
+		}
+
+		locale := strings.TrimSuffix(entry.Name(), ".json")
+		if _, err := language.Parse(locale); err != nil {
+			return fmt.Errorf("invalid locale in catalog name %s: %w", entry.Name(), err)
+		}
+		catalogs[locale] = messages
+	}
+
+	if _, ok := catalogs[DefaultLocale]; !ok {
+		return fmt.Errorf("missing catalog for default locale %q", DefaultLocale)
+	}
+
+	// The matcher prefers its first tag when nothing matches, so the
+	// default locale goes first
+	locales = []string{DefaultLocale}
+	for locale := range catalogs {
+		if locale != DefaultLocale {
+			locales = append(locales, locale)
+		}
+	}
+	sort.Strings(locales[1:])
+
+	tags := make([]language.Tag, 0, len(locales))
+	for _, locale := range locales {
+		tags = append(tags, language.MustParse(locale))
+	}
+	matcher = language.NewMatcher(tags)
+
+	return nil
+}
+
+// Locales returns the locales that have a catalog, default first
+func Locales() []string {
+	return append([]string(nil), locales...)
+}
+
+// Negotiate picks the best supported locale for an Accept-Language header
+func Negotiate(acceptLanguage string) string {
+	tags, _, err := language.ParseAcceptLanguage(acceptLanguage)
+	if err != nil || len(tags) == 0 {
+		return DefaultLocale
+	}
+
+	_, index, confidence := matcher.Match(tags...)
+	if confidence == language.No {
+		return DefaultLocale
+	}
+	return locales[index]
+}
+
+// fallbackChain lists the locales to try for a message, most specific first:
+// "pt-BR" yields pt-BR, pt, en
+func fallbackChain(locale string) []string {
+	var chain []string
+	for locale != "" {
+		chain = append(chain, locale)
+		i := strings.LastIndex(locale, "-")
+		if i < 0 {
+			break
+		}
+		locale = locale[:i]
+	}
+	if chain == nil || chain[len(chain)-1] != DefaultLocale {
+		chain = append(chain, DefaultLocale)
+	}
+	return chain
+}
+
+// T translates a message code, substituting {name} placeholders from args.
+// Unknown codes are returned unchanged.
+func T(locale, code string, args map[string]string) string {
+	message := code
+	for _, candidate := range fallbackChain(locale) {
+		if m, ok := catalogs[candidate][code]; ok {
+			message = m
+			break
+		}
+	}
+
+	for name, value := range args {
+		message = strings.ReplaceAll(message, "{"+name+"}", value)
+	}
+	return message
+}
+
+// Locale returns the locale negotiated for the request
+func Locale(c *gin.Context) string {
+	if locale := c.GetString(ContextKey); locale != "" {
+		return locale
+	}
+	return DefaultLocale
+}
+
+// Message translates a message code for the request's locale
+func Message(c *gin.Context, code string) string {
+	return T(Locale(c), code, nil)
+}
+
+// Error builds the JSON body for an error response: a stable code that
+// clients can branch on and a message in the negotiated language
+func Error(c *gin.Context, code string) gin.H {
+	return gin.H{"error": Message(c, code), "code": code}
+}
@@ -0,0 +1,376 @@
+package imports
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"golang-boilerplate/main/config"
+	"golang-boilerplate/main/core"
+	"golang-boilerplate/main/i18n"
+	"golang-boilerplate/main/models"
+	"golang-boilerplate/main/repo"
+	"golang-boilerplate/main/validation"
+	"golang-boilerplate/pkg/async"
+
+	"github.com/gin-gonic/gin/binding"
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+// ErrNotRunning is returned when a job is submitted before Start or after Stop
+var ErrNotRunning = errors.New("importer is not running")
+
+// saveTimeout bounds writes of job status, which also happen during shutdown
+const saveTimeout = 5 * time.Second
+
+// Importer creates users in bulk from uploaded files. Uploads are spooled to
+// a temporary file and processed in the background: rows are validated,
+// passwords are hashed in parallel on a worker pool and each batch is
+// inserted with COPY.
+type Importer struct {
+	users  repo.UserRepository
+	rdb    *redis.Client
+	cfg    config.ImportsConfig
+	logger *zap.Logger
+
+	mu        sync.Mutex
+	pool      *async.WorkerPool
+	store     *jobStore
+	batchSize int
+	ctx       context.Context
+	cancel    context.CancelFunc
+	wg        sync.WaitGroup
+}
+
+// NewImporter creates an importer that keeps job status in Redis, or in
+// memory when rdb is nil
+func NewImporter(users repo.UserRepository, rdb *redis.Client, cfg config.ImportsConfig, logger *zap.Logger) *Importer {
+	return &Importer{users: users, rdb: rdb, cfg: cfg, logger: logger}
+}
+
+// Start sizes the worker pool from config and begins accepting jobs
+func (im *Importer) Start() {
+	im.mu.Lock()
+	defer im.mu.Unlock()
+
+	im.pool = async.NewWorkerPool(im.cfg.Workers)
+	im.pool.Start()
+	im.store = &jobStore{rdb: im.rdb, retention: im.cfg.JobRetention, jobs: make(map[string][]byte)}
+	im.batchSize = im.cfg.BatchSize
+	im.ctx, im.cancel = context.WithCancel(context.Background())
+}
+
+// Stop interrupts running jobs, marking them failed, and stops the pool
+func (im *Importer) Stop() {
+	im.mu.Lock()
+	if im.cancel == nil {
+		im.mu.Unlock()
+		return
+	}
+	im.cancel()
+	im.cancel = nil
+	im.mu.Unlock()
+
+	// Jobs must finish submitting before the pool closes its queue
+	im.wg.Wait()
+	im.pool.Stop()
+}
+
+// Submit spools body to disk and starts importing it. A CSV header without
+// the required columns is reported here as a *HeaderError; problems with
+// individual rows are reported on the job.
+func (im *Importer) Submit(ctx context.Context, format Format, body io.Reader, createdBy uint, locale string) (*Job, error) {
+	im.mu.Lock()
+	running := im.cancel != nil
+	if running {
+		im.wg.Add(1)
+	}
+	im.mu.Unlock()
+	if !running {
+		return nil, ErrNotRunning
+	}
+
+	started := false
+	defer func() {
+		if !started {
+			im.wg.Done()
+		}
+	}()
+
+	file, err := spool(body)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if !started {
+			file.Close()
+			os.Remove(file.Name())
+		}
+	}()
+
+	reader, err := newRowReader(format, file)
+	if err != nil {
+		return nil, err
+	}
+
+	job := newJob(format, createdBy, locale)
+	if err := im.store.save(ctx, job); err != nil {
+		return nil, fmt.Errorf("could not save import job: %w", err)
+	}
+
+	// The caller gets a snapshot; the job itself is owned by the goroutine
+	accepted := *job
+
+	started = true
+	go func() {
+		defer im.wg.Done()
+		defer os.Remove(file.Name())
+		defer file.Close()
+		im.run(job, reader)
+	}()
+
+	return &accepted, nil
+}
+
+// Job returns the current status of a job
+func (im *Importer) Job(ctx context.Context, id string) (*Job, error) {
+	im.mu.Lock()
+	store := im.store
+	im.mu.Unlock()
+	if store == nil {
+		return nil, ErrJobNotFound
+	}
+	return store.load(ctx, id)
+}
+
+// spool copies the upload to a temporary file so the request can finish
+// while the import runs
+func spool(body io.Reader) (*os.File, error) {
+	file, err := os.CreateTemp("", "user-import-*")
+	if err != nil {
+		return nil, fmt.Errorf("could not create spool file: %w", err)
+	}
+
+	if _, err := io.Copy(file, body); err != nil {
+		file.Close()
+		os.Remove(file.Name())
+		return nil, err
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		file.Close()
+		os.Remove(file.Name())
+		return nil, err
+	}
+
+	return file, nil
+}
+
+func (im *Importer) run(job *Job, reader rowReader) {
+	err := im.process(job, reader)
+	if err != nil {
+		im.logger.Error("user import failed", zap.String("job_id", job.ID), zap.Error(err))
+		job.Error = i18n.T(job.locale, "import.failed", nil)
+	}
+	job.finish(err != nil)
+	im.save(job)
+
+	im.logger.Info("user import finished",
+		zap.String("job_id", job.ID),
+		zap.String("status", string(job.Status)),
+		zap.Int("imported", job.Imported),
+		zap.Int("failed", job.Failed))
+}
+
+func (im *Importer) process(job *Job, reader rowReader) error {
+	// Line of the first occurrence of each username and email, so
+	// duplicates within the file are reported instead of failing a batch
+	seenUsernames := make(map[string]int)
+	seenEmails := make(map[string]int)
+
+	batch := make([]row, 0, im.batchSize)
+	for {
+		if err := im.ctx.Err(); err != nil {
+			return err
+		}
+
+		r, err := reader.next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		job.Processed++
+
+		if r.err != nil {
+			job.fail(r.line, validation.Errors(r.err, job.locale)...)
+			continue
+		}
+		if err := binding.Validator.ValidateStruct(r.input); err != nil {
+			job.fail(r.line, validation.Errors(err, job.locale)...)
+			continue
+		}
+		if line, ok := seenUsernames[r.input.Username]; ok {
+			job.fail(r.line, duplicateError(job.locale, "username", line))
+			continue
+		}
+		if line, ok := seenEmails[r.input.Email]; ok {
+			job.fail(r.line, duplicateError(job.locale, "email", line))
+			continue
+		}
+		seenUsernames[r.input.Username] = r.line
+		seenEmails[r.input.Email] = r.line
+
+		batch = append(batch, r)
+		if len(batch) == im.batchSize {
+			if err := im.flush(job, batch); err != nil {
+				return err
+			}
+			batch = batch[:0]
+			im.save(job)
+		}
+	}
+
+	return im.flush(job, batch)
+}
+
+// flush skips rows whose username or email is already taken, hashes the
+// remaining passwords in parallel and inserts them with one COPY
+func (im *Importer) flush(job *Job, batch []row) error {
+	if len(batch) == 0 {
+		return nil
+	}
+
+	usernames := make([]string, len(batch))
+	emails := make([]string, len(batch))
+	for i, r := range batch {
+		usernames[i] = r.input.Username
+		emails[i] = r.input.Email
+	}
+
+	takenUsernames, takenEmails, err := im.users.FindTaken(im.ctx, usernames, emails)
+	if err != nil {
+		return fmt.Errorf("could not check existing users: %w", err)
+	}
+
+	pending := make([]row, 0, len(batch))
+	for _, r := range batch {
+		switch {
+		case takenUsernames[r.input.Username]:
+			job.fail(r.line, existsError(job.locale, "username"))
+		case takenEmails[r.input.Email]:
+			job.fail(r.line, existsError(job.locale, "email"))
+		default:
+			pending = append(pending, r)
+		}
+	}
+
+	users, err := im.hashAll(pending)
+	if err != nil {
+		return err
+	}
+	if len(users) == 0 {
+		return nil
+	}
+
+	err = im.users.CopyUsers(im.ctx, users)
+	if errors.Is(err, repo.ErrConflict) {
+		// Someone registered one of these users since FindTaken; insert
+		// one at a time to find out which
+		return im.insertEach(job, pending, users)
+	}
+	if err != nil {
+		return fmt.Errorf("could not copy users: %w", err)
+	}
+
+	job.Imported += len(users)
+	return nil
+}
+
+// hashAll hashes the passwords of rows on the worker pool
+func (im *Importer) hashAll(rows []row) ([]*models.User, error) {
+	users := make([]*models.User, len(rows))
+	errs := make([]error, len(rows))
+	now := time.Now()
+
+	var wg sync.WaitGroup
+	for i, r := range rows {
+		wg.Add(1)
+		err := im.pool.SubmitWait(im.ctx, func(ctx context.Context) error {
+			defer wg.Done()
+			hashed, err := core.HashPassword(r.input.Password)
+			if err != nil {
+				errs[i] = err
+				return err
+			}
+			users[i] = &models.User{
+				Username:  r.input.Username,
+				Email:     r.input.Email,
+				Password:  hashed,
+				Role:      models.RoleUser,
+				CreatedAt: now,
+				UpdatedAt: now,
+			}
+			return nil
+		})
+		if err != nil {
+			wg.Done()
+			wg.Wait()
+			return nil, err
+		}
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+	return users, nil
+}
+
+func (im *Importer) insertEach(job *Job, rows []row, users []*models.User) error {
+	for i, user := range users {
+		err := im.users.CreateUser(im.ctx, user)
+		if errors.Is(err, repo.ErrConflict) {
+			job.fail(rows[i].line, existsError(job.locale, ""))
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("could not create user: %w", err)
+		}
+		job.Imported++
+	}
+	return nil
+}
+
+func (im *Importer) save(job *Job) {
+	ctx, cancel := context.WithTimeout(context.Background(), saveTimeout)
+	defer cancel()
+
+	if err := im.store.save(ctx, job); err != nil {
+		im.logger.Error("could not save import job", zap.String("job_id", job.ID), zap.Error(err))
+	}
+}
+
+func duplicateError(locale, field string, firstLine int) validation.FieldError {
+	return validation.FieldError{
+		Field:   field,
+		Rule:    "duplicate",
+		Message: i18n.T(locale, "import.duplicate", map[string]string{"field": field, "line": strconv.Itoa(firstLine)}),
+	}
+}
+
+func existsError(locale, field string) validation.FieldError {
+	return validation.FieldError{
+		Field:   field,
+		Rule:    "exists",
+		Message: i18n.T(locale, "user.exists", nil),
+	}
+}
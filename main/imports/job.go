@@ -0,0 +1,148 @@
+package imports
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"sync"
+	"time"
+
+	"golang-boilerplate/main/validation"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Status is the lifecycle state of an import job
+type Status string
+
+const (
+	StatusRunning   Status = "running"
+	StatusCompleted Status = "completed"
+	StatusFailed    Status = "failed"
+)
+
+// maxRowErrors caps how many row errors a job keeps, so a file that is wrong
+// on every line does not produce a huge status document
+const maxRowErrors = 1000
+
+// ErrJobNotFound is returned for unknown or expired job IDs
+var ErrJobNotFound = errors.New("import job not found")
+
+// RowError describes why a row was not imported
+type RowError struct {
+	Line int `json:"line"`
+	validation.FieldError
+}
+
+// Job reports the progress of an import. Counts are updated after each batch.
+type Job struct {
+	ID              string     `json:"id"`
+	Status          Status     `json:"status"`
+	Format          Format     `json:"format"`
+	Processed       int        `json:"processed"`
+	Imported        int        `json:"imported"`
+	Failed          int        `json:"failed"`
+	Errors          []RowError `json:"errors"`
+	ErrorsTruncated bool       `json:"errors_truncated"`
+	Error           string     `json:"error,omitempty"`
+	CreatedBy       uint       `json:"created_by"`
+	CreatedAt       time.Time  `json:"created_at"`
+	FinishedAt      *time.Time `json:"finished_at,omitempty"`
+
+	locale string
+}
+
+func newJob(format Format, createdBy uint, locale string) *Job {
+	id := make([]byte, 16)
+	_, _ = rand.Read(id)
+
+	return &Job{
+		ID:        hex.EncodeToString(id),
+		Status:    StatusRunning,
+		Format:    format,
+		Errors:    []RowError{},
+		CreatedBy: createdBy,
+		CreatedAt: time.Now(),
+		locale:    locale,
+	}
+}
+
+// fail records a row that could not be imported and why
+func (j *Job) fail(line int, fieldErrs ...validation.FieldError) {
+	j.Failed++
+	for _, fieldErr := range fieldErrs {
+		if len(j.Errors) == maxRowErrors {
+			j.ErrorsTruncated = true
+			return
+		}
+		j.Errors = append(j.Errors, RowError{Line: line, FieldError: fieldErr})
+	}
+}
+
+func (j *Job) finish(failed bool) {
+	now := time.Now()
+	j.FinishedAt = &now
+	j.Status = StatusCompleted
+	if failed {
+		j.Status = StatusFailed
+	}
+}
+
+// jobStore keeps job status in Redis so any replica can report on a job,
+// falling back to process memory when Redis is not configured
+type jobStore struct {
+	rdb       *redis.Client
+	retention time.Duration
+
+	mu   sync.RWMutex
+	jobs map[string][]byte
+}
+
+func jobKey(id string) string {
+	return "import:job:" + id
+}
+
+func (s *jobStore) save(ctx context.Context, job *Job) error {
+	data, err := json.Marshal(job)
+	if err != nil {
+		return err
+	}
+
+	if s.rdb != nil {
+		return s.rdb.Set(ctx, jobKey(job.ID), data, s.retention).Err()
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.jobs[job.ID] = data
+	return nil
+}
+
+func (s *jobStore) load(ctx context.Context, id string) (*Job, error) {
+	var data []byte
+	if s.rdb != nil {
+		var err error
+		data, err = s.rdb.Get(ctx, jobKey(id)).Bytes()
+		if errors.Is(err, redis.Nil) {
+			return nil, ErrJobNotFound
+		}
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		s.mu.RLock()
+		data = s.jobs[id]
+		s.mu.RUnlock()
+		if data == nil {
+			return nil, ErrJobNotFound
+		}
+	}
+
+	var job Job
+	if err := json.Unmarshal(data, &job); err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
@@ -0,0 +1,169 @@
+package imports
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"strings"
+
+	"golang-boilerplate/main/core"
+)
+
+// Format is the encoding of an uploaded file
+type Format string
+
+const (
+	FormatCSV    Format = "csv"
+	FormatNDJSON Format = "ndjson"
+)
+
+// maxLineBytes bounds a single NDJSON line
+const maxLineBytes = 1 << 20
+
+// csvColumns are the columns a CSV upload must have, in any order
+var csvColumns = []string{"username", "email", "password"}
+
+// ErrUnsupportedFormat is returned for content types other than CSV and NDJSON
+var ErrUnsupportedFormat = errors.New("unsupported import format")
+
+// HeaderError is returned when a CSV header lacks required columns
+type HeaderError struct {
+	Missing []string
+}
+
+func (e *HeaderError) Error() string {
+	return fmt.Sprintf("csv header is missing columns: %s", strings.Join(e.Missing, ", "))
+}
+
+// FormatFromContentType maps a request content type to an import format
+func FormatFromContentType(contentType string) (Format, error) {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	switch mediaType {
+	case "text/csv":
+		return FormatCSV, nil
+	case "application/x-ndjson", "application/ndjson", "application/jsonl":
+		return FormatNDJSON, nil
+	default:
+		return "", ErrUnsupportedFormat
+	}
+}
+
+// row is one record from the upload. A row that could not be decoded
+// carries err instead of input.
+type row struct {
+	line  int
+	input core.RegisterInput
+	err   error
+}
+
+// rowReader yields rows until io.EOF. Any other error from next is fatal
+// for the whole file; problems with a single row are reported on the row.
+type rowReader interface {
+	next() (row, error)
+}
+
+func newRowReader(format Format, r io.Reader) (rowReader, error) {
+	switch format {
+	case FormatCSV:
+		return newCSVReader(r)
+	case FormatNDJSON:
+		return newNDJSONReader(r), nil
+	default:
+		return nil, ErrUnsupportedFormat
+	}
+}
+
+type csvReader struct {
+	reader  *csv.Reader
+	columns map[string]int
+}
+
+func newCSVReader(r io.Reader) (*csvReader, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+	reader.ReuseRecord = true
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, &HeaderError{Missing: csvColumns}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+
+	var missing []string
+	for _, name := range csvColumns {
+		if _, ok := columns[name]; !ok {
+			missing = append(missing, name)
+		}
+	}
+	if len(missing) > 0 {
+		return nil, &HeaderError{Missing: missing}
+	}
+
+	return &csvReader{reader: reader, columns: columns}, nil
+}
+
+func (r *csvReader) next() (row, error) {
+	record, err := r.reader.Read()
+
+	var parseErr *csv.ParseError
+	if errors.As(err, &parseErr) {
+		return row{line: parseErr.StartLine, err: err}, nil
+	}
+	if err != nil {
+		return row{}, err
+	}
+
+	line, _ := r.reader.FieldPos(0)
+	return row{
+		line: line,
+		input: core.RegisterInput{
+			Username: strings.TrimSpace(record[r.columns["username"]]),
+			Email:    strings.TrimSpace(record[r.columns["email"]]),
+			Password: record[r.columns["password"]],
+		},
+	}, nil
+}
+
+type ndjsonReader struct {
+	scanner *bufio.Scanner
+	line    int
+}
+
+func newNDJSONReader(r io.Reader) *ndjsonReader {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), maxLineBytes)
+	return &ndjsonReader{scanner: scanner}
+}
+
+func (r *ndjsonReader) next() (row, error) {
+	for r.scanner.Scan() {
+		r.line++
+		data := bytes.TrimSpace(r.scanner.Bytes())
+		if len(data) == 0 {
+			continue
+		}
+
+		var input core.RegisterInput
+		if err := json.Unmarshal(data, &input); err != nil {
+			return row{line: r.line, err: err}, nil
+		}
+		return row{line: r.line, input: input}, nil
+	}
+
+	if err := r.scanner.Err(); err != nil {
+		return row{}, fmt.Errorf("line %d: %w", r.line+1, err)
+	}
+	return row{}, io.EOF
+}
@@ -2,6 +2,7 @@ package middleware
 
 import (
 	"fmt"
+	"golang-boilerplate/main/i18n"
 	"net/http"
 	"time"
 
@@ -10,26 +11,30 @@ import (
 	"go.uber.org/zap"
 )
 
-var (
-	postgresBreaker *breaker.Breaker
-	redisBreaker    *breaker.Breaker
-)
+// Breakers are the circuit breakers of the services handlers depend on
+type Breakers struct {
+	Postgres *breaker.Breaker
+	Redis    *breaker.Breaker
+}
 
-func init() {
-	// Initialize circuit breakers with error threshold and success threshold
-	postgresBreaker = breaker.New(3, 1, 5*time.Second) // 3 failures, 1 success, 5s timeout
-	redisBreaker = breaker.New(3, 1, 5*time.Second)
+// NewBreakers opens a breaker after 3 failures and tries the service again
+// after 5 seconds, closing it on the first success
+func NewBreakers() *Breakers {
+	return &Breakers{
+		Postgres: breaker.New(3, 1, 5*time.Second),
+		Redis:    breaker.New(3, 1, 5*time.Second),
+	}
 }
 
 // CircuitBreakerMiddleware wraps handlers with circuit breaker protection
-func CircuitBreakerMiddleware(service string) gin.HandlerFunc {
+func CircuitBreakerMiddleware(breakers *Breakers, service string, logger *zap.Logger) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		var b *breaker.Breaker
 		switch service {
 		case "postgres":
-			b = postgresBreaker
+			b = breakers.Postgres
 		case "redis":
-			b = redisBreaker
+			b = breakers.Redis
 		default:
 			c.Next()
 			return
@@ -49,7 +54,8 @@ func CircuitBreakerMiddleware(service string) gin.HandlerFunc {
 				zap.Error(err),
 			)
 			c.AbortWithStatusJSON(http.StatusServiceUnavailable, gin.H{
-				"error":   "Service temporarily unavailable",
+				"error":   i18n.Message(c, "service.unavailable"),
+				"code":    "service.unavailable",
 				"service": service,
 			})
 			return
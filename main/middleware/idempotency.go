@@ -0,0 +1,183 @@
+package middleware
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"golang-boilerplate/main/i18n"
+
+	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+const (
+	idempotencyHeader = "Idempotency-Key"
+	// idempotencyLockTTL bounds how long a crashed request can block its key
+	idempotencyLockTTL = time.Minute
+	maxIdempotencyKey  = 255
+)
+
+// replayedHeaders are restored when a stored response is replayed
+var replayedHeaders = []string{"Content-Type", "ETag", "Location"}
+
+// idempotencyRecord is what is stored in Redis under each key
+type idempotencyRecord struct {
+	Fingerprint string            `json:"fingerprint"`
+	Completed   bool              `json:"completed"`
+	Status      int               `json:"status,omitempty"`
+	Headers     map[string]string `json:"headers,omitempty"`
+	Body        []byte            `json:"body,omitempty"`
+}
+
+// recordingWriter passes the response through while keeping a copy
+type recordingWriter struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *recordingWriter) Write(data []byte) (int, error) {
+	w.body.Write(data)
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *recordingWriter) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
+
+// IdempotencyMiddleware makes POST requests carrying an Idempotency-Key safe
+// to retry. The first request runs and its response is stored in Redis;
+// repeats get the stored response back, concurrent repeats get 409, and a key
+// reused with a different body gets 422. Completed responses are kept for
+// ttl. Requests without the header, or while Redis is unavailable, run
+// normally. Stored responses are replayed to anyone presenting the key, so
+// routes answering with credentials, such as login, must not use it.
+func IdempotencyMiddleware(rdb *redis.Client, ttl time.Duration, logger *zap.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := c.GetHeader(idempotencyHeader)
+		if c.Request.Method != http.MethodPost || key == "" || rdb == nil {
+			c.Next()
+			return
+		}
+		if len(key) > maxIdempotencyKey {
+			c.AbortWithStatusJSON(http.StatusBadRequest, i18n.Error(c, "idempotency.invalid_key"))
+			return
+		}
+
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, i18n.Error(c, "validation.malformed"))
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		ctx := c.Request.Context()
+		redisKey := idempotencyKey(c, key)
+		fingerprint := requestFingerprint(c, body)
+
+		lock, _ := json.Marshal(idempotencyRecord{Fingerprint: fingerprint})
+		acquired, err := rdb.SetNX(ctx, redisKey, lock, idempotencyLockTTL).Result()
+		if err != nil {
+			logger.Warn("idempotency store unavailable, running request without it", zap.Error(err))
+			c.Next()
+			return
+		}
+
+		if !acquired {
+			replayIdempotent(c, rdb, logger, redisKey, fingerprint)
+			return
+		}
+
+		recorder := &recordingWriter{ResponseWriter: c.Writer}
+		c.Writer = recorder
+		c.Next()
+		c.Writer = recorder.ResponseWriter
+
+		// Server errors are not stored so the client can retry them
+		status := recorder.Status()
+		if status >= http.StatusInternalServerError {
+			rdb.Del(ctx, redisKey)
+			return
+		}
+
+		record := idempotencyRecord{
+			Fingerprint: fingerprint,
+			Completed:   true,
+			Status:      status,
+			Headers:     make(map[string]string),
+			Body:        recorder.body.Bytes(),
+		}
+		for _, name := range replayedHeaders {
+			if value := recorder.Header().Get(name); value != "" {
+				record.Headers[name] = value
+			}
+		}
+
+		data, _ := json.Marshal(record)
+		if err := rdb.Set(ctx, redisKey, data, ttl).Err(); err != nil {
+			logger.Error("failed to store idempotent response", zap.String("key", redisKey), zap.Error(err))
+		}
+	}
+}
+
+// replayIdempotent answers a request whose key is already taken
+func replayIdempotent(c *gin.Context, rdb *redis.Client, logger *zap.Logger, redisKey, fingerprint string) {
+	data, err := rdb.Get(c.Request.Context(), redisKey).Bytes()
+	if err != nil {
+		// The first request finished with a server error in the meantime,
+		// or the lock expired; either way it is safe to ask for a retry
+		c.AbortWithStatusJSON(http.StatusConflict, i18n.Error(c, "idempotency.in_flight"))
+		return
+	}
+
+	var record idempotencyRecord
+	if err := json.Unmarshal(data, &record); err != nil {
+		logger.Error("corrupt idempotency record", zap.String("key", redisKey), zap.Error(err))
+		c.AbortWithStatusJSON(http.StatusConflict, i18n.Error(c, "idempotency.in_flight"))
+		return
+	}
+
+	switch {
+	case record.Fingerprint != fingerprint:
+		c.AbortWithStatusJSON(http.StatusUnprocessableEntity, i18n.Error(c, "idempotency.key_reused"))
+	case !record.Completed:
+		c.AbortWithStatusJSON(http.StatusConflict, i18n.Error(c, "idempotency.in_flight"))
+	default:
+		for name, value := range record.Headers {
+			c.Header(name, value)
+		}
+		c.Header("Idempotent-Replayed", "true")
+		c.Data(record.Status, record.Headers["Content-Type"], record.Body)
+		c.Abort()
+	}
+}
+
+// idempotencyKey scopes client keys to the caller so two users cannot
+// collide on (or probe) each other's keys. Anonymous callers are told apart
+// by their address.
+func idempotencyKey(c *gin.Context, key string) string {
+	scope := "anonymous:" + c.ClientIP()
+	if userID, exists := c.Get("user_id"); exists {
+		scope = fmt.Sprint(userID)
+	}
+	sum := sha256.Sum256([]byte(key))
+	return fmt.Sprintf("idempotency:%s:%s", scope, hex.EncodeToString(sum[:]))
+}
+
+// requestFingerprint identifies the request a key was first used with
+func requestFingerprint(c *gin.Context, body []byte) string {
+	h := sha256.New()
+	h.Write([]byte(c.Request.Method))
+	h.Write([]byte{0})
+	h.Write([]byte(c.Request.URL.Path))
+	h.Write([]byte{0})
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
@@ -0,0 +1,19 @@
+package middleware
+
+import (
+	"golang-boilerplate/main/i18n"
+
+	"github.com/gin-gonic/gin"
+)
+
+// LocaleMiddleware negotiates the response language from Accept-Language
+func LocaleMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		locale := i18n.Negotiate(c.GetHeader("Accept-Language"))
+		c.Set(i18n.ContextKey, locale)
+		c.Header("Content-Language", locale)
+		c.Writer.Header().Add("Vary", "Accept-Language")
+
+		c.Next()
+	}
+}
@@ -1,45 +1,53 @@
 package middleware
 
 import (
+	"fmt"
+	"golang-boilerplate/main/i18n"
 	"net/http"
 	"sync"
-	"time"
 
 	"github.com/gin-gonic/gin"
 	"golang.org/x/time/rate"
 )
 
-var (
-	limiters  = make(map[string]*rate.Limiter)
-	mu        sync.RWMutex
-	rateLimit = rate.Every(1 * time.Second) // 1 request per second
-	burst     = 5                           // burst of 5
-)
+// RateLimiter keeps a token bucket per caller
+type RateLimiter struct {
+	mu       sync.Mutex
+	limiters map[string]*rate.Limiter
+	limit    rate.Limit
+	burst    int
+}
+
+// NewRateLimiter lets each caller make limit requests a second after a
+// burst of burst
+func NewRateLimiter(limit rate.Limit, burst int) *RateLimiter {
+	return &RateLimiter{limiters: make(map[string]*rate.Limiter), limit: limit, burst: burst}
+}
 
-func getLimiter(key string) *rate.Limiter {
-	mu.Lock()
-	defer mu.Unlock()
+func (l *RateLimiter) get(key string) *rate.Limiter {
+	l.mu.Lock()
+	defer l.mu.Unlock()
 
-	limiter, exists := limiters[key]
+	limiter, exists := l.limiters[key]
 	if !exists {
-		limiter = rate.NewLimiter(rateLimit, burst)
-		limiters[key] = limiter
+		limiter = rate.NewLimiter(l.limit, l.burst)
+		l.limiters[key] = limiter
 	}
 
 	return limiter
 }
 
-func RateLimitMiddleware() gin.HandlerFunc {
+func RateLimitMiddleware(limiters *RateLimiter) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// Use user ID if authenticated, otherwise use IP
 		key := c.ClientIP()
 		if userID, exists := c.Get("user_id"); exists {
-			key = userID.(string)
+			key = fmt.Sprint(userID)
 		}
 
-		limiter := getLimiter(key)
+		limiter := limiters.get(key)
 		if !limiter.Allow() {
-			c.JSON(http.StatusTooManyRequests, gin.H{"error": "Too many requests"})
+			c.JSON(http.StatusTooManyRequests, i18n.Error(c, "rate_limit.exceeded"))
 			c.Abort()
 			return
 		}
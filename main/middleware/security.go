@@ -1,16 +1,33 @@
 package middleware
 
 import (
+	"golang-boilerplate/main/config"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+
 	"github.com/gin-gonic/gin"
 )
 
-// CORSMiddleware handles CORS headers
-func CORSMiddleware() gin.HandlerFunc {
+// CORSMiddleware handles CORS headers for the allowed origins
+func CORSMiddleware(cfg config.CORSConfig) gin.HandlerFunc {
+	anyOrigin := slices.Contains(cfg.AllowedOrigins, "*")
 	return func(c *gin.Context) {
-		c.Header("Access-Control-Allow-Origin", "*")
-		c.Header("Access-Control-Allow-Credentials", "true")
-		c.Header("Access-Control-Allow-Headers", "Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, accept, origin, Cache-Control, X-Requested-With")
-		c.Header("Access-Control-Allow-Methods", "POST, OPTIONS, GET, PUT, DELETE")
+		origin := c.GetHeader("Origin")
+		switch {
+		case anyOrigin:
+			c.Header("Access-Control-Allow-Origin", "*")
+		case listedOrigin(cfg.AllowedOrigins, origin):
+			c.Header("Access-Control-Allow-Origin", origin)
+			c.Header("Access-Control-Allow-Credentials", "true")
+		}
+		if !anyOrigin {
+			c.Writer.Header().Add("Vary", "Origin")
+		}
+		c.Header("Access-Control-Allow-Headers", "Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, accept, origin, Cache-Control, X-Requested-With, If-Match, If-None-Match, Idempotency-Key, X-Client-Name")
+		c.Header("Access-Control-Allow-Methods", "POST, OPTIONS, GET, PUT, PATCH, DELETE")
+		c.Header("Access-Control-Expose-Headers", "ETag, Idempotent-Replayed, API-Version, Deprecation, Sunset, Link")
 
 		if c.Request.Method == "OPTIONS" {
 			c.AbortWithStatus(204)
@@ -21,6 +38,37 @@ func CORSMiddleware() gin.HandlerFunc {
 	}
 }
 
+// WebSocketOrigin checks the origin of WebSocket upgrades. Browsers open
+// cross-site WebSockets without asking CORS, and the realtime routes take
+// the token from the query string, so only the API's own origin and origins
+// listed by name are accepted; "*" does not cover them. Requests without an
+// Origin do not come from a browser page and are let through.
+func WebSocketOrigin(cfg config.CORSConfig) func(r *http.Request) bool {
+	return func(r *http.Request) bool {
+		origin := r.Header.Get("Origin")
+		if origin == "" {
+			return true
+		}
+		if u, err := url.Parse(origin); err == nil && strings.EqualFold(u.Host, r.Host) {
+			return true
+		}
+		return listedOrigin(cfg.AllowedOrigins, origin)
+	}
+}
+
+// listedOrigin reports whether origin is one of allowed, by name
+func listedOrigin(allowed []string, origin string) bool {
+	if origin == "" {
+		return false
+	}
+	for _, o := range allowed {
+		if strings.EqualFold(o, origin) {
+			return true
+		}
+	}
+	return false
+}
+
 // SecurityHeadersMiddleware adds security headers
 func SecurityHeadersMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -6,34 +6,11 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/opentracing/opentracing-go"
 	"github.com/opentracing/opentracing-go/ext"
-	"github.com/uber/jaeger-client-go/config"
-	"go.uber.org/zap"
 )
 
-var tracer opentracing.Tracer
-
-func init() {
-	cfg := config.Configuration{
-		ServiceName: "golang-boilerplate",
-		Sampler: &config.SamplerConfig{
-			Type:  "const",
-			Param: 1,
-		},
-		Reporter: &config.ReporterConfig{
-			LogSpans: true,
-		},
-	}
-
-	var err error
-	tracer, _, err = cfg.NewTracer()
-	if err != nil {
-		logger.Error("failed to create tracer", zap.Error(err))
-	}
-	opentracing.SetGlobalTracer(tracer)
-}
-
-// TracingMiddleware adds distributed tracing to requests
-func TracingMiddleware() gin.HandlerFunc {
+// TracingMiddleware adds distributed tracing to requests. A nil tracer
+// disables it.
+func TracingMiddleware(tracer opentracing.Tracer) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		if tracer == nil {
 			c.Next()
@@ -48,7 +25,9 @@ func TracingMiddleware() gin.HandlerFunc {
 		ext.HTTPUrl.Set(span, c.Request.URL.String())
 		ext.Component.Set(span, "gin")
 
+		// Queries made with the request context are traced as children
 		c.Set("span", span)
+		c.Request = c.Request.WithContext(opentracing.ContextWithSpan(c.Request.Context(), span))
 		c.Next()
 
 		ext.HTTPStatusCode.Set(span, uint16(c.Writer.Status()))
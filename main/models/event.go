@@ -0,0 +1,51 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Domain event types, as published to webhooks and the outbox
+const (
+	EventUserRegistered = "user.registered"
+	EventUserUpdated    = "user.updated"
+	EventUserDeleted    = "user.deleted"
+)
+
+// EventTypes lists every event type, for validating subscriptions
+var EventTypes = []string{EventUserRegistered, EventUserUpdated, EventUserDeleted}
+
+// AggregateUser is the aggregate type of user events in the outbox
+const AggregateUser = "user"
+
+// UserEventData is the data of user events. Deleted users only carry their ID.
+type UserEventData struct {
+	ID        uint       `json:"id"`
+	Username  string     `json:"username,omitempty"`
+	Email     string     `json:"email,omitempty"`
+	CreatedAt *time.Time `json:"created_at,omitempty"`
+	UpdatedAt *time.Time `json:"updated_at,omitempty"`
+}
+
+// NewUserEventData describes a user for an event payload
+func NewUserEventData(user *User) UserEventData {
+	return UserEventData{
+		ID:        user.ID,
+		Username:  user.Username,
+		Email:     user.Email,
+		CreatedAt: &user.CreatedAt,
+		UpdatedAt: &user.UpdatedAt,
+	}
+}
+
+// OutboxMessage is an event recorded in the same transaction as the change
+// it describes. Messages of one aggregate are published in ID order.
+type OutboxMessage struct {
+	ID            int64           `json:"id"`
+	AggregateType string          `json:"aggregate_type"`
+	AggregateID   string          `json:"aggregate_id"`
+	EventType     string          `json:"event_type"`
+	Payload       json.RawMessage `json:"payload"`
+	Attempts      int             `json:"attempts"`
+	CreatedAt     time.Time       `json:"created_at"`
+}
@@ -4,10 +4,30 @@ import (
 	"time"
 )
 
+// Roles a user can have
+const (
+	RoleUser  = "user"
+	RoleAdmin = "admin"
+)
+
 type User struct {
 	ID        uint      `json:"id"`
 	Username  string    `json:"username"`
+	Email     string    `json:"email"`
 	Password  string    `json:"-"`
+	Role      string    `json:"role"`
+	Tenant    string    `json:"tenant,omitempty"` // set by operators, never by the user
+	AvatarKey string    `json:"-"`
+	AvatarURL string    `json:"avatar_url,omitempty"` // signed on demand, not stored
+	Version   int       `json:"-"`
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
 }
+
+// UserSearchHit is a user found by a search, with its relevance and the
+// matched parts of its fields
+type UserSearchHit struct {
+	User       *User
+	Rank       float64
+	Highlights map[string]string // field name to HTML with matches wrapped in <mark>
+}
@@ -0,0 +1,82 @@
+// Package module lets feature areas plug their routes, migrations,
+// background workers, health checks and metrics into the server without
+// the server knowing about them
+package module
+
+import (
+	"context"
+	"io/fs"
+
+	"golang-boilerplate/main/handlers"
+	"golang-boilerplate/main/openapi"
+	"golang-boilerplate/main/versioning"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Module is a self-contained feature area. Modules embed Base and override
+// the hooks they need.
+type Module interface {
+	// Name identifies the module in configuration and logs. It is lower
+	// case letters, digits and underscores, starting with a letter.
+	Name() string
+	// Routes registers the module's HTTP routes
+	Routes(r *Router)
+	// Migrations returns the module's own migrations, in the layout of the
+	// migrations directory, or nil. They are tracked apart from the core
+	// ones, in a schema_migrations_<name> table, and applied even while the
+	// module is disabled.
+	Migrations() fs.FS
+	// Workers returns the background workers to run alongside the server
+	Workers() []Worker
+	// HealthChecks returns checks to report on the health endpoint
+	HealthChecks() []HealthCheck
+	// Collectors returns metrics to register with Prometheus
+	Collectors() []prometheus.Collector
+}
+
+// Base implements every hook of Module as doing nothing
+type Base struct{}
+
+func (Base) Routes(*Router)                     {}
+func (Base) Migrations() fs.FS                  { return nil }
+func (Base) Workers() []Worker                  { return nil }
+func (Base) HealthChecks() []HealthCheck        { return nil }
+func (Base) Collectors() []prometheus.Collector { return nil }
+
+// Worker is a background process. Start must return once the worker is
+// running, and Stop once it has stopped.
+type Worker struct {
+	Name  string
+	Start func(ctx context.Context) error
+	Stop  func()
+	// Early workers are stopped before the HTTP server shuts down, because
+	// connections they hold open would stall the shutdown until it times out
+	Early bool
+}
+
+// HealthCheck reports whether a dependency of a module works
+type HealthCheck struct {
+	Name  string
+	Check func(ctx context.Context) error
+}
+
+// Router is where modules mount their routes
+type Router struct {
+	// API has a group per version, v1 being the current one
+	API  *versioning.API
+	Spec *openapi.Spec
+	// Handlers serves routes with the shared services; modules add the
+	// services they build themselves with Handlers.With
+	Handlers *handlers.Handler
+
+	// Auth requires a valid token, and Admin, used after it, the admin role
+	Auth  gin.HandlerFunc
+	Admin gin.HandlerFunc
+	// Idempotency replays the response to a retried write
+	Idempotency gin.HandlerFunc
+	// CircuitBreaker fails fast while the named service, "postgres" or
+	// "redis", keeps failing
+	CircuitBreaker func(service string) gin.HandlerFunc
+}
@@ -0,0 +1,173 @@
+package module
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"regexp"
+	"sync"
+
+	"golang-boilerplate/main/config"
+	"golang-boilerplate/main/service"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/zap"
+)
+
+// namePattern matches module names, which also name migration tables
+var namePattern = regexp.MustCompile(`^[a-z][a-z0-9_]*$`)
+
+// Registry runs the hooks of the enabled modules, in the order the modules
+// were given
+type Registry struct {
+	modules []Module
+	all     []Module // disabled ones included
+	logger  *zap.Logger
+
+	mu      sync.Mutex
+	running []Worker
+}
+
+// NewRegistry keeps the modules that cfg does not disable. Disabling a
+// module that is not given is an error, as it is most likely a typo.
+func NewRegistry(cfg config.ModulesConfig, logger *zap.Logger, modules ...Module) (*Registry, error) {
+	known := make(map[string]bool, len(modules))
+	for _, m := range modules {
+		name := m.Name()
+		if !namePattern.MatchString(name) {
+			return nil, fmt.Errorf("invalid module name %q", name)
+		}
+		if known[name] {
+			return nil, fmt.Errorf("module %q given twice", name)
+		}
+		known[name] = true
+	}
+
+	disabled := make(map[string]bool, len(cfg.Disabled))
+	for _, name := range cfg.Disabled {
+		if !known[name] {
+			return nil, fmt.Errorf("cannot disable unknown module %q", name)
+		}
+		disabled[name] = true
+	}
+
+	r := &Registry{all: modules, logger: logger}
+	for _, m := range modules {
+		if disabled[m.Name()] {
+			logger.Info("module disabled", zap.String("module", m.Name()))
+			continue
+		}
+		r.modules = append(r.modules, m)
+	}
+	return r, nil
+}
+
+// Modules returns the enabled modules
+func (r *Registry) Modules() []Module {
+	return r.modules
+}
+
+// Routes has every module register its routes on router
+func (r *Registry) Routes(router *Router) {
+	for _, m := range r.modules {
+		m.Routes(router)
+	}
+}
+
+// Migrated returns the modules that have migrations of their own, disabled
+// ones included: the schema does not depend on which modules run, as other
+// code may use their tables and a module enabled again finds them ready
+func (r *Registry) Migrated() []Module {
+	var migrated []Module
+	for _, m := range r.all {
+		if m.Migrations() != nil {
+			migrated = append(migrated, m)
+		}
+	}
+	return migrated
+}
+
+// Migrate applies the migrations of the modules that have their own
+func (r *Registry) Migrate(db *sql.DB) error {
+	for _, m := range r.Migrated() {
+		if err := service.RunModuleMigrations(db, m.Name(), m.Migrations()); err != nil {
+			return fmt.Errorf("module %s: %w", m.Name(), err)
+		}
+	}
+	return nil
+}
+
+// HealthChecks returns the checks of every module by name. A check is named
+// after its module, followed by its own name if it has one.
+func (r *Registry) HealthChecks() map[string]func(context.Context) error {
+	checks := make(map[string]func(context.Context) error)
+	for _, m := range r.modules {
+		for _, check := range m.HealthChecks() {
+			name := m.Name()
+			if check.Name != "" {
+				name += "." + check.Name
+			}
+			checks[name] = check.Check
+		}
+	}
+	return checks
+}
+
+// RegisterMetrics registers the collectors of every module with reg
+func (r *Registry) RegisterMetrics(reg prometheus.Registerer) error {
+	for _, m := range r.modules {
+		for _, collector := range m.Collectors() {
+			if err := reg.Register(collector); err != nil {
+				return fmt.Errorf("module %s: could not register metrics: %w", m.Name(), err)
+			}
+		}
+	}
+	return nil
+}
+
+// Start starts the workers of every module in order. If one fails to
+// start, the ones already running are stopped again.
+func (r *Registry) Start(ctx context.Context) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, m := range r.modules {
+		for _, w := range m.Workers() {
+			if err := w.Start(ctx); err != nil {
+				r.stop(func(Worker) bool { return true })
+				return fmt.Errorf("could not start %s worker of module %s: %w", w.Name, m.Name(), err)
+			}
+			r.running = append(r.running, w)
+		}
+	}
+	return nil
+}
+
+// StopEarly stops the early workers, before the HTTP server shuts down
+func (r *Registry) StopEarly() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.stop(func(w Worker) bool { return w.Early })
+}
+
+// Stop stops the workers still running, in the reverse order of starting
+func (r *Registry) Stop() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.stop(func(Worker) bool { return true })
+}
+
+// stop stops the running workers that match, last started first. The
+// caller holds the lock.
+func (r *Registry) stop(match func(Worker) bool) {
+	var remaining []Worker
+	for i := len(r.running) - 1; i >= 0; i-- {
+		w := r.running[i]
+		if !match(w) {
+			remaining = append([]Worker{w}, remaining...)
+			continue
+		}
+		w.Stop()
+	}
+	r.running = remaining
+}
@@ -0,0 +1,89 @@
+// Package modules holds the feature areas of the server, each a
+// module.Module that cmd/main.go composes
+package modules
+
+import (
+	"golang-boilerplate/main/handlers"
+	"golang-boilerplate/main/middleware"
+	"golang-boilerplate/main/module"
+	"golang-boilerplate/main/openapi"
+	"golang-boilerplate/main/versioning"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Auth serves registration, login and the example protected route on every
+// API version
+type Auth struct {
+	module.Base
+}
+
+func NewAuth() *Auth {
+	return &Auth{}
+}
+
+func (m *Auth) Name() string {
+	return "auth"
+}
+
+func (m *Auth) Routes(r *module.Router) {
+	h := r.Handlers
+
+	r.API.Each(func(group *gin.RouterGroup, v *versioning.Version) {
+		deprecated := v.IsDeprecated()
+
+		// Login is left out of idempotency, which would keep the token it
+		// answers with and hand it to whoever repeats the key
+		login := group.Group("")
+		login.Use(middleware.ETagMiddleware())
+		{
+			r.Spec.Handle(login, http.MethodPost, "/login", openapi.Route{
+				Summary:    "Exchange credentials for a JWT",
+				Tags:       []string{"auth"},
+				Negotiated: true,
+				Deprecated: deprecated,
+				Request:    handlers.LoginRequest{},
+				Responses: map[int]interface{}{
+					http.StatusOK:           handlers.TokenResponse{},
+					http.StatusBadRequest:   handlers.ValidationErrorResponse{},
+					http.StatusUnauthorized: handlers.ErrorResponse{},
+				},
+			}, h.LoginHandler)
+		}
+
+		public := group.Group("")
+		public.Use(middleware.ETagMiddleware(), r.Idempotency)
+		{
+			r.Spec.Handle(public, http.MethodPost, "/register", openapi.Route{
+				Summary:    "Create a user account",
+				Tags:       []string{"auth"},
+				Negotiated: true,
+				Deprecated: deprecated,
+				Request:    handlers.RegisterRequest{},
+				Responses: map[int]interface{}{
+					http.StatusCreated:             handlers.MessageResponse{},
+					http.StatusBadRequest:          handlers.ValidationErrorResponse{},
+					http.StatusConflict:            handlers.ErrorResponse{},
+					http.StatusInternalServerError: handlers.ErrorResponse{},
+				},
+			}, h.RegisterHandler)
+		}
+
+		protected := group.Group("")
+		protected.Use(r.Auth, middleware.ETagMiddleware(), r.Idempotency)
+		{
+			r.Spec.Handle(protected, http.MethodGet, "/protected", openapi.Route{
+				Summary:    "Example route that requires a JWT",
+				Tags:       []string{"auth"},
+				Negotiated: true,
+				Deprecated: deprecated,
+				Secured:    true,
+				Responses: map[int]interface{}{
+					http.StatusOK:           handlers.ProtectedResponse{},
+					http.StatusUnauthorized: handlers.ErrorResponse{},
+				},
+			}, h.ProtectedHandler)
+		}
+	})
+}
@@ -0,0 +1,116 @@
+package modules
+
+import (
+	"context"
+	"golang-boilerplate/main/app"
+	"golang-boilerplate/main/flags"
+	"golang-boilerplate/main/handlers"
+	"golang-boilerplate/main/module"
+	"golang-boilerplate/main/openapi"
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Flags serves the admin API of the feature flags in App.Flags and keeps the
+// flags of every instance in sync through Redis. With the module disabled
+// the store is never loaded, so every flag is off.
+type Flags struct {
+	module.Base
+	app *app.App
+}
+
+// NewFlags returns the flags module. With a nil a the module only has its
+// name.
+func NewFlags(a *app.App) *Flags {
+	return &Flags{app: a}
+}
+
+func (m *Flags) Name() string {
+	return "flags"
+}
+
+func (m *Flags) Routes(r *module.Router) {
+	h := r.Handlers
+
+	admin := r.API.Group("v1").Group("/admin")
+	admin.Use(r.Auth, r.Admin)
+	{
+		r.Spec.Handle(admin, http.MethodGet, "/flags", openapi.Route{
+			Summary:    "List feature flags",
+			Tags:       []string{"flags"},
+			Negotiated: true,
+			Secured:    true,
+			Responses:  map[int]interface{}{http.StatusOK: []flags.Flag{}},
+		}, h.ListFlagsHandler)
+		r.Spec.Handle(admin, http.MethodGet, "/flags/:key", openapi.Route{
+			Summary:    "Fetch a feature flag",
+			Tags:       []string{"flags"},
+			Negotiated: true,
+			Params:     handlers.FlagParams{},
+			Secured:    true,
+			Responses: map[int]interface{}{
+				http.StatusOK:       flags.Flag{},
+				http.StatusNotFound: handlers.ErrorResponse{},
+			},
+		}, h.GetFlagHandler)
+		r.Spec.Handle(admin, http.MethodPut, "/flags/:key", openapi.Route{
+			Summary:    "Create or replace a feature flag",
+			Tags:       []string{"flags"},
+			Negotiated: true,
+			Params:     handlers.FlagParams{},
+			Request:    handlers.PutFlagRequest{},
+			Secured:    true,
+			Responses: map[int]interface{}{
+				http.StatusOK:         flags.Flag{},
+				http.StatusBadRequest: handlers.ValidationErrorResponse{},
+			},
+		}, h.PutFlagHandler)
+		r.Spec.Handle(admin, http.MethodDelete, "/flags/:key", openapi.Route{
+			Summary:    "Delete a feature flag",
+			Tags:       []string{"flags"},
+			Negotiated: true,
+			Params:     handlers.FlagParams{},
+			Secured:    true,
+			Responses: map[int]interface{}{
+				http.StatusNoContent: nil,
+				http.StatusNotFound:  handlers.ErrorResponse{},
+			},
+		}, h.DeleteFlagHandler)
+		r.Spec.Handle(admin, http.MethodPost, "/flags/:key/evaluate", openapi.Route{
+			Summary:    "Evaluate a feature flag for a user, role or tenant",
+			Tags:       []string{"flags"},
+			Negotiated: true,
+			Params:     handlers.FlagParams{},
+			Request:    handlers.EvaluateFlagRequest{},
+			Secured:    true,
+			Responses: map[int]interface{}{
+				http.StatusOK:       flags.Evaluation{},
+				http.StatusNotFound: handlers.ErrorResponse{},
+			},
+		}, h.EvaluateFlagHandler)
+	}
+}
+
+func (m *Flags) Workers() []module.Worker {
+	store := m.app.Flags
+	return []module.Worker{{
+		Name: "sync",
+		Start: func(ctx context.Context) error {
+			return store.Start(ctx, m.app.Redis, m.app.Config.Flags.RefreshInterval)
+		},
+		Stop: store.Stop,
+	}}
+}
+
+func (m *Flags) Collectors() []prometheus.Collector {
+	store := m.app.Flags
+	return []prometheus.Collector{
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Name: "feature_flags",
+			Help: "Number of feature flags defined",
+		}, func() float64 {
+			return float64(len(store.List()))
+		}),
+	}
+}
@@ -0,0 +1,62 @@
+package modules
+
+import (
+	"context"
+	"embed"
+	"fmt"
+	"golang-boilerplate/main/app"
+	"golang-boilerplate/main/module"
+	"golang-boilerplate/main/outbox"
+	"golang-boilerplate/main/repo"
+	"io/fs"
+)
+
+//go:embed migrations/outbox/*.sql
+var outboxMigrations embed.FS
+
+// Outbox relays the events recorded in the outbox to the configured sink
+// and to webhook subscribers. It should come after the webhooks module, so
+// the dispatcher is running before the first events are relayed.
+type Outbox struct {
+	module.Base
+	app      *app.App
+	webhooks *Webhooks
+	relay    *outbox.Relay
+}
+
+// NewOutbox builds the relay, which also hands events to the dispatcher of
+// webhooks. With a nil a the module only has its name and migrations.
+func NewOutbox(a *app.App, webhooks *Webhooks) *Outbox {
+	m := &Outbox{app: a, webhooks: webhooks}
+	if a != nil {
+		m.relay = outbox.NewRelay(repo.NewOutboxRepo(a.DB, a.Config.Database.QueryTimeout), a.Config.Outbox, a.Logger)
+	}
+	return m
+}
+
+func (m *Outbox) Name() string {
+	return "outbox"
+}
+
+// Migrations creates the outbox table. The user repository records events
+// in it whether or not the relay runs.
+func (m *Outbox) Migrations() fs.FS {
+	migrations, _ := fs.Sub(outboxMigrations, "migrations/outbox")
+	return migrations
+}
+
+func (m *Outbox) Workers() []module.Worker {
+	a := m.app
+	return []module.Worker{{
+		Name: "relay",
+		Start: func(context.Context) error {
+			sink, err := outbox.NewSink(a.Config.Outbox, a.Redis, a.Logger)
+			if err != nil {
+				return fmt.Errorf("could not create outbox sink: %w", err)
+			}
+			m.relay.Start(outbox.Fanout(sink, outbox.SinkFunc(m.webhooks.publishOutbox)))
+			return nil
+		},
+		Stop: m.relay.Stop,
+	}}
+}
@@ -0,0 +1,47 @@
+package modules
+
+import (
+	"context"
+	"golang-boilerplate/main/app"
+	"golang-boilerplate/main/middleware"
+	"golang-boilerplate/main/module"
+)
+
+// Realtime streams events to signed-in users over WebSocket and SSE, fanned
+// out to every instance through Redis
+type Realtime struct {
+	module.Base
+	app *app.App
+}
+
+func NewRealtime(a *app.App) *Realtime {
+	return &Realtime{app: a}
+}
+
+func (m *Realtime) Name() string {
+	return "realtime"
+}
+
+func (m *Realtime) Routes(r *module.Router) {
+	hub := m.app.Hub
+
+	events := r.API.Group("v1").Group("/events")
+	events.Use(middleware.QueryTokenMiddleware(), r.Auth)
+	{
+		events.GET("/ws", hub.WebSocketHandler(middleware.WebSocketOrigin(m.app.Config.CORS)))
+		events.GET("/stream", hub.SSEHandler())
+	}
+}
+
+func (m *Realtime) Workers() []module.Worker {
+	hub := m.app.Hub
+	return []module.Worker{{
+		Name: "hub",
+		Start: func(ctx context.Context) error {
+			return hub.Start(ctx, m.app.Redis)
+		},
+		Stop: hub.Stop,
+		// Open streams would hold the HTTP shutdown until it times out
+		Early: true,
+	}}
+}
@@ -0,0 +1,173 @@
+package modules
+
+import (
+	"context"
+	"golang-boilerplate/main/app"
+	"golang-boilerplate/main/gql"
+	"golang-boilerplate/main/handlers"
+	"golang-boilerplate/main/imports"
+	"golang-boilerplate/main/middleware"
+	"golang-boilerplate/main/module"
+	"golang-boilerplate/main/openapi"
+	"net/http"
+)
+
+// Users serves the user resource, avatars, GraphQL and the admin search and
+// bulk import, and runs the import workers
+type Users struct {
+	module.Base
+	app      *app.App
+	importer *imports.Importer
+}
+
+// NewUsers builds the importer. With a nil a the module only has its name.
+func NewUsers(a *app.App) *Users {
+	m := &Users{app: a}
+	if a != nil {
+		m.importer = imports.NewImporter(a.Users, a.Redis, a.Config.Imports, a.Logger)
+	}
+	return m
+}
+
+func (m *Users) Name() string {
+	return "users"
+}
+
+func (m *Users) Routes(r *module.Router) {
+	h := r.Handlers.With(func(deps *handlers.Deps) {
+		deps.Importer = m.importer
+	})
+	v1 := r.API.Group("v1")
+
+	protected := v1.Group("")
+	protected.Use(r.Auth, middleware.ETagMiddleware(), r.Idempotency)
+	{
+		r.Spec.Handle(protected, http.MethodGet, "/users/:id", openapi.Route{
+			Summary:    "Fetch a user; supports If-None-Match",
+			Tags:       []string{"users"},
+			Negotiated: true,
+			Params:     handlers.UserParams{},
+			Secured:    true,
+			Responses: map[int]interface{}{
+				http.StatusOK:          handlers.UserResponse{},
+				http.StatusNotModified: nil,
+				http.StatusNotFound:    handlers.ErrorResponse{},
+			},
+		}, h.GetUserHandler)
+		r.Spec.Handle(protected, http.MethodPatch, "/users/:id", openapi.Route{
+			Summary:    "Update your own user; requires If-Match",
+			Tags:       []string{"users"},
+			Negotiated: true,
+			Params:     handlers.UserParams{},
+			Request:    handlers.UpdateUserRequest{},
+			Secured:    true,
+			Responses: map[int]interface{}{
+				http.StatusOK:                   handlers.UserResponse{},
+				http.StatusBadRequest:           handlers.ValidationErrorResponse{},
+				http.StatusForbidden:            handlers.ErrorResponse{},
+				http.StatusNotFound:             handlers.ErrorResponse{},
+				http.StatusConflict:             handlers.ErrorResponse{},
+				http.StatusPreconditionFailed:   handlers.ErrorResponse{},
+				http.StatusPreconditionRequired: handlers.ErrorResponse{},
+			},
+		}, h.UpdateUserHandler)
+		r.Spec.Handle(protected, http.MethodDelete, "/users/:id", openapi.Route{
+			Summary:    "Delete your own user; requires If-Match",
+			Tags:       []string{"users"},
+			Negotiated: true,
+			Params:     handlers.UserParams{},
+			Secured:    true,
+			Responses: map[int]interface{}{
+				http.StatusNoContent:            nil,
+				http.StatusForbidden:            handlers.ErrorResponse{},
+				http.StatusNotFound:             handlers.ErrorResponse{},
+				http.StatusPreconditionFailed:   handlers.ErrorResponse{},
+				http.StatusPreconditionRequired: handlers.ErrorResponse{},
+			},
+		}, h.DeleteUserHandler)
+
+		r.Spec.Handle(protected, http.MethodPost, "/users/:id/avatar", openapi.Route{
+			Summary:    "Upload your avatar as multipart/form-data in the avatar field",
+			Tags:       []string{"users"},
+			Negotiated: true,
+			Params:     handlers.UserParams{},
+			Secured:    true,
+			Responses: map[int]interface{}{
+				http.StatusOK:                    handlers.UserResponse{},
+				http.StatusBadRequest:            handlers.ErrorResponse{},
+				http.StatusForbidden:             handlers.ErrorResponse{},
+				http.StatusRequestEntityTooLarge: handlers.ErrorResponse{},
+				http.StatusUnsupportedMediaType:  handlers.ErrorResponse{},
+				http.StatusUnprocessableEntity:   handlers.ErrorResponse{},
+			},
+		}, h.UploadAvatarHandler)
+		r.Spec.Handle(protected, http.MethodDelete, "/users/:id/avatar", openapi.Route{
+			Summary:    "Remove your avatar",
+			Tags:       []string{"users"},
+			Negotiated: true,
+			Params:     handlers.UserParams{},
+			Secured:    true,
+			Responses: map[int]interface{}{
+				http.StatusOK:        handlers.UserResponse{},
+				http.StatusForbidden: handlers.ErrorResponse{},
+				http.StatusNotFound:  handlers.ErrorResponse{},
+			},
+		}, h.DeleteAvatarHandler)
+
+		protected.POST("/graphql", gql.Handler(m.app.UserService, m.app.Config.GraphQL))
+	}
+
+	// These skip the ETag and idempotency middleware, which would buffer
+	// large uploads in memory
+	admin := v1.Group("/admin")
+	admin.Use(r.Auth, r.Admin)
+	{
+		r.Spec.Handle(admin, http.MethodGet, "/users/search", openapi.Route{
+			Summary: "Search users by partial username or email, best matches first",
+			Tags:    []string{"admin"},
+			Query:   handlers.SearchUsersQuery{},
+			Secured: true,
+			Responses: map[int]interface{}{
+				http.StatusOK:         handlers.SearchUsersResponse{},
+				http.StatusBadRequest: handlers.ValidationErrorResponse{},
+				http.StatusForbidden:  handlers.ErrorResponse{},
+			},
+		}, h.SearchUsersHandler)
+		r.Spec.Handle(admin, http.MethodPost, "/users/import", openapi.Route{
+			Summary: "Bulk import users from a text/csv or application/x-ndjson body",
+			Tags:    []string{"admin"},
+			Secured: true,
+			Responses: map[int]interface{}{
+				http.StatusAccepted:              imports.Job{},
+				http.StatusBadRequest:            handlers.ErrorResponse{},
+				http.StatusForbidden:             handlers.ErrorResponse{},
+				http.StatusRequestEntityTooLarge: handlers.ErrorResponse{},
+				http.StatusUnsupportedMediaType:  handlers.ErrorResponse{},
+			},
+		}, h.ImportUsersHandler)
+		r.Spec.Handle(admin, http.MethodGet, "/users/import/:id", openapi.Route{
+			Summary: "Status and row errors of a user import",
+			Tags:    []string{"admin"},
+			Params:  handlers.ImportParams{},
+			Secured: true,
+			Responses: map[int]interface{}{
+				http.StatusOK:        imports.Job{},
+				http.StatusForbidden: handlers.ErrorResponse{},
+				http.StatusNotFound:  handlers.ErrorResponse{},
+			},
+		}, h.ImportJobHandler)
+	}
+}
+
+func (m *Users) Workers() []module.Worker {
+	importer := m.importer
+	return []module.Worker{{
+		Name: "imports",
+		Start: func(context.Context) error {
+			importer.Start()
+			return nil
+		},
+		// Running imports are marked failed; their status stays queryable
+		Stop: importer.Stop,
+	}}
+}
@@ -0,0 +1,161 @@
+package modules
+
+import (
+	"context"
+	"embed"
+	"errors"
+	"golang-boilerplate/main/app"
+	"golang-boilerplate/main/handlers"
+	"golang-boilerplate/main/models"
+	"golang-boilerplate/main/module"
+	"golang-boilerplate/main/openapi"
+	"golang-boilerplate/main/repo"
+	"golang-boilerplate/main/webhooks"
+	"io/fs"
+	"net/http"
+)
+
+//go:embed migrations/webhooks/*.sql
+var webhooksMigrations embed.FS
+
+// Webhooks serves the admin API of webhook subscriptions and runs the
+// dispatcher delivering events to them. With the module disabled, events
+// are not delivered to subscribers.
+type Webhooks struct {
+	module.Base
+	repo       *repo.WebhookRepo
+	dispatcher *webhooks.Dispatcher
+}
+
+// NewWebhooks builds the dispatcher and subscribes it to the events of a.
+// With a nil a the module only has its name and migrations.
+func NewWebhooks(a *app.App) *Webhooks {
+	m := &Webhooks{}
+	if a != nil {
+		m.repo = repo.NewWebhookRepo(a.DB, a.Config.Database.QueryTimeout)
+		m.dispatcher = webhooks.NewDispatcher(m.repo, a.Config.Webhooks, a.Logger)
+		a.Events.Subscribe(m.dispatcher)
+	}
+	return m
+}
+
+func (m *Webhooks) Name() string {
+	return "webhooks"
+}
+
+func (m *Webhooks) Routes(r *module.Router) {
+	h := r.Handlers.With(func(deps *handlers.Deps) {
+		deps.Webhooks = m.repo
+		deps.Dispatcher = m.dispatcher
+	})
+
+	admin := r.API.Group("v1").Group("/admin")
+	admin.Use(r.Auth, r.Admin)
+	{
+		r.Spec.Handle(admin, http.MethodPost, "/webhooks", openapi.Route{
+			Summary: "Subscribe an endpoint to events; the response carries the signing secret",
+			Tags:    []string{"webhooks"},
+			Request: handlers.CreateWebhookRequest{},
+			Secured: true,
+			Responses: map[int]interface{}{
+				http.StatusCreated:    handlers.CreateWebhookResponse{},
+				http.StatusBadRequest: handlers.ValidationErrorResponse{},
+			},
+		}, h.CreateWebhookHandler)
+		r.Spec.Handle(admin, http.MethodGet, "/webhooks", openapi.Route{
+			Summary:   "List webhook subscriptions",
+			Tags:      []string{"webhooks"},
+			Secured:   true,
+			Responses: map[int]interface{}{http.StatusOK: []models.WebhookSubscription{}},
+		}, h.ListWebhooksHandler)
+		r.Spec.Handle(admin, http.MethodGet, "/webhooks/:id", openapi.Route{
+			Summary: "Fetch a webhook subscription",
+			Tags:    []string{"webhooks"},
+			Params:  handlers.WebhookParams{},
+			Secured: true,
+			Responses: map[int]interface{}{
+				http.StatusOK:       models.WebhookSubscription{},
+				http.StatusNotFound: handlers.ErrorResponse{},
+			},
+		}, h.GetWebhookHandler)
+		r.Spec.Handle(admin, http.MethodPatch, "/webhooks/:id", openapi.Route{
+			Summary: "Enable or disable a webhook subscription",
+			Tags:    []string{"webhooks"},
+			Params:  handlers.WebhookParams{},
+			Request: handlers.UpdateWebhookRequest{},
+			Secured: true,
+			Responses: map[int]interface{}{
+				http.StatusOK:       models.WebhookSubscription{},
+				http.StatusNotFound: handlers.ErrorResponse{},
+			},
+		}, h.UpdateWebhookHandler)
+		r.Spec.Handle(admin, http.MethodDelete, "/webhooks/:id", openapi.Route{
+			Summary: "Delete a webhook subscription and its delivery log",
+			Tags:    []string{"webhooks"},
+			Params:  handlers.WebhookParams{},
+			Secured: true,
+			Responses: map[int]interface{}{
+				http.StatusNoContent: nil,
+				http.StatusNotFound:  handlers.ErrorResponse{},
+			},
+		}, h.DeleteWebhookHandler)
+		r.Spec.Handle(admin, http.MethodGet, "/webhooks/:id/deliveries", openapi.Route{
+			Summary: "List deliveries of a webhook subscription, newest first",
+			Tags:    []string{"webhooks"},
+			Params:  handlers.WebhookParams{},
+			Query:   handlers.PageQuery{},
+			Secured: true,
+			Responses: map[int]interface{}{
+				http.StatusOK:       []models.WebhookDelivery{},
+				http.StatusNotFound: handlers.ErrorResponse{},
+			},
+		}, h.ListDeliveriesHandler)
+		r.Spec.Handle(admin, http.MethodGet, "/webhooks/:id/deliveries/:delivery_id", openapi.Route{
+			Summary: "Fetch a delivery with its attempts",
+			Tags:    []string{"webhooks"},
+			Params:  handlers.DeliveryParams{},
+			Secured: true,
+			Responses: map[int]interface{}{
+				http.StatusOK:       handlers.DeliveryResponse{},
+				http.StatusNotFound: handlers.ErrorResponse{},
+			},
+		}, h.GetDeliveryHandler)
+		r.Spec.Handle(admin, http.MethodPost, "/webhooks/:id/deliveries/:delivery_id/redeliver", openapi.Route{
+			Summary: "Send a delivery again",
+			Tags:    []string{"webhooks"},
+			Params:  handlers.DeliveryParams{},
+			Secured: true,
+			Responses: map[int]interface{}{
+				http.StatusAccepted: models.WebhookDelivery{},
+				http.StatusNotFound: handlers.ErrorResponse{},
+			},
+		}, h.RedeliverHandler)
+	}
+}
+
+func (m *Webhooks) Migrations() fs.FS {
+	migrations, _ := fs.Sub(webhooksMigrations, "migrations/webhooks")
+	return migrations
+}
+
+func (m *Webhooks) Workers() []module.Worker {
+	dispatcher := m.dispatcher
+	return []module.Worker{{
+		Name: "dispatcher",
+		Start: func(context.Context) error {
+			dispatcher.Start()
+			return nil
+		},
+		Stop: dispatcher.Stop,
+	}}
+}
+
+// publishOutbox hands a message relayed by the outbox to the dispatcher.
+// While the module is disabled the message must still count as published.
+func (m *Webhooks) publishOutbox(ctx context.Context, msg *models.OutboxMessage) error {
+	err := m.dispatcher.PublishOutbox(ctx, msg)
+	if errors.Is(err, webhooks.ErrNotRunning) {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,193 @@
+package negotiate
+
+import (
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"golang-boilerplate/main/i18n"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/gin-gonic/gin/render"
+	"google.golang.org/protobuf/proto"
+)
+
+// Media types handlers can exchange. MessagePack bodies use the JSON field
+// names.
+const (
+	MIMEJSON     = binding.MIMEJSON
+	MIMEMsgPack  = binding.MIMEMSGPACK2
+	MIMEProtobuf = binding.MIMEPROTOBUF
+)
+
+// aliases maps other names clients use to the media types above
+var aliases = map[string]string{
+	binding.MIMEMSGPACK:               MIMEMsgPack,
+	"application/protobuf":            MIMEProtobuf,
+	"application/vnd.google.protobuf": MIMEProtobuf,
+}
+
+// ErrUnsupportedMediaType is returned by Bind for bodies it cannot decode
+var ErrUnsupportedMediaType = errors.New("unsupported media type")
+
+// Protoer is implemented by responses that have a Protobuf form. Responses
+// without one are only offered as JSON and MessagePack.
+type Protoer interface {
+	Proto() proto.Message
+}
+
+// ProtoDecoder is implemented by requests that can be decoded from
+// Protobuf. NewProto returns an empty message to decode the body into, and
+// FromProto copies the decoded message into the request.
+type ProtoDecoder interface {
+	NewProto() proto.Message
+	FromProto(msg proto.Message)
+}
+
+// Respond writes obj in the format the Accept header prefers. If the client
+// accepts none of the formats obj has, success responses get 406 Not
+// Acceptable, while error responses fall back to JSON so the error is not
+// masked.
+func Respond(c *gin.Context, status int, obj interface{}) {
+	c.Writer.Header().Add("Vary", "Accept")
+
+	switch Format(c.GetHeader("Accept"), obj) {
+	case MIMEJSON:
+		c.Render(status, render.JSON{Data: obj})
+	case MIMEMsgPack:
+		c.Render(status, render.MsgPack{Data: obj})
+	case MIMEProtobuf:
+		c.Render(status, render.ProtoBuf{Data: obj.(Protoer).Proto()})
+	default:
+		if status >= http.StatusBadRequest {
+			c.JSON(status, obj)
+			return
+		}
+		c.JSON(http.StatusNotAcceptable, i18n.Error(c, "request.not_acceptable"))
+	}
+}
+
+// Acceptable rejects a request with 406 Not Acceptable when its Accept
+// header allows none of the formats of the success responses, given as zero
+// values. Placed before a handler it turns the request away before the
+// handler has changed anything, rather than when it responds.
+func Acceptable(responses ...interface{}) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		accept := c.GetHeader("Accept")
+		for _, obj := range responses {
+			if Format(accept, obj) != "" {
+				c.Next()
+				return
+			}
+		}
+		c.Writer.Header().Add("Vary", "Accept")
+		c.AbortWithStatusJSON(http.StatusNotAcceptable, i18n.Error(c, "request.not_acceptable"))
+	}
+}
+
+// Format picks the media type to render obj in for an Accept header, or ""
+// if none is acceptable. Formats the client weighs equally are chosen in the
+// order JSON, MessagePack, Protobuf.
+func Format(accept string, obj interface{}) string {
+	offered := []string{MIMEJSON, MIMEMsgPack}
+	if _, ok := obj.(Protoer); ok {
+		offered = append(offered, MIMEProtobuf)
+	}
+	if strings.TrimSpace(accept) == "" {
+		return MIMEJSON
+	}
+
+	best, bestQ := "", 0.0
+	for _, mediaType := range offered {
+		if q := quality(accept, mediaType); q > bestQ {
+			best, bestQ = mediaType, q
+		}
+	}
+	return best
+}
+
+// quality returns the weight an Accept header gives a media type, using
+// the most specific matching range
+func quality(accept, mediaType string) float64 {
+	q, specificity := 0.0, -1
+	for _, part := range strings.Split(accept, ",") {
+		rangeType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		rangeType = Canonical(rangeType)
+
+		var s int
+		switch {
+		case rangeType == mediaType:
+			s = 2
+		case strings.HasSuffix(rangeType, "/*") && strings.HasPrefix(mediaType, strings.TrimSuffix(rangeType, "*")):
+			s = 1
+		case rangeType == "*/*":
+			s = 0
+		default:
+			continue
+		}
+		if s <= specificity {
+			continue
+		}
+
+		weight := 1.0
+		if v, ok := params["q"]; ok {
+			if weight, err = strconv.ParseFloat(v, 64); err != nil {
+				weight = 0
+			}
+		}
+		q, specificity = weight, s
+	}
+	return q
+}
+
+// Bind decodes the request body according to its Content-Type into obj and
+// validates it. JSON is assumed when no Content-Type is given. It returns
+// ErrUnsupportedMediaType for other types, and for Protobuf bodies when obj
+// is not a ProtoDecoder.
+func Bind(c *gin.Context, obj interface{}) error {
+	contentType := c.ContentType()
+	if contentType == "" {
+		contentType = MIMEJSON
+	}
+
+	switch Canonical(contentType) {
+	case MIMEJSON:
+		return c.ShouldBindWith(obj, binding.JSON)
+	case MIMEMsgPack:
+		return c.ShouldBindWith(obj, binding.MsgPack)
+	case MIMEProtobuf:
+		decoder, ok := obj.(ProtoDecoder)
+		if !ok {
+			return ErrUnsupportedMediaType
+		}
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			return err
+		}
+		msg := decoder.NewProto()
+		if err := proto.Unmarshal(body, msg); err != nil {
+			return err
+		}
+		decoder.FromProto(msg)
+		return binding.Validator.ValidateStruct(obj)
+	default:
+		return ErrUnsupportedMediaType
+	}
+}
+
+// Canonical maps alternative names of the supported media types, such as
+// application/x-msgpack, to the names used here
+func Canonical(mediaType string) string {
+	mediaType = strings.ToLower(mediaType)
+	if alias, ok := aliases[mediaType]; ok {
+		return alias
+	}
+	return mediaType
+}
@@ -0,0 +1,189 @@
+package openapi
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang-boilerplate/main/validation"
+)
+
+// Schema is the subset of JSON Schema used to describe request and response types
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+	Enum                 []string           `json:"enum,omitempty"`
+	Pattern              string             `json:"pattern,omitempty"`
+	MinLength            *int               `json:"minLength,omitempty"`
+	MaxLength            *int               `json:"maxLength,omitempty"`
+	Minimum              *float64           `json:"minimum,omitempty"`
+	Maximum              *float64           `json:"maximum,omitempty"`
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// schemaFor describes a Go type, registering named structs as components
+func (s *Spec) schemaFor(t reflect.Type) *Schema {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	switch {
+	case t == timeType:
+		return &Schema{Type: "string", Format: "date-time"}
+	case t.Kind() == reflect.Struct && t.Name() != "":
+		name := t.Name()
+		if _, ok := s.doc.Components.Schemas[name]; !ok {
+			// Reserve the name first so self-referencing types terminate
+			s.doc.Components.Schemas[name] = &Schema{}
+			*s.doc.Components.Schemas[name] = *s.structSchema(t)
+		}
+		return &Schema{Ref: "#/components/schemas/" + name}
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		return s.structSchema(t)
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &Schema{Type: "integer"}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: "string", Format: "byte"}
+		}
+		return &Schema{Type: "array", Items: s.schemaFor(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: s.schemaFor(t.Elem())}
+	default:
+		// interface{} and anything else accept any value
+		return &Schema{}
+	}
+}
+
+func (s *Spec) structSchema(t reflect.Type) *Schema {
+	schema := &Schema{Type: "object", Properties: make(map[string]*Schema)}
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		// Embedded structs without a json name are flattened, as
+		// encoding/json does
+		if field.Anonymous && field.Tag.Get("json") == "" && field.Type.Kind() == reflect.Struct {
+			embedded := s.structSchema(field.Type)
+			for name, prop := range embedded.Properties {
+				schema.Properties[name] = prop
+			}
+			schema.Required = append(schema.Required, embedded.Required...)
+			continue
+		}
+
+		name := fieldName(field)
+		if name == "" {
+			continue
+		}
+
+		prop := s.schemaFor(field.Type)
+		if applyBinding(prop, field.Tag.Get("binding")) {
+			schema.Required = append(schema.Required, name)
+		}
+		schema.Properties[name] = prop
+	}
+
+	return schema
+}
+
+// fieldName mirrors the names clients use: json for bodies, form for query
+// strings and uri for path params
+func fieldName(field reflect.StructField) string {
+	for _, tag := range []string{"json", "form", "uri"} {
+		name := strings.SplitN(field.Tag.Get(tag), ",", 2)[0]
+		if name == "-" {
+			return ""
+		}
+		if name != "" {
+			return name
+		}
+	}
+	return field.Name
+}
+
+// applyBinding translates gin binding rules into schema constraints and
+// reports whether the field is required. Rules after "dive" apply to the
+// items of an array.
+func applyBinding(schema *Schema, binding string) bool {
+	required := false
+	target := schema
+	for _, rule := range strings.Split(binding, ",") {
+		name, param, _ := strings.Cut(rule, "=")
+		if name == "required" && target == schema {
+			required = true
+		}
+		if name == "dive" && target.Items != nil {
+			target = target.Items
+			continue
+		}
+		// Constraints on a $ref would be ignored by the referenced schema
+		if target.Ref != "" {
+			continue
+		}
+
+		switch name {
+		case "username":
+			target.MinLength = intPtr(validation.UsernameMinLength)
+			target.MaxLength = intPtr(validation.UsernameMaxLength)
+			target.Pattern = validation.UsernamePattern
+		case "password":
+			target.MinLength = intPtr(validation.PasswordMinLength)
+			target.MaxLength = intPtr(validation.PasswordMaxLength)
+		case "flag_key":
+			target.MaxLength = intPtr(validation.FlagKeyMaxLength)
+			target.Pattern = validation.FlagKeyPattern
+		case "email_address", "email":
+			target.Format = "email"
+		case "url", "http_url":
+			target.Format = "uri"
+		case "oneof":
+			target.Enum = strings.Fields(param)
+		case "min", "max":
+			n, err := strconv.ParseFloat(param, 64)
+			if err != nil {
+				continue
+			}
+			switch target.Type {
+			case "string":
+				if name == "min" {
+					target.MinLength = intPtr(int(n))
+				} else {
+					target.MaxLength = intPtr(int(n))
+				}
+			case "integer", "number":
+				if name == "min" {
+					target.Minimum = &n
+				} else {
+					target.Maximum = &n
+				}
+			}
+		}
+	}
+
+	return required
+}
+
+func intPtr(n int) *int {
+	return &n
+}
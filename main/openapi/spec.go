@@ -0,0 +1,351 @@
+package openapi
+
+import (
+	"net/http"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"golang-boilerplate/main/negotiate"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Document is an OpenAPI 3.1 document
+type Document struct {
+	OpenAPI    string                           `json:"openapi"`
+	Info       Info                             `json:"info"`
+	Paths      map[string]map[string]*Operation `json:"paths"`
+	Components Components                       `json:"components"`
+}
+
+// Info describes the API
+type Info struct {
+	Title   string `json:"title"`
+	Version string `json:"version"`
+}
+
+// Components holds the reusable schemas and security schemes
+type Components struct {
+	Schemas         map[string]*Schema         `json:"schemas"`
+	SecuritySchemes map[string]*SecurityScheme `json:"securitySchemes"`
+}
+
+// SecurityScheme describes how clients authenticate
+type SecurityScheme struct {
+	Type         string `json:"type"`
+	Scheme       string `json:"scheme"`
+	BearerFormat string `json:"bearerFormat,omitempty"`
+}
+
+// Operation is a documented route
+type Operation struct {
+	OperationID string                `json:"operationId,omitempty"`
+	Summary     string                `json:"summary,omitempty"`
+	Tags        []string              `json:"tags,omitempty"`
+	Parameters  []*Parameter          `json:"parameters,omitempty"`
+	RequestBody *RequestBody          `json:"requestBody,omitempty"`
+	Responses   map[string]*Response  `json:"responses"`
+	Security    []map[string][]string `json:"security,omitempty"`
+	Deprecated  bool                  `json:"deprecated,omitempty"`
+}
+
+// Parameter is a path or query parameter
+type Parameter struct {
+	Name     string  `json:"name"`
+	In       string  `json:"in"`
+	Required bool    `json:"required"`
+	Schema   *Schema `json:"schema"`
+}
+
+// RequestBody describes the JSON body an operation accepts
+type RequestBody struct {
+	Required bool                  `json:"required"`
+	Content  map[string]*MediaType `json:"content"`
+}
+
+// Response describes one status code of an operation
+type Response struct {
+	Description string                `json:"description"`
+	Content     map[string]*MediaType `json:"content,omitempty"`
+}
+
+// MediaType wraps the schema for a content type
+type MediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+// Route is the information handlers provide when registering a route. Types
+// are given as zero values, e.g. Request: handlers.LoginRequest{}.
+type Route struct {
+	Summary    string
+	Tags       []string
+	Request    interface{}
+	Query      interface{}
+	Params     interface{}
+	Responses  map[int]interface{}
+	Secured    bool
+	Deprecated bool
+	// Negotiated marks handlers that use the negotiate package, so their
+	// bodies are also offered as MessagePack and, where the type supports
+	// it, Protobuf. Requests accepting none of the formats of the success
+	// responses are refused before the handler runs.
+	Negotiated bool
+}
+
+const bearerScheme = "bearerAuth"
+
+var ginParam = regexp.MustCompile(`[:*]([^/]+)`)
+
+// Spec builds an OpenAPI document from route registrations
+type Spec struct {
+	mu  sync.RWMutex
+	doc *Document
+
+	// validated holds the path prefixes whose routes check their requests
+	validated []string
+}
+
+// New creates an empty spec
+func New(title, version string) *Spec {
+	return &Spec{
+		doc: &Document{
+			OpenAPI: "3.1.0",
+			Info:    Info{Title: title, Version: version},
+			Paths:   make(map[string]map[string]*Operation),
+			Components: Components{
+				Schemas: make(map[string]*Schema),
+				SecuritySchemes: map[string]*SecurityScheme{
+					bearerScheme: {Type: "http", Scheme: "bearer", BearerFormat: "JWT"},
+				},
+			},
+		},
+	}
+}
+
+// ValidateRequests makes the routes registered from now on under pathPrefix
+// reject requests that do not conform to the spec. The check runs just
+// before the route's handler, after the middleware of its group and route,
+// so that e.g. an unauthenticated request is refused as such whatever its
+// body.
+func (s *Spec) ValidateRequests(pathPrefix string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.validated = append(s.validated, pathPrefix)
+}
+
+// Handle registers a route on the group and documents it
+func (s *Spec) Handle(group *gin.RouterGroup, method, relativePath string, route Route, handlers ...gin.HandlerFunc) {
+	fullPath := joinPaths(group.BasePath(), relativePath)
+
+	// Checks of the request run just before the handler, after the
+	// middleware of the group and route
+	var checks []gin.HandlerFunc
+	if bodies := successBodies(route.Responses); route.Negotiated && len(bodies) > 0 {
+		checks = append(checks, negotiate.Acceptable(bodies...))
+	}
+	if s.validates(fullPath) {
+		checks = append(checks, s.ValidationMiddleware())
+	}
+	if len(checks) > 0 && len(handlers) > 0 {
+		last := len(handlers) - 1
+		handlers = append(append(handlers[:last:last], checks...), handlers[last])
+	}
+	group.Handle(method, relativePath, handlers...)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	path := ginParam.ReplaceAllString(fullPath, "{$1}")
+	if s.doc.Paths[path] == nil {
+		s.doc.Paths[path] = make(map[string]*Operation)
+	}
+	s.doc.Paths[path][strings.ToLower(method)] = s.operation(method, fullPath, route)
+}
+
+// successBodies returns the body types of the 2xx responses
+func successBodies(responses map[int]interface{}) []interface{} {
+	var bodies []interface{}
+	for status, body := range responses {
+		if status >= 200 && status < 300 && body != nil {
+			bodies = append(bodies, body)
+		}
+	}
+	return bodies
+}
+
+// validates reports whether routes at fullPath check their requests
+func (s *Spec) validates(fullPath string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, prefix := range s.validated {
+		if fullPath == prefix || strings.HasPrefix(fullPath, strings.TrimSuffix(prefix, "/")+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Spec) operation(method, fullPath string, route Route) *Operation {
+	op := &Operation{
+		OperationID: operationID(method, fullPath),
+		Summary:     route.Summary,
+		Tags:        route.Tags,
+		Responses:   make(map[string]*Response),
+		Deprecated:  route.Deprecated,
+	}
+
+	if route.Request != nil {
+		op.RequestBody = &RequestBody{
+			Required: true,
+			Content:  s.content(route.Request, route.Negotiated),
+		}
+	}
+
+	op.Parameters = append(op.Parameters, s.parameters("query", route.Query)...)
+	op.Parameters = append(op.Parameters, s.parameters("path", route.Params)...)
+
+	// Path params that have no struct describing them are plain strings
+	for _, match := range ginParam.FindAllStringSubmatch(fullPath, -1) {
+		if !hasParameter(op.Parameters, match[1]) {
+			op.Parameters = append(op.Parameters, &Parameter{
+				Name: match[1], In: "path", Required: true, Schema: &Schema{Type: "string"},
+			})
+		}
+	}
+
+	for status, body := range route.Responses {
+		resp := &Response{Description: http.StatusText(status)}
+		if body != nil {
+			resp.Content = s.content(body, route.Negotiated)
+		}
+		op.Responses[strconv.Itoa(status)] = resp
+	}
+
+	if route.Secured {
+		op.Security = []map[string][]string{{bearerScheme: {}}}
+	}
+
+	return op
+}
+
+// content describes a body in each media type it is exchanged in
+func (s *Spec) content(body interface{}, negotiated bool) map[string]*MediaType {
+	schema := s.schemaFor(reflect.TypeOf(body))
+	content := map[string]*MediaType{negotiate.MIMEJSON: {Schema: schema}}
+	if !negotiated {
+		return content
+	}
+
+	content[negotiate.MIMEMsgPack] = &MediaType{Schema: schema}
+	_, encodes := body.(negotiate.Protoer)
+	_, decodes := reflect.New(reflect.TypeOf(body)).Interface().(negotiate.ProtoDecoder)
+	if encodes || decodes {
+		content[negotiate.MIMEProtobuf] = &MediaType{Schema: &Schema{Type: "string", Format: "binary"}}
+	}
+	return content
+}
+
+// parameters describes each field of a query or path params struct
+func (s *Spec) parameters(in string, params interface{}) []*Parameter {
+	if params == nil {
+		return nil
+	}
+
+	schema := s.structSchema(reflect.TypeOf(params))
+	required := make(map[string]bool, len(schema.Required))
+	for _, name := range schema.Required {
+		required[name] = true
+	}
+
+	names := make([]string, 0, len(schema.Properties))
+	for name := range schema.Properties {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	parameters := make([]*Parameter, 0, len(names))
+	for _, name := range names {
+		parameters = append(parameters, &Parameter{
+			Name:     name,
+			In:       in,
+			Required: in == "path" || required[name],
+			Schema:   schema.Properties[name],
+		})
+	}
+	return parameters
+}
+
+// Document returns the spec as built so far
+func (s *Spec) Document() *Document {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.doc
+}
+
+// Handler serves the spec as JSON
+func (s *Spec) Handler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		s.mu.RLock()
+		defer s.mu.RUnlock()
+		c.JSON(http.StatusOK, s.doc)
+	}
+}
+
+// lookup finds the operation for a gin route pattern such as /api/v1/users/:id
+func (s *Spec) lookup(method, fullPath string) *Operation {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	ops := s.doc.Paths[ginParam.ReplaceAllString(fullPath, "{$1}")]
+	if ops == nil {
+		return nil
+	}
+	return ops[strings.ToLower(method)]
+}
+
+// resolve follows a $ref to the component schema
+func (s *Spec) resolve(schema *Schema) *Schema {
+	if schema == nil || schema.Ref == "" {
+		return schema
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.doc.Components.Schemas[strings.TrimPrefix(schema.Ref, "#/components/schemas/")]
+}
+
+func hasParameter(params []*Parameter, name string) bool {
+	for _, p := range params {
+		if p.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// operationID derives a stable ID such as postApiV1Login
+func operationID(method, fullPath string) string {
+	var b strings.Builder
+	b.WriteString(strings.ToLower(method))
+	for _, segment := range strings.FieldsFunc(fullPath, func(r rune) bool {
+		return r == '/' || r == ':' || r == '*' || r == '-' || r == '_'
+	}) {
+		b.WriteString(strings.ToUpper(segment[:1]) + segment[1:])
+	}
+	return b.String()
+}
+
+func joinPaths(base, relative string) string {
+	if relative == "" {
+		return base
+	}
+	joined := strings.TrimSuffix(base, "/") + "/" + strings.TrimPrefix(relative, "/")
+	if strings.HasSuffix(relative, "/") && !strings.HasSuffix(joined, "/") {
+		joined += "/"
+	}
+	return joined
+}
@@ -0,0 +1,256 @@
+package openapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/mail"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"golang-boilerplate/main/i18n"
+	"golang-boilerplate/main/negotiate"
+	"golang-boilerplate/main/validation"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ValidationMiddleware rejects requests that do not conform to the spec.
+// Routes that are not documented pass through untouched. ValidateRequests
+// places it on routes as they are registered.
+func (s *Spec) ValidationMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		op := s.lookup(c.Request.Method, c.FullPath())
+		if op == nil {
+			c.Next()
+			return
+		}
+
+		locale := i18n.Locale(c)
+		var errs []validation.FieldError
+
+		for _, param := range op.Parameters {
+			var value string
+			var present bool
+			switch param.In {
+			case "query":
+				value, present = c.GetQuery(param.Name)
+			case "path":
+				value = c.Param(param.Name)
+				present = value != ""
+			}
+
+			if !present {
+				if param.Required {
+					errs = append(errs, validation.NewFieldError(locale, param.Name, "required", ""))
+				}
+				continue
+			}
+			errs = append(errs, s.validateParam(locale, param, value)...)
+		}
+
+		if op.RequestBody != nil {
+			mediaType, _, _ := mime.ParseMediaType(c.ContentType())
+			mediaType = negotiate.Canonical(mediaType)
+			media, ok := op.RequestBody.Content[mediaType]
+			if !ok {
+				c.AbortWithStatusJSON(http.StatusUnsupportedMediaType, i18n.Error(c, "request.unsupported_media_type"))
+				return
+			}
+
+			// Only JSON is checked against the schema; handlers validate
+			// the other formats after decoding
+			if mediaType == negotiate.MIMEJSON {
+				body, err := io.ReadAll(c.Request.Body)
+				if err != nil {
+					errs = append(errs, validation.NewFieldError(locale, "", "malformed", ""))
+				} else {
+					c.Request.Body = io.NopCloser(bytes.NewReader(body))
+					errs = append(errs, s.validateBody(locale, media.Schema, body)...)
+				}
+			}
+		}
+
+		if len(errs) > 0 {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+				"error":   i18n.Message(c, "validation.failed"),
+				"code":    "validation.failed",
+				"details": errs,
+			})
+			return
+		}
+
+		c.Next()
+	}
+}
+
+func (s *Spec) validateParam(locale string, param *Parameter, raw string) []validation.FieldError {
+	var value interface{} = raw
+	switch param.Schema.Type {
+	case "integer", "number":
+		value = json.Number(raw)
+	case "boolean":
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return []validation.FieldError{validation.NewFieldError(locale, param.Name, "type", "boolean")}
+		}
+		value = b
+	}
+	return s.validateValue(locale, param.Name, param.Schema, value)
+}
+
+func (s *Spec) validateBody(locale string, schema *Schema, body []byte) []validation.FieldError {
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return []validation.FieldError{validation.NewFieldError(locale, "", "malformed", "")}
+	}
+	return s.validateValue(locale, "", schema, value)
+}
+
+// validateValue checks a decoded JSON value against a schema, reporting
+// failures with the same rule names the binding validators use
+func (s *Spec) validateValue(locale, field string, schema *Schema, value interface{}) []validation.FieldError {
+	schema = s.resolve(schema)
+	if schema == nil || value == nil {
+		return nil
+	}
+
+	fail := func(rule, param string) []validation.FieldError {
+		return []validation.FieldError{validation.NewFieldError(locale, field, rule, param)}
+	}
+
+	switch schema.Type {
+	case "object":
+		obj, ok := value.(map[string]interface{})
+		if !ok {
+			return fail("type", "object")
+		}
+		return s.validateObject(locale, field, schema, obj)
+
+	case "array":
+		items, ok := value.([]interface{})
+		if !ok {
+			return fail("type", "array")
+		}
+		var errs []validation.FieldError
+		for i, item := range items {
+			errs = append(errs, s.validateValue(locale, fmt.Sprintf("%s[%d]", field, i), schema.Items, item)...)
+		}
+		return errs
+
+	case "string":
+		str, ok := value.(string)
+		if !ok {
+			return fail("type", "string")
+		}
+		return validateString(schema, str, fail)
+
+	case "integer", "number":
+		num, ok := value.(json.Number)
+		if !ok {
+			return fail("type", schema.Type)
+		}
+		n, err := num.Float64()
+		if err != nil || (schema.Type == "integer" && n != float64(int64(n))) {
+			return fail("type", schema.Type)
+		}
+		if schema.Minimum != nil && n < *schema.Minimum {
+			return fail("min", strconv.FormatFloat(*schema.Minimum, 'f', -1, 64))
+		}
+		if schema.Maximum != nil && n > *schema.Maximum {
+			return fail("max", strconv.FormatFloat(*schema.Maximum, 'f', -1, 64))
+		}
+
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			return fail("type", "boolean")
+		}
+	}
+
+	return nil
+}
+
+func (s *Spec) validateObject(locale, field string, schema *Schema, obj map[string]interface{}) []validation.FieldError {
+	var errs []validation.FieldError
+	for _, name := range schema.Required {
+		if v, ok := obj[name]; !ok || v == nil {
+			errs = append(errs, validation.NewFieldError(locale, joinField(field, name), "required", ""))
+		}
+	}
+
+	names := make([]string, 0, len(obj))
+	for name := range obj {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		prop, ok := schema.Properties[name]
+		if !ok {
+			prop = schema.AdditionalProperties
+		}
+		errs = append(errs, s.validateValue(locale, joinField(field, name), prop, obj[name])...)
+	}
+	return errs
+}
+
+func validateString(schema *Schema, str string, fail func(rule, param string) []validation.FieldError) []validation.FieldError {
+	length := utf8.RuneCountInString(str)
+	if schema.MinLength != nil && length < *schema.MinLength {
+		return fail("min", strconv.Itoa(*schema.MinLength))
+	}
+	if schema.MaxLength != nil && length > *schema.MaxLength {
+		return fail("max", strconv.Itoa(*schema.MaxLength))
+	}
+	if schema.Pattern != "" {
+		if re := compilePattern(schema.Pattern); re != nil && !re.MatchString(str) {
+			return fail("pattern", schema.Pattern)
+		}
+	}
+	if schema.Format == "email" {
+		if addr, err := mail.ParseAddress(str); err != nil || addr.Address != str {
+			return fail("email_address", "")
+		}
+	}
+	if len(schema.Enum) > 0 {
+		for _, allowed := range schema.Enum {
+			if str == allowed {
+				return nil
+			}
+		}
+		return fail("oneof", strings.Join(schema.Enum, " "))
+	}
+	return nil
+}
+
+var patterns sync.Map
+
+// compilePattern caches compiled schema patterns, returning nil for invalid ones
+func compilePattern(pattern string) *regexp.Regexp {
+	if re, ok := patterns.Load(pattern); ok {
+		return re.(*regexp.Regexp)
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil
+	}
+	patterns.Store(pattern, re)
+	return re
+}
+
+func joinField(parent, name string) string {
+	if parent == "" {
+		return name
+	}
+	return parent + "." + name
+}
@@ -0,0 +1,116 @@
+package outbox
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"golang-boilerplate/main/config"
+	"golang-boilerplate/main/models"
+	"golang-boilerplate/main/repo"
+
+	"go.uber.org/zap"
+)
+
+// pruneInterval is how often published messages past retention are deleted
+const pruneInterval = time.Hour
+
+// Relay publishes outbox messages to a sink. It polls for unpublished
+// messages and drains them batch by batch; several relays, e.g. one per
+// replica, can run against the same table.
+type Relay struct {
+	outbox *repo.OutboxRepo
+	cfg    config.OutboxConfig
+	logger *zap.Logger
+
+	mu     sync.Mutex
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+func NewRelay(outbox *repo.OutboxRepo, cfg config.OutboxConfig, logger *zap.Logger) *Relay {
+	return &Relay{outbox: outbox, cfg: cfg, logger: logger}
+}
+
+// Start begins relaying to sink, with the interval and batch size from config
+func (r *Relay) Start(sink Sink) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.cancel != nil {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	r.cancel = cancel
+	r.done = make(chan struct{})
+	go r.run(ctx, sink, r.cfg)
+}
+
+// Stop waits for the batch in progress and stops relaying. Messages left
+// unpublished are picked up on the next start.
+func (r *Relay) Stop() {
+	r.mu.Lock()
+	if r.cancel == nil {
+		r.mu.Unlock()
+		return
+	}
+	r.cancel()
+	r.cancel = nil
+	done := r.done
+	r.mu.Unlock()
+
+	<-done
+}
+
+func (r *Relay) run(ctx context.Context, sink Sink, cfg config.OutboxConfig) {
+	defer close(r.done)
+
+	ticker := time.NewTicker(cfg.PollInterval)
+	defer ticker.Stop()
+
+	var lastPrune time.Time
+	for {
+		r.drain(ctx, sink, cfg.BatchSize)
+
+		if cfg.Retention > 0 && time.Since(lastPrune) >= pruneInterval {
+			lastPrune = time.Now()
+			if n, err := r.outbox.DeletePublishedBefore(ctx, lastPrune.Add(-cfg.Retention)); err != nil {
+				r.logger.Error("could not prune outbox", zap.Error(err))
+			} else if n > 0 {
+				r.logger.Debug("pruned outbox", zap.Int64("deleted", n))
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// drain publishes batches until one publishes nothing. Only the oldest
+// pending message of an aggregate is claimed at a time, so a busy aggregate
+// takes one batch per message.
+func (r *Relay) drain(ctx context.Context, sink Sink, batchSize int) {
+	publish := func(ctx context.Context, msg *models.OutboxMessage) error {
+		err := sink.Publish(ctx, msg)
+		if err != nil && ctx.Err() == nil {
+			r.logger.Warn("could not publish outbox message", zap.Int64("id", msg.ID),
+				zap.String("event_type", msg.EventType), zap.Int("attempts", msg.Attempts+1), zap.Error(err))
+		}
+		return err
+	}
+
+	for ctx.Err() == nil {
+		n, err := r.outbox.Publish(ctx, batchSize, publish)
+		if err != nil {
+			r.logger.Error("could not relay outbox messages", zap.Error(err))
+			return
+		}
+		if n == 0 {
+			return
+		}
+		r.logger.Debug("relayed outbox messages", zap.Int("published", n))
+	}
+}
@@ -0,0 +1,136 @@
+package outbox
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"golang-boilerplate/main/config"
+	"golang-boilerplate/main/models"
+
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+// Sink receives messages from the relay. A message is only marked published
+// once Publish returns nil; an error makes the relay try it again later.
+type Sink interface {
+	Publish(ctx context.Context, msg *models.OutboxMessage) error
+}
+
+// SinkFunc adapts a function to Sink
+type SinkFunc func(ctx context.Context, msg *models.OutboxMessage) error
+
+func (f SinkFunc) Publish(ctx context.Context, msg *models.OutboxMessage) error {
+	return f(ctx, msg)
+}
+
+// Fanout publishes to each sink in turn and stops at the first error. A
+// retried message is published again to the sinks that already took it, so
+// they should tolerate duplicates.
+func Fanout(sinks ...Sink) Sink {
+	return SinkFunc(func(ctx context.Context, msg *models.OutboxMessage) error {
+		for _, sink := range sinks {
+			if err := sink.Publish(ctx, msg); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// NewSink creates the sink selected by cfg.Sink
+func NewSink(cfg config.OutboxConfig, rdb *redis.Client, logger *zap.Logger) (Sink, error) {
+	switch cfg.Sink {
+	case "redis":
+		if rdb == nil {
+			return nil, fmt.Errorf("outbox sink %q needs a redis client", cfg.Sink)
+		}
+		return NewRedisStreamSink(rdb, cfg.Stream, cfg.StreamMaxLen), nil
+	case "log", "":
+		return NewLogSink(logger), nil
+	case "memory":
+		return NewMemorySink(), nil
+	default:
+		return nil, fmt.Errorf("unknown outbox sink %q", cfg.Sink)
+	}
+}
+
+// RedisStreamSink appends messages to a Redis stream. Consumers read them
+// with XREAD or consumer groups; the message ID is included so they can
+// deduplicate.
+type RedisStreamSink struct {
+	rdb    *redis.Client
+	stream string
+	maxLen int64
+}
+
+// NewRedisStreamSink appends to stream, trimming it to about maxLen entries
+// when maxLen is positive
+func NewRedisStreamSink(rdb *redis.Client, stream string, maxLen int64) *RedisStreamSink {
+	return &RedisStreamSink{rdb: rdb, stream: stream, maxLen: maxLen}
+}
+
+func (s *RedisStreamSink) Publish(ctx context.Context, msg *models.OutboxMessage) error {
+	args := &redis.XAddArgs{
+		Stream: s.stream,
+		Values: map[string]interface{}{
+			"id":             strconv.FormatInt(msg.ID, 10),
+			"aggregate_type": msg.AggregateType,
+			"aggregate_id":   msg.AggregateID,
+			"event_type":     msg.EventType,
+			"payload":        string(msg.Payload),
+			"created_at":     msg.CreatedAt.UTC().Format(time.RFC3339Nano),
+		},
+	}
+	if s.maxLen > 0 {
+		args.MaxLen = s.maxLen
+		args.Approx = true
+	}
+	return s.rdb.XAdd(ctx, args).Err()
+}
+
+// LogSink writes messages to a logger, for development
+type LogSink struct {
+	logger *zap.Logger
+}
+
+func NewLogSink(logger *zap.Logger) *LogSink {
+	return &LogSink{logger: logger}
+}
+
+func (s *LogSink) Publish(ctx context.Context, msg *models.OutboxMessage) error {
+	s.logger.Info("outbox event",
+		zap.Int64("id", msg.ID),
+		zap.String("aggregate_type", msg.AggregateType),
+		zap.String("aggregate_id", msg.AggregateID),
+		zap.String("event_type", msg.EventType),
+		zap.ByteString("payload", msg.Payload))
+	return nil
+}
+
+// MemorySink keeps messages in memory, for tests
+type MemorySink struct {
+	mu   sync.Mutex
+	msgs []*models.OutboxMessage
+}
+
+func NewMemorySink() *MemorySink {
+	return &MemorySink{}
+}
+
+func (s *MemorySink) Publish(ctx context.Context, msg *models.OutboxMessage) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.msgs = append(s.msgs, msg)
+	return nil
+}
+
+// Messages returns the messages published so far, in order
+func (s *MemorySink) Messages() []*models.OutboxMessage {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]*models.OutboxMessage(nil), s.msgs...)
+}
@@ -0,0 +1,141 @@
+package realtime
+
+import (
+	"fmt"
+	"golang-boilerplate/main/core"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	"go.uber.org/zap"
+)
+
+const (
+	// heartbeatInterval is how often idle connections are pinged
+	heartbeatInterval = 25 * time.Second
+	// pongWait is how long a WebSocket peer has to answer a ping
+	pongWait = 2 * heartbeatInterval
+	// writeWait bounds a single write to a WebSocket peer
+	writeWait = 10 * time.Second
+)
+
+// WebSocketHandler streams the authenticated user's events over a WebSocket.
+// Browsers do not apply CORS to WebSockets, so checkOrigin decides which
+// pages may open one.
+func (h *Hub) WebSocketHandler(checkOrigin func(r *http.Request) bool) gin.HandlerFunc {
+	upgrader := websocket.Upgrader{
+		ReadBufferSize:  1024,
+		WriteBufferSize: 1024,
+		CheckOrigin:     checkOrigin,
+	}
+	return func(c *gin.Context) {
+		userID, ok := core.UserIDFromContext(c.Request.Context())
+		if !ok {
+			c.AbortWithStatus(http.StatusUnauthorized)
+			return
+		}
+
+		conn, err := upgrader.Upgrade(c.Writer, c.Request, nil)
+		if err != nil {
+			// The upgrader has already written an error response
+			return
+		}
+		defer conn.Close()
+
+		cl := h.register(userID, "websocket")
+		defer h.unregister(cl, "websocket")
+
+		// Reading is required to process pongs and close frames; clients
+		// are not expected to send anything else
+		readDone := make(chan struct{})
+		go func() {
+			defer close(readDone)
+			conn.SetReadLimit(512)
+			conn.SetReadDeadline(time.Now().Add(pongWait))
+			conn.SetPongHandler(func(string) error {
+				return conn.SetReadDeadline(time.Now().Add(pongWait))
+			})
+			for {
+				if _, _, err := conn.ReadMessage(); err != nil {
+					return
+				}
+			}
+		}()
+
+		ticker := time.NewTicker(heartbeatInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case msg := <-cl.send:
+				conn.SetWriteDeadline(time.Now().Add(writeWait))
+				if err := conn.WriteMessage(websocket.TextMessage, msg.payload); err != nil {
+					return
+				}
+			case <-ticker.C:
+				if err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(writeWait)); err != nil {
+					return
+				}
+			case <-cl.evicted:
+				code := websocket.CloseTryAgainLater
+				if cl.reason == reasonShutdown {
+					code = websocket.CloseGoingAway
+				}
+				conn.WriteControl(websocket.CloseMessage,
+					websocket.FormatCloseMessage(code, cl.reason),
+					time.Now().Add(writeWait))
+				return
+			case <-readDone:
+				return
+			}
+		}
+	}
+}
+
+// SSEHandler streams the authenticated user's events as Server-Sent Events
+func (h *Hub) SSEHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, ok := core.UserIDFromContext(c.Request.Context())
+		if !ok {
+			c.AbortWithStatus(http.StatusUnauthorized)
+			return
+		}
+
+		c.Header("Content-Type", "text/event-stream")
+		c.Header("Cache-Control", "no-cache")
+		c.Header("Connection", "keep-alive")
+		// Stop nginx from buffering the stream
+		c.Header("X-Accel-Buffering", "no")
+		c.Status(http.StatusOK)
+		c.Writer.Flush()
+
+		cl := h.register(userID, "sse")
+		defer h.unregister(cl, "sse")
+
+		ticker := time.NewTicker(heartbeatInterval)
+		defer ticker.Stop()
+
+		for {
+			var err error
+			select {
+			case msg := <-cl.send:
+				_, err = fmt.Fprintf(c.Writer, "event: %s\ndata: %s\n\n", msg.eventType, msg.payload)
+			case <-ticker.C:
+				_, err = fmt.Fprint(c.Writer, ": ping\n\n")
+			case <-cl.evicted:
+				fmt.Fprintf(c.Writer, "event: close\ndata: {\"reason\":%q}\n\n", cl.reason)
+				c.Writer.Flush()
+				return
+			case <-c.Request.Context().Done():
+				return
+			}
+
+			if err != nil {
+				h.logger.Debug("sse write failed", zap.Error(err))
+				return
+			}
+			c.Writer.Flush()
+		}
+	}
+}
@@ -0,0 +1,192 @@
+package realtime
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"golang-boilerplate/pkg/metrics"
+
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+// channelPrefix namespaces the per-user Redis channels, e.g. realtime:user:42
+const channelPrefix = "realtime:user:"
+
+// sendBuffer is how many events a client may fall behind before it is
+// disconnected as a slow consumer
+const sendBuffer = 64
+
+// Event is pushed to every connection of a user
+type Event struct {
+	Type      string      `json:"type"`
+	Data      interface{} `json:"data,omitempty"`
+	Timestamp time.Time   `json:"timestamp"`
+}
+
+// message is an encoded event ready to be written to a connection
+type message struct {
+	eventType string
+	payload   []byte
+}
+
+// Reasons a connection is closed by the server
+const (
+	reasonSlowClient = "client too slow"
+	reasonShutdown   = "server shutting down"
+)
+
+// client is one WebSocket or SSE connection
+type client struct {
+	userID  uint
+	send    chan message
+	evicted chan struct{}
+	reason  string
+	once    sync.Once
+}
+
+// evict asks the connection's handler to close it. The reason is readable
+// once evicted is closed.
+func (c *client) evict(reason string) {
+	c.once.Do(func() {
+		c.reason = reason
+		close(c.evicted)
+	})
+}
+
+// Hub tracks the connections on this replica and fans events out to them.
+// Events travel through Redis pub/sub so that a publish on any replica
+// reaches connections on all of them.
+type Hub struct {
+	mu      sync.RWMutex
+	clients map[uint]map[*client]struct{}
+	redis   *redis.Client
+	pubsub  *redis.PubSub
+	done    chan struct{}
+	logger  *zap.Logger
+}
+
+func NewHub(logger *zap.Logger) *Hub {
+	return &Hub{
+		clients: make(map[uint]map[*client]struct{}),
+		done:    make(chan struct{}),
+		logger:  logger,
+	}
+}
+
+// Start subscribes to the per-user channels. Until it is called, or if rdb is
+// nil, events are only delivered to connections on this replica.
+func (h *Hub) Start(ctx context.Context, rdb *redis.Client) error {
+	if rdb == nil {
+		return nil
+	}
+
+	h.redis = rdb
+	h.pubsub = h.redis.PSubscribe(ctx, channelPrefix+"*")
+	if _, err := h.pubsub.Receive(ctx); err != nil {
+		return fmt.Errorf("could not subscribe to realtime channels: %w", err)
+	}
+
+	go func() {
+		defer close(h.done)
+		for msg := range h.pubsub.Channel() {
+			userID, err := strconv.ParseUint(strings.TrimPrefix(msg.Channel, channelPrefix), 10, 64)
+			if err != nil {
+				continue
+			}
+			h.deliver(uint(userID), []byte(msg.Payload))
+		}
+	}()
+
+	return nil
+}
+
+// Stop unsubscribes and disconnects every client
+func (h *Hub) Stop() {
+	if h.pubsub != nil {
+		h.pubsub.Close()
+		<-h.done
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for _, clients := range h.clients {
+		for c := range clients {
+			c.evict(reasonShutdown)
+		}
+	}
+}
+
+// Publish sends an event to every connection of the user, on any replica
+func (h *Hub) Publish(ctx context.Context, userID uint, eventType string, data interface{}) error {
+	payload, err := json.Marshal(Event{Type: eventType, Data: data, Timestamp: time.Now().UTC()})
+	if err != nil {
+		return err
+	}
+
+	if h.redis == nil {
+		h.deliver(userID, payload)
+		return nil
+	}
+
+	return h.redis.Publish(ctx, channelPrefix+strconv.FormatUint(uint64(userID), 10), payload).Err()
+}
+
+func (h *Hub) register(userID uint, transport string) *client {
+	c := &client{
+		userID:  userID,
+		send:    make(chan message, sendBuffer),
+		evicted: make(chan struct{}),
+	}
+
+	h.mu.Lock()
+	if h.clients[userID] == nil {
+		h.clients[userID] = make(map[*client]struct{})
+	}
+	h.clients[userID][c] = struct{}{}
+	h.mu.Unlock()
+
+	metrics.RealtimeConnections.WithLabelValues(transport).Inc()
+	return c
+}
+
+func (h *Hub) unregister(c *client, transport string) {
+	h.mu.Lock()
+	delete(h.clients[c.userID], c)
+	if len(h.clients[c.userID]) == 0 {
+		delete(h.clients, c.userID)
+	}
+	h.mu.Unlock()
+
+	metrics.RealtimeConnections.WithLabelValues(transport).Dec()
+}
+
+// deliver hands an event to the user's local connections. A connection whose
+// buffer is full is evicted rather than allowed to block the others.
+func (h *Hub) deliver(userID uint, payload []byte) {
+	var event struct {
+		Type string `json:"type"`
+	}
+	if err := json.Unmarshal(payload, &event); err != nil {
+		h.logger.Warn("dropping malformed realtime event", zap.Error(err))
+		return
+	}
+	msg := message{eventType: event.Type, payload: payload}
+
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	for c := range h.clients[userID] {
+		select {
+		case c.send <- msg:
+		default:
+			metrics.RealtimeEventsDropped.Inc()
+			h.logger.Warn("evicting slow realtime client", zap.Uint("user_id", userID))
+			c.evict(reasonSlowClient)
+		}
+	}
+}
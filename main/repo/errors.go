@@ -0,0 +1,34 @@
+package repo
+
+import (
+	"database/sql"
+	"errors"
+
+	"github.com/lib/pq"
+)
+
+var (
+	// ErrNotFound is returned when no row matches the lookup
+	ErrNotFound = errors.New("record not found")
+	// ErrConflict is returned when a write violates a unique constraint
+	ErrConflict = errors.New("record already exists")
+	// ErrVersionConflict is returned when a guarded write finds a newer version
+	ErrVersionConflict = errors.New("record was modified concurrently")
+)
+
+// uniqueViolation is the Postgres error code for unique constraint violations
+const uniqueViolation = "23505"
+
+// mapError translates driver errors into the repo's sentinel errors
+func mapError(err error) error {
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrNotFound
+	}
+
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == uniqueViolation {
+		return ErrConflict
+	}
+
+	return err
+}
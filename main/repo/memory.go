@@ -0,0 +1,337 @@
+package repo
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+
+	"golang-boilerplate/main/models"
+)
+
+var _ UserRepository = (*MemoryUserRepo)(nil)
+
+// MemoryUserRepo is a UserRepository kept in process, for tests and local
+// runs without a database. It enforces the same unique usernames and emails
+// and version checks as Postgres, but records no outbox events. Like a
+// query, a call made after its context ended returns a *CanceledError.
+type MemoryUserRepo struct {
+	mu     sync.RWMutex
+	users  map[uint]*models.User
+	nextID uint
+}
+
+func NewMemoryUserRepo() *MemoryUserRepo {
+	return &MemoryUserRepo{users: make(map[uint]*models.User), nextID: 1}
+}
+
+func (r *MemoryUserRepo) CreateUser(ctx context.Context, user *models.User) error {
+	if err := checkContext(ctx, "users.create"); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.taken(user.Username, user.Email, 0) {
+		return ErrConflict
+	}
+	r.insert(user)
+	return nil
+}
+
+func (r *MemoryUserRepo) GetUserByUsername(ctx context.Context, username string) (*models.User, error) {
+	if err := checkContext(ctx, "users.get_by_username"); err != nil {
+		return nil, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, user := range r.users {
+		if user.Username == username {
+			return clone(user), nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (r *MemoryUserRepo) GetUserByID(ctx context.Context, id uint) (*models.User, error) {
+	if err := checkContext(ctx, "users.get_by_id"); err != nil {
+		return nil, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	user, ok := r.users[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return clone(user), nil
+}
+
+// GetUsersByIDs returns the users that exist, each once and ordered by ID
+func (r *MemoryUserRepo) GetUsersByIDs(ctx context.Context, ids []uint) ([]*models.User, error) {
+	if err := checkContext(ctx, "users.get_by_ids"); err != nil {
+		return nil, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	seen := make(map[uint]bool, len(ids))
+	var users []*models.User
+	for _, id := range ids {
+		if user, ok := r.users[id]; ok && !seen[id] {
+			seen[id] = true
+			users = append(users, clone(user))
+		}
+	}
+	sort.Slice(users, func(i, j int) bool { return users[i].ID < users[j].ID })
+	return users, nil
+}
+
+func (r *MemoryUserRepo) UpdateUser(ctx context.Context, user *models.User, expectedVersion int) error {
+	if err := checkContext(ctx, "users.update"); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored, err := r.guarded(user.ID, expectedVersion)
+	if err != nil {
+		return err
+	}
+	if r.taken(user.Username, user.Email, user.ID) {
+		return ErrConflict
+	}
+
+	stored.Username = user.Username
+	stored.Email = user.Email
+	stored.Password = user.Password
+	stored.UpdatedAt = user.UpdatedAt
+	stored.Version++
+	user.Version = stored.Version
+	return nil
+}
+
+func (r *MemoryUserRepo) DeleteUser(ctx context.Context, id uint, expectedVersion int) error {
+	if err := checkContext(ctx, "users.delete"); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, err := r.guarded(id, expectedVersion); err != nil {
+		return err
+	}
+	delete(r.users, id)
+	return nil
+}
+
+func (r *MemoryUserRepo) SetAvatarKey(ctx context.Context, user *models.User) (string, error) {
+	if err := checkContext(ctx, "users.set_avatar_key"); err != nil {
+		return "", err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored, ok := r.users[user.ID]
+	if !ok {
+		return "", ErrNotFound
+	}
+
+	previous := stored.AvatarKey
+	stored.AvatarKey = user.AvatarKey
+	stored.UpdatedAt = user.UpdatedAt
+	stored.Version++
+	user.Version = stored.Version
+	return previous, nil
+}
+
+func (r *MemoryUserRepo) FindTaken(ctx context.Context, usernames, emails []string) (map[string]bool, map[string]bool, error) {
+	if err := checkContext(ctx, "users.find_taken"); err != nil {
+		return nil, nil, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	wantUsernames := make(map[string]bool, len(usernames))
+	for _, username := range usernames {
+		wantUsernames[username] = true
+	}
+	wantEmails := make(map[string]bool, len(emails))
+	for _, email := range emails {
+		wantEmails[email] = true
+	}
+
+	// Like the Postgres query, a user matching either list reports both its
+	// username and email
+	takenUsernames := make(map[string]bool)
+	takenEmails := make(map[string]bool)
+	for _, user := range r.users {
+		if wantUsernames[user.Username] || (user.Email != "" && wantEmails[user.Email]) {
+			takenUsernames[user.Username] = true
+			if user.Email != "" {
+				takenEmails[user.Email] = true
+			}
+		}
+	}
+	return takenUsernames, takenEmails, nil
+}
+
+// CopyUsers inserts the batch all or nothing, like the Postgres COPY
+func (r *MemoryUserRepo) CopyUsers(ctx context.Context, users []*models.User) error {
+	if err := checkContext(ctx, "users.copy"); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	usernames := make(map[string]bool, len(users))
+	emails := make(map[string]bool, len(users))
+	for _, user := range users {
+		if usernames[user.Username] || (user.Email != "" && emails[user.Email]) || r.taken(user.Username, user.Email, 0) {
+			return ErrConflict
+		}
+		usernames[user.Username] = true
+		if user.Email != "" {
+			emails[user.Email] = true
+		}
+	}
+
+	for _, user := range users {
+		r.insert(user)
+	}
+	return nil
+}
+
+// SearchUsers approximates the Postgres search: users whose username or
+// email words start with every term rank first, usernames above emails,
+// followed by users containing the query anywhere
+func (r *MemoryUserRepo) SearchUsers(ctx context.Context, q string, limit, offset int) ([]*models.UserSearchHit, int, error) {
+	if err := checkContext(ctx, "users.search"); err != nil {
+		return nil, 0, err
+	}
+
+	terms := searchTerms(q)
+	hits := []*models.UserSearchHit{}
+	if len(terms) == 0 {
+		return hits, 0, nil
+	}
+
+	fuzzy := strings.ToLower(strings.TrimSpace(q))
+	highlighter := newHighlighter(terms)
+
+	r.mu.RLock()
+	for _, user := range r.users {
+		var rank float64
+		switch {
+		case prefixMatch(terms, searchTerms(user.Username)):
+			rank = 1.5
+		case prefixMatch(terms, append(searchTerms(user.Username), searchTerms(user.Email)...)):
+			rank = 1
+		case strings.Contains(strings.ToLower(user.Username), fuzzy) || strings.Contains(strings.ToLower(user.Email), fuzzy):
+			rank = 0.5
+		default:
+			continue
+		}
+		hits = append(hits, &models.UserSearchHit{
+			User:       clone(user),
+			Rank:       rank,
+			Highlights: highlighter.fields(map[string]string{"username": user.Username, "email": user.Email}),
+		})
+	}
+	r.mu.RUnlock()
+
+	sort.Slice(hits, func(i, j int) bool {
+		if hits[i].Rank != hits[j].Rank {
+			return hits[i].Rank > hits[j].Rank
+		}
+		return hits[i].User.ID < hits[j].User.ID
+	})
+
+	total := len(hits)
+	if offset >= total {
+		return []*models.UserSearchHit{}, total, nil
+	}
+	hits = hits[offset:]
+	if len(hits) > limit {
+		hits = hits[:limit]
+	}
+	return hits, total, nil
+}
+
+// prefixMatch reports whether every term starts one of the words
+func prefixMatch(terms, words []string) bool {
+	for _, term := range terms {
+		found := false
+		for _, word := range words {
+			if strings.HasPrefix(word, term) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// insert stores a copy of the user, filling in its ID and version. The
+// caller holds the write lock.
+func (r *MemoryUserRepo) insert(user *models.User) {
+	user.ID = r.nextID
+	user.Version = 1
+	r.nextID++
+	r.users[user.ID] = clone(user)
+}
+
+// taken reports whether a user other than exceptID has the username or the
+// non-empty email. The caller holds the lock.
+func (r *MemoryUserRepo) taken(username, email string, exceptID uint) bool {
+	for id, user := range r.users {
+		if id == exceptID {
+			continue
+		}
+		if user.Username == username || (email != "" && user.Email == email) {
+			return true
+		}
+	}
+	return false
+}
+
+// guarded returns the stored user if its version matches. The caller holds
+// the write lock.
+func (r *MemoryUserRepo) guarded(id uint, expectedVersion int) (*models.User, error) {
+	stored, ok := r.users[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	if stored.Version != expectedVersion {
+		return nil, ErrVersionConflict
+	}
+	return stored, nil
+}
+
+// checkContext fails fast once ctx has ended, as a query would
+func checkContext(ctx context.Context, op string) error {
+	if err := ctx.Err(); err != nil {
+		return &CanceledError{Op: op, Err: err}
+	}
+	return nil
+}
+
+// clone copies a user so callers cannot modify the stored one
+func clone(user *models.User) *models.User {
+	copied := *user
+	copied.AvatarURL = ""
+	return &copied
+}
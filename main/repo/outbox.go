@@ -0,0 +1,171 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"golang-boilerplate/main/models"
+	"strconv"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// claimQuery selects the oldest unpublished message of each aggregate and
+// locks it. A later message only becomes eligible once the one before it is
+// published, so each aggregate's messages go out in order even with several
+// relays; rows another relay holds are skipped rather than waited for.
+const claimQuery = `SELECT id, aggregate_type, aggregate_id, event_type, payload, attempts, created_at
+	FROM outbox o
+	WHERE published_at IS NULL AND NOT EXISTS (
+		SELECT 1 FROM outbox p
+		WHERE p.published_at IS NULL AND p.aggregate_type = o.aggregate_type
+			AND p.aggregate_id = o.aggregate_id AND p.id < o.id)
+	ORDER BY id
+	LIMIT $1
+	FOR UPDATE SKIP LOCKED`
+
+type OutboxRepo struct {
+	conn
+	pool *sql.DB // Publish runs its own transaction, never a unit of work's
+}
+
+// NewOutboxRepo creates an OutboxRepo whose queries time out after
+// queryTimeout. Publish is exempt, since it holds its transaction open while
+// messages are published.
+func NewOutboxRepo(db *sql.DB, queryTimeout time.Duration) *OutboxRepo {
+	return &OutboxRepo{conn: conn{db: db, timeout: queryTimeout}, pool: db}
+}
+
+// userOutboxMessage describes an event about user for the outbox
+func userOutboxMessage(eventType string, user *models.User) (*models.OutboxMessage, error) {
+	payload, err := json.Marshal(models.NewUserEventData(user))
+	if err != nil {
+		return nil, fmt.Errorf("could not encode %s event: %w", eventType, err)
+	}
+	return &models.OutboxMessage{
+		AggregateType: models.AggregateUser,
+		AggregateID:   strconv.FormatUint(uint64(user.ID), 10),
+		EventType:     eventType,
+		Payload:       payload,
+		CreatedAt:     time.Now(),
+	}, nil
+}
+
+// insertOutbox records a message in the transaction of the change it describes
+func insertOutbox(ctx context.Context, tx querier, msg *models.OutboxMessage) error {
+	query := `INSERT INTO outbox (aggregate_type, aggregate_id, event_type, payload, created_at)
+		VALUES ($1, $2, $3, $4, $5) RETURNING id`
+	err := tx.QueryRowContext(ctx, query, msg.AggregateType, msg.AggregateID, msg.EventType, []byte(msg.Payload), msg.CreatedAt).
+		Scan(&msg.ID)
+	return mapError(err)
+}
+
+// Publish claims up to limit messages and passes them to publish in order,
+// marking those it accepts as published. A message publish rejects has its
+// error recorded and is retried by a later call; the messages queued behind
+// it for the same aggregate wait until then. It returns how many messages
+// were published.
+//
+// The claimed rows stay locked while publish runs. A crash between publish
+// and the commit sends the message again, so delivery is at least once.
+func (r *OutboxRepo) Publish(ctx context.Context, limit int, publish func(context.Context, *models.OutboxMessage) error) (int, error) {
+	// Not BeginTx(ctx): a cancelled context would roll back the marks of
+	// messages that were already published
+	tx, err := r.pool.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	msgs, err := r.claim(tx, limit)
+	if err != nil {
+		return 0, err
+	}
+
+	published := 0
+	for _, msg := range msgs {
+		if ctx.Err() != nil {
+			break
+		}
+
+		if err := publish(ctx, msg); err != nil {
+			if ctx.Err() != nil {
+				break
+			}
+			if _, err := tx.Exec(`UPDATE outbox SET attempts = attempts + 1, last_error = $1 WHERE id = $2`, err.Error(), msg.ID); err != nil {
+				return 0, mapError(err)
+			}
+			continue
+		}
+
+		if _, err := tx.Exec(`UPDATE outbox SET published_at = NOW(), last_error = NULL WHERE id = $1`, msg.ID); err != nil {
+			return 0, mapError(err)
+		}
+		published++
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	return published, nil
+}
+
+func (r *OutboxRepo) claim(tx *sql.Tx, limit int) ([]*models.OutboxMessage, error) {
+	rows, err := tx.Query(claimQuery, limit)
+	if err != nil {
+		return nil, mapError(err)
+	}
+	defer rows.Close()
+
+	var msgs []*models.OutboxMessage
+	for rows.Next() {
+		var msg models.OutboxMessage
+		var payload []byte
+		if err := rows.Scan(&msg.ID, &msg.AggregateType, &msg.AggregateID, &msg.EventType, &payload, &msg.Attempts, &msg.CreatedAt); err != nil {
+			return nil, err
+		}
+		msg.Payload = payload
+		msgs = append(msgs, &msg)
+	}
+	return msgs, rows.Err()
+}
+
+// DeletePublishedBefore removes messages published before t
+func (r *OutboxRepo) DeletePublishedBefore(ctx context.Context, t time.Time) (_ int64, err error) {
+	ctx, done := r.begin(ctx, "outbox.delete_published")
+	defer done(&err)
+
+	result, err := r.db.ExecContext(ctx, `DELETE FROM outbox WHERE published_at < $1`, t)
+	if err != nil {
+		return 0, mapError(err)
+	}
+	return result.RowsAffected()
+}
+
+// insertUserOutbox records an event for each of users, which must have their
+// IDs set, with a single COPY
+func insertUserOutbox(ctx context.Context, tx querier, eventType string, users []*models.User) error {
+	stmt, err := tx.PrepareContext(ctx, pq.CopyIn("outbox", "aggregate_type", "aggregate_id", "event_type", "payload", "created_at"))
+	if err != nil {
+		return mapError(err)
+	}
+
+	for _, user := range users {
+		msg, err := userOutboxMessage(eventType, user)
+		if err == nil {
+			_, err = stmt.ExecContext(ctx, msg.AggregateType, msg.AggregateID, msg.EventType, string(msg.Payload), msg.CreatedAt)
+		}
+		if err != nil {
+			stmt.Close()
+			return mapError(err)
+		}
+	}
+
+	if _, err := stmt.ExecContext(ctx); err != nil {
+		stmt.Close()
+		return mapError(err)
+	}
+	return mapError(stmt.Close())
+}
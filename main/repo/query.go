@@ -0,0 +1,157 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/ext"
+	"github.com/opentracing/opentracing-go/log"
+)
+
+// queryCanceled is the Postgres error code for a statement cancelled by the
+// client or by statement_timeout
+const queryCanceled = "57014"
+
+// CanceledError is returned when a query is abandoned because its context
+// ended, e.g. the client disconnected or the query timeout passed
+type CanceledError struct {
+	Op  string
+	Err error // context.Canceled or context.DeadlineExceeded
+}
+
+func (e *CanceledError) Error() string {
+	return fmt.Sprintf("%s: query canceled: %v", e.Op, e.Err)
+}
+
+func (e *CanceledError) Unwrap() error {
+	return e.Err
+}
+
+// Timeout reports whether the query ran out of time, as opposed to the
+// caller giving up on it
+func (e *CanceledError) Timeout() bool {
+	return errors.Is(e.Err, context.DeadlineExceeded)
+}
+
+// querier runs statements, on the pool or in the transaction of a unit of
+// work
+type querier interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+	PrepareContext(ctx context.Context, query string) (*sql.Stmt, error)
+}
+
+// conn is a database handle whose queries run under a default timeout
+type conn struct {
+	db      querier // *sql.DB, or the *sql.Tx of a unit of work
+	timeout time.Duration
+}
+
+// transact runs fn in a transaction: the unit of work's if the conn belongs
+// to one, otherwise one of its own committed when fn succeeds
+func (c conn) transact(ctx context.Context, fn func(q querier) error) error {
+	db, ok := c.db.(*sql.DB)
+	if !ok {
+		return fn(c.db)
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+	return mapError(tx.Commit())
+}
+
+// execOne runs a statement that must affect exactly one row,
+// returning ErrNotFound when none matched
+func (c conn) execOne(ctx context.Context, query string, args ...interface{}) error {
+	result, err := c.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return mapError(err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// begin starts the operation op. The returned context carries the query
+// timeout and, when the caller is traced, a span for op. The returned
+// function must be deferred with the operation's error: it turns errors
+// caused by the context ending into a *CanceledError and finishes the span.
+func (c conn) begin(ctx context.Context, op string) (context.Context, func(*error)) {
+	var span opentracing.Span
+	if parent := opentracing.SpanFromContext(ctx); parent != nil {
+		span = parent.Tracer().StartSpan(op, opentracing.ChildOf(parent.Context()))
+		ext.Component.Set(span, "postgres")
+		ext.DBType.Set(span, "sql")
+		ctx = opentracing.ContextWithSpan(ctx, span)
+	}
+
+	cancel := context.CancelFunc(func() {})
+	if c.timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, c.timeout)
+	}
+
+	return ctx, func(errp *error) {
+		// Checked before cancel, which would end the context either way
+		*errp = canceled(ctx, op, *errp)
+		cancel()
+		finishSpan(span, *errp)
+	}
+}
+
+// canceled wraps err in a *CanceledError if it was caused by ctx ending
+func canceled(ctx context.Context, op string, err error) error {
+	if err == nil {
+		return nil
+	}
+	var canceledErr *CanceledError
+	if errors.As(err, &canceledErr) {
+		return err
+	}
+
+	var pqErr *pq.Error
+	switch {
+	case ctx.Err() != nil:
+		return &CanceledError{Op: op, Err: ctx.Err()}
+	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		return &CanceledError{Op: op, Err: err}
+	case errors.As(err, &pqErr) && pqErr.Code == queryCanceled:
+		// The server gave up first, on its own statement_timeout
+		return &CanceledError{Op: op, Err: context.DeadlineExceeded}
+	}
+	return err
+}
+
+func finishSpan(span opentracing.Span, err error) {
+	if span == nil {
+		return
+	}
+	// Expected outcomes such as a missing row are not span errors
+	if err != nil && !errors.Is(err, ErrNotFound) && !errors.Is(err, ErrConflict) && !errors.Is(err, ErrVersionConflict) {
+		ext.Error.Set(span, true)
+		span.LogFields(log.Error(err))
+	}
+	var canceledErr *CanceledError
+	if errors.As(err, &canceledErr) {
+		span.SetTag("db.canceled", true)
+		span.SetTag("db.timeout", canceledErr.Timeout())
+	}
+	span.Finish()
+}
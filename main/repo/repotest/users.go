@@ -0,0 +1,413 @@
+// Package repotest holds contract tests every repository implementation has
+// to pass, so that fakes used in tests behave like the real thing
+package repotest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"golang-boilerplate/main/models"
+	"golang-boilerplate/main/repo"
+)
+
+// UserRepository runs the UserRepository contract. newRepo is called once
+// per subtest and must return an empty repository.
+func UserRepository(t *testing.T, newRepo func(t *testing.T) repo.UserRepository) {
+	tests := []struct {
+		name string
+		run  func(t *testing.T, r repo.UserRepository)
+	}{
+		{"CreateAssignsIDAndVersion", testCreateAssignsIDAndVersion},
+		{"DuplicateUsername", testDuplicateUsername},
+		{"DuplicateEmail", testDuplicateEmail},
+		{"WithoutEmail", testWithoutEmail},
+		{"NotFound", testNotFound},
+		{"Timestamps", testTimestamps},
+		{"UpdateChecksVersion", testUpdateChecksVersion},
+		{"UpdateToTakenUsername", testUpdateToTakenUsername},
+		{"DeleteChecksVersion", testDeleteChecksVersion},
+		{"SetAvatarKey", testSetAvatarKey},
+		{"GetUsersByIDs", testGetUsersByIDs},
+		{"FindTaken", testFindTaken},
+		{"CopyUsersIsAllOrNothing", testCopyUsersIsAllOrNothing},
+		{"SearchUsers", testSearchUsers},
+		{"ConcurrentCreates", testConcurrentCreates},
+		{"CanceledContext", testCanceledContext},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.run(t, newRepo(t))
+		})
+	}
+}
+
+// timestamp is a fixed time at the microsecond precision Postgres stores
+var timestamp = time.Date(2024, 3, 1, 12, 30, 0, 123456000, time.UTC)
+
+func newUser(name string) *models.User {
+	return &models.User{
+		Username:  name,
+		Email:     name + "@example.com",
+		Password:  "hash-" + name,
+		Role:      models.RoleUser,
+		CreatedAt: timestamp,
+		UpdatedAt: timestamp,
+	}
+}
+
+func mustCreate(t *testing.T, r repo.UserRepository, name string) *models.User {
+	t.Helper()
+	user := newUser(name)
+	if err := r.CreateUser(t.Context(), user); err != nil {
+		t.Fatalf("CreateUser(%q): %v", name, err)
+	}
+	return user
+}
+
+func expectError(t *testing.T, op string, err, want error) {
+	t.Helper()
+	if !errors.Is(err, want) {
+		t.Fatalf("%s: got error %v, want %v", op, err, want)
+	}
+}
+
+func sameTime(a, b time.Time) bool {
+	return a.Sub(b).Abs() < time.Microsecond
+}
+
+func testCreateAssignsIDAndVersion(t *testing.T, r repo.UserRepository) {
+	first := mustCreate(t, r, "alice")
+	second := mustCreate(t, r, "bob")
+	if first.ID == 0 || second.ID == 0 || first.ID == second.ID {
+		t.Fatalf("got IDs %d and %d, want distinct non-zero IDs", first.ID, second.ID)
+	}
+	if first.Version != 1 {
+		t.Fatalf("got version %d, want 1", first.Version)
+	}
+
+	got, err := r.GetUserByID(t.Context(), first.ID)
+	if err != nil {
+		t.Fatalf("GetUserByID: %v", err)
+	}
+	if got.Username != "alice" || got.Email != "alice@example.com" || got.Password != "hash-alice" || got.Role != models.RoleUser {
+		t.Fatalf("got %+v, want the created user", got)
+	}
+}
+
+func testDuplicateUsername(t *testing.T, r repo.UserRepository) {
+	mustCreate(t, r, "alice")
+
+	dup := newUser("alice")
+	dup.Email = "other@example.com"
+	expectError(t, "CreateUser", r.CreateUser(t.Context(), dup), repo.ErrConflict)
+}
+
+func testDuplicateEmail(t *testing.T, r repo.UserRepository) {
+	mustCreate(t, r, "alice")
+
+	dup := newUser("alicia")
+	dup.Email = "alice@example.com"
+	expectError(t, "CreateUser", r.CreateUser(t.Context(), dup), repo.ErrConflict)
+}
+
+// testWithoutEmail covers accounts from before emails were collected, any
+// number of which may have none
+func testWithoutEmail(t *testing.T, r repo.UserRepository) {
+	first := newUser("alice")
+	first.Email = ""
+	if err := r.CreateUser(t.Context(), first); err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	second := newUser("bob")
+	second.Email = ""
+	if err := r.CopyUsers(t.Context(), []*models.User{second}); err != nil {
+		t.Fatalf("CopyUsers: %v", err)
+	}
+
+	got, err := r.GetUserByUsername(t.Context(), "alice")
+	if err != nil {
+		t.Fatalf("GetUserByUsername: %v", err)
+	}
+	if got.Email != "" {
+		t.Fatalf("got email %q, want none", got.Email)
+	}
+
+	got.Username = "alicia"
+	if err := r.UpdateUser(t.Context(), got, got.Version); err != nil {
+		t.Fatalf("UpdateUser: %v", err)
+	}
+	if _, err := r.GetUserByUsername(t.Context(), "alicia"); err != nil {
+		t.Fatalf("GetUserByUsername after update: %v", err)
+	}
+}
+
+func testNotFound(t *testing.T, r repo.UserRepository) {
+	_, err := r.GetUserByID(t.Context(), 4242)
+	expectError(t, "GetUserByID", err, repo.ErrNotFound)
+
+	_, err = r.GetUserByUsername(t.Context(), "nobody")
+	expectError(t, "GetUserByUsername", err, repo.ErrNotFound)
+
+	missing := newUser("nobody")
+	missing.ID = 4242
+	expectError(t, "UpdateUser", r.UpdateUser(t.Context(), missing, 1), repo.ErrNotFound)
+	expectError(t, "DeleteUser", r.DeleteUser(t.Context(), 4242, 1), repo.ErrNotFound)
+
+	_, err = r.SetAvatarKey(t.Context(), missing)
+	expectError(t, "SetAvatarKey", err, repo.ErrNotFound)
+}
+
+func testTimestamps(t *testing.T, r repo.UserRepository) {
+	user := mustCreate(t, r, "alice")
+
+	got, err := r.GetUserByUsername(t.Context(), "alice")
+	if err != nil {
+		t.Fatalf("GetUserByUsername: %v", err)
+	}
+	if !sameTime(got.CreatedAt, timestamp) || !sameTime(got.UpdatedAt, timestamp) {
+		t.Fatalf("got created %v updated %v, want both %v", got.CreatedAt, got.UpdatedAt, timestamp)
+	}
+
+	later := timestamp.Add(time.Hour)
+	user.Email = "alice@example.org"
+	user.UpdatedAt = later
+	if err := r.UpdateUser(t.Context(), user, 1); err != nil {
+		t.Fatalf("UpdateUser: %v", err)
+	}
+
+	got, err = r.GetUserByID(t.Context(), user.ID)
+	if err != nil {
+		t.Fatalf("GetUserByID: %v", err)
+	}
+	if !sameTime(got.CreatedAt, timestamp) {
+		t.Fatalf("update changed created_at to %v", got.CreatedAt)
+	}
+	if !sameTime(got.UpdatedAt, later) {
+		t.Fatalf("got updated_at %v, want %v", got.UpdatedAt, later)
+	}
+}
+
+func testUpdateChecksVersion(t *testing.T, r repo.UserRepository) {
+	user := mustCreate(t, r, "alice")
+
+	user.Email = "alice@example.org"
+	if err := r.UpdateUser(t.Context(), user, 1); err != nil {
+		t.Fatalf("UpdateUser: %v", err)
+	}
+	if user.Version != 2 {
+		t.Fatalf("got version %d after update, want 2", user.Version)
+	}
+
+	stale := newUser("alice")
+	stale.ID = user.ID
+	expectError(t, "UpdateUser with stale version", r.UpdateUser(t.Context(), stale, 1), repo.ErrVersionConflict)
+
+	got, err := r.GetUserByID(t.Context(), user.ID)
+	if err != nil {
+		t.Fatalf("GetUserByID: %v", err)
+	}
+	if got.Email != "alice@example.org" || got.Version != 2 {
+		t.Fatalf("got %+v, want the first update only", got)
+	}
+}
+
+func testUpdateToTakenUsername(t *testing.T, r repo.UserRepository) {
+	mustCreate(t, r, "alice")
+	bob := mustCreate(t, r, "bob")
+
+	bob.Username = "alice"
+	expectError(t, "UpdateUser", r.UpdateUser(t.Context(), bob, bob.Version), repo.ErrConflict)
+}
+
+func testDeleteChecksVersion(t *testing.T, r repo.UserRepository) {
+	user := mustCreate(t, r, "alice")
+
+	expectError(t, "DeleteUser with stale version", r.DeleteUser(t.Context(), user.ID, 7), repo.ErrVersionConflict)
+	if err := r.DeleteUser(t.Context(), user.ID, user.Version); err != nil {
+		t.Fatalf("DeleteUser: %v", err)
+	}
+
+	_, err := r.GetUserByID(t.Context(), user.ID)
+	expectError(t, "GetUserByID after delete", err, repo.ErrNotFound)
+
+	// The username is free again
+	mustCreate(t, r, "alice")
+}
+
+func testSetAvatarKey(t *testing.T, r repo.UserRepository) {
+	user := mustCreate(t, r, "alice")
+
+	update := &models.User{ID: user.ID, AvatarKey: "avatars/1/a", UpdatedAt: timestamp.Add(time.Minute)}
+	previous, err := r.SetAvatarKey(t.Context(), update)
+	if err != nil {
+		t.Fatalf("SetAvatarKey: %v", err)
+	}
+	if previous != "" || update.Version != 2 {
+		t.Fatalf("got previous %q version %d, want \"\" and 2", previous, update.Version)
+	}
+
+	update.AvatarKey = ""
+	previous, err = r.SetAvatarKey(t.Context(), update)
+	if err != nil {
+		t.Fatalf("SetAvatarKey: %v", err)
+	}
+	if previous != "avatars/1/a" {
+		t.Fatalf("got previous %q, want avatars/1/a", previous)
+	}
+
+	got, err := r.GetUserByID(t.Context(), user.ID)
+	if err != nil {
+		t.Fatalf("GetUserByID: %v", err)
+	}
+	if got.AvatarKey != "" || got.Version != 3 {
+		t.Fatalf("got avatar %q version %d, want none and 3", got.AvatarKey, got.Version)
+	}
+}
+
+func testGetUsersByIDs(t *testing.T, r repo.UserRepository) {
+	alice := mustCreate(t, r, "alice")
+	bob := mustCreate(t, r, "bob")
+	mustCreate(t, r, "carol")
+
+	users, err := r.GetUsersByIDs(t.Context(), []uint{bob.ID, alice.ID, bob.ID, 4242})
+	if err != nil {
+		t.Fatalf("GetUsersByIDs: %v", err)
+	}
+
+	names := make(map[string]int)
+	for _, user := range users {
+		names[user.Username]++
+	}
+	if len(users) != 2 || names["alice"] != 1 || names["bob"] != 1 {
+		t.Fatalf("got %v, want alice and bob once each", names)
+	}
+}
+
+func testFindTaken(t *testing.T, r repo.UserRepository) {
+	mustCreate(t, r, "alice")
+	mustCreate(t, r, "bob")
+
+	usernames, emails, err := r.FindTaken(t.Context(), []string{"alice", "dave"}, []string{"bob@example.com", "dave@example.com"})
+	if err != nil {
+		t.Fatalf("FindTaken: %v", err)
+	}
+	if !usernames["alice"] || usernames["dave"] {
+		t.Fatalf("got taken usernames %v, want alice", usernames)
+	}
+	if !emails["bob@example.com"] || emails["dave@example.com"] {
+		t.Fatalf("got taken emails %v, want bob@example.com", emails)
+	}
+}
+
+func testCopyUsersIsAllOrNothing(t *testing.T, r repo.UserRepository) {
+	mustCreate(t, r, "alice")
+
+	batch := []*models.User{newUser("bob"), newUser("alice")}
+	expectError(t, "CopyUsers with a taken username", r.CopyUsers(t.Context(), batch), repo.ErrConflict)
+	_, err := r.GetUserByUsername(t.Context(), "bob")
+	expectError(t, "GetUserByUsername after failed copy", err, repo.ErrNotFound)
+
+	batch = []*models.User{newUser("bob"), newUser("carol")}
+	if err := r.CopyUsers(t.Context(), batch); err != nil {
+		t.Fatalf("CopyUsers: %v", err)
+	}
+	for _, user := range batch {
+		if user.ID == 0 {
+			t.Fatalf("CopyUsers did not fill in the ID of %s", user.Username)
+		}
+		got, err := r.GetUserByID(t.Context(), user.ID)
+		if err != nil || got.Username != user.Username {
+			t.Fatalf("GetUserByID(%d) = %v, %v, want %s", user.ID, got, err, user.Username)
+		}
+	}
+}
+
+func testSearchUsers(t *testing.T, r repo.UserRepository) {
+	mustCreate(t, r, "john.smith")
+	mustCreate(t, r, "johanna")
+	mustCreate(t, r, "bob")
+
+	hits, total, err := r.SearchUsers(t.Context(), "john", 10, 0)
+	if err != nil {
+		t.Fatalf("SearchUsers: %v", err)
+	}
+	if total < 1 || len(hits) < 1 || hits[0].User.Username != "john.smith" {
+		t.Fatalf("got %d hits of %d, want john.smith first", len(hits), total)
+	}
+	if hits[0].Highlights["username"] != "<mark>john</mark>.smith" {
+		t.Fatalf("got highlight %q", hits[0].Highlights["username"])
+	}
+	for _, hit := range hits {
+		if hit.User.Username == "bob" {
+			t.Fatal("search for john matched bob")
+		}
+	}
+
+	hits, total, err = r.SearchUsers(t.Context(), "john", 10, total)
+	if err != nil {
+		t.Fatalf("SearchUsers past the end: %v", err)
+	}
+	if len(hits) != 0 || total == 0 {
+		t.Fatalf("got %d hits of %d past the end, want none of a non-zero total", len(hits), total)
+	}
+}
+
+func testConcurrentCreates(t *testing.T, r repo.UserRepository) {
+	const workers = 8
+
+	var wg sync.WaitGroup
+	errs := make(chan error, workers*2)
+	for i := 0; i < workers; i++ {
+		wg.Add(2)
+		// Every username is created twice at once; exactly one must win
+		for j := 0; j < 2; j++ {
+			go func(i int) {
+				defer wg.Done()
+				errs <- r.CreateUser(t.Context(), newUser(fmt.Sprintf("user%d", i)))
+			}(i)
+		}
+	}
+	wg.Wait()
+	close(errs)
+
+	created, conflicts := 0, 0
+	for err := range errs {
+		switch {
+		case err == nil:
+			created++
+		case errors.Is(err, repo.ErrConflict):
+			conflicts++
+		default:
+			t.Fatalf("CreateUser: %v", err)
+		}
+	}
+	if created != workers || conflicts != workers {
+		t.Fatalf("got %d created and %d conflicts, want %d of each", created, conflicts, workers)
+	}
+}
+
+func testCanceledContext(t *testing.T, r repo.UserRepository) {
+	user := mustCreate(t, r, "alice")
+
+	ctx, cancel := context.WithCancel(t.Context())
+	cancel()
+
+	_, err := r.GetUserByID(ctx, user.ID)
+	var canceled *repo.CanceledError
+	if !errors.As(err, &canceled) || !errors.Is(err, context.Canceled) || canceled.Timeout() {
+		t.Fatalf("GetUserByID with a canceled context: got %v, want a *CanceledError wrapping context.Canceled", err)
+	}
+
+	ctx, cancel = context.WithTimeout(t.Context(), -time.Second)
+	defer cancel()
+
+	user.Email = "alice@example.org"
+	err = r.UpdateUser(ctx, user, user.Version)
+	if !errors.As(err, &canceled) || !canceled.Timeout() {
+		t.Fatalf("UpdateUser past its deadline: got %v, want a timed out *CanceledError", err)
+	}
+}
@@ -0,0 +1,148 @@
+package repo
+
+import (
+	"context"
+	"html"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+
+	"golang-boilerplate/main/models"
+)
+
+// maxSearchTerms bounds the size of the full-text query built from input
+const maxSearchTerms = 8
+
+// searchMatch matches users on the full-text vector, on trigram similarity
+// for misspellings and on substrings the trigram indexes can serve
+const searchMatch = `FROM users, to_tsquery('simple', $1) query
+	WHERE search_vector @@ query OR username % $2 OR email % $2 OR username ILIKE $3 OR email ILIKE $3`
+
+// SearchUsers finds users whose username or email matches the query, best
+// matches first. Full-text matches, including prefixes of each word, rank
+// above fuzzy ones, which are only there to catch typos and partial words.
+// It also returns the total number of matches for pagination.
+func (r *UserRepo) SearchUsers(ctx context.Context, q string, limit, offset int) (_ []*models.UserSearchHit, _ int, err error) {
+	ctx, done := r.begin(ctx, "users.search")
+	defer done(&err)
+
+	terms := searchTerms(q)
+	hits := []*models.UserSearchHit{}
+	if len(terms) == 0 {
+		return hits, 0, nil
+	}
+
+	fuzzy := strings.ToLower(strings.TrimSpace(q))
+	args := []interface{}{tsQuery(terms), fuzzy, "%" + escapeLike(fuzzy) + "%"}
+
+	// Full-text ranks are normalised into 0-1 and lifted above the 0-1
+	// trigram similarities, so the fallback never outranks a real match
+	query := `SELECT ` + userColumns + `, COUNT(*) OVER (),
+		CASE WHEN search_vector @@ query THEN 1 + ts_rank_cd(search_vector, query, 32)
+			ELSE GREATEST(similarity(username, $2), similarity(COALESCE(email, ''), $2)) END AS rank
+		` + searchMatch + `
+		ORDER BY rank DESC, id LIMIT $4 OFFSET $5`
+	rows, err := r.db.QueryContext(ctx, query, append(args, limit, offset)...)
+	if err != nil {
+		return nil, 0, mapError(err)
+	}
+	defer rows.Close()
+
+	highlighter := newHighlighter(terms)
+	total := 0
+	for rows.Next() {
+		var user models.User
+		hit := &models.UserSearchHit{User: &user}
+		err := rows.Scan(&user.ID, &user.Username, &user.Email, &user.Password, &user.Role, &user.Tenant, &user.AvatarKey, &user.Version, &user.CreatedAt, &user.UpdatedAt, &total, &hit.Rank)
+		if err != nil {
+			return nil, 0, mapError(err)
+		}
+		hit.Highlights = highlighter.fields(map[string]string{"username": user.Username, "email": user.Email})
+		hits = append(hits, hit)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, err
+	}
+
+	// The window count is only known when the page has rows
+	if len(hits) == 0 && offset > 0 {
+		if err := r.db.QueryRowContext(ctx, `SELECT COUNT(*) `+searchMatch, args...).Scan(&total); err != nil {
+			return nil, 0, mapError(err)
+		}
+	}
+	return hits, total, nil
+}
+
+// searchTerms splits a query into words the way the search vector splits
+// usernames and emails
+func searchTerms(q string) []string {
+	terms := strings.FieldsFunc(strings.ToLower(q), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	if len(terms) > maxSearchTerms {
+		terms = terms[:maxSearchTerms]
+	}
+	return terms
+}
+
+// tsQuery matches every term as a word prefix. Terms are letters and digits
+// only, so they need no quoting.
+func tsQuery(terms []string) string {
+	prefixes := make([]string, len(terms))
+	for i, term := range terms {
+		prefixes[i] = term + ":*"
+	}
+	return strings.Join(prefixes, " & ")
+}
+
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
+
+// highlighter marks occurrences of search terms in field values
+type highlighter struct {
+	pattern *regexp.Regexp
+}
+
+func newHighlighter(terms []string) *highlighter {
+	// Longer terms first, so "john" is not marked as "jo" when both are given
+	quoted := make([]string, len(terms))
+	for i, term := range terms {
+		quoted[i] = regexp.QuoteMeta(term)
+	}
+	sort.Slice(quoted, func(i, j int) bool { return len(quoted[i]) > len(quoted[j]) })
+	return &highlighter{pattern: regexp.MustCompile(`(?i)` + strings.Join(quoted, "|"))}
+}
+
+// fields highlights each value, leaving out the ones without a match, e.g.
+// a user found by a misspelling
+func (h *highlighter) fields(values map[string]string) map[string]string {
+	highlights := make(map[string]string, len(values))
+	for field, value := range values {
+		if marked, ok := h.mark(value); ok {
+			highlights[field] = marked
+		}
+	}
+	return highlights
+}
+
+// mark escapes the value as HTML and wraps matches in <mark>
+func (h *highlighter) mark(value string) (string, bool) {
+	matches := h.pattern.FindAllStringIndex(value, -1)
+	if len(matches) == 0 {
+		return "", false
+	}
+
+	var b strings.Builder
+	last := 0
+	for _, m := range matches {
+		b.WriteString(html.EscapeString(value[last:m[0]]))
+		b.WriteString("<mark>")
+		b.WriteString(html.EscapeString(value[m[0]:m[1]]))
+		b.WriteString("</mark>")
+		last = m[1]
+	}
+	b.WriteString(html.EscapeString(value[last:]))
+	return b.String(), true
+}
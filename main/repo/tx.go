@@ -0,0 +1,117 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"golang-boilerplate/main/config"
+	"golang-boilerplate/pkg/utils"
+
+	"github.com/lib/pq"
+	"github.com/opentracing/opentracing-go"
+	"go.uber.org/zap"
+)
+
+const (
+	// serializationFailure is the Postgres error code for a transaction that
+	// could not be serialized with concurrent ones
+	serializationFailure = "40001"
+	// deadlockDetected is the Postgres error code for a transaction chosen
+	// to break a deadlock
+	deadlockDetected = "40P01"
+)
+
+// Repos are the repositories of a unit of work, all running their
+// statements in its transaction
+type Repos struct {
+	Users    UserRepository
+	Webhooks *WebhookRepo
+}
+
+// UnitOfWork runs functions in a transaction shared by every repository, so
+// that their writes commit or roll back together
+type UnitOfWork struct {
+	db     *sql.DB
+	cfg    config.DatabaseConfig
+	logger *zap.Logger
+}
+
+func NewUnitOfWork(db *sql.DB, cfg config.DatabaseConfig, logger *zap.Logger) *UnitOfWork {
+	return &UnitOfWork{db: db, cfg: cfg, logger: logger}
+}
+
+// WithTx runs fn in a transaction at the configured isolation level,
+// committing it if fn returns nil and rolling it back otherwise. A
+// transaction failing on a serialization failure or deadlock is retried from
+// the start, so fn may run more than once and must not have effects outside
+// the transaction that a retry would repeat.
+func (u *UnitOfWork) WithTx(ctx context.Context, fn func(tx Repos) error) error {
+	return u.WithTxOptions(ctx, sql.TxOptions{}, fn)
+}
+
+// WithTxOptions is WithTx with the transaction's isolation level and access
+// mode. sql.LevelDefault keeps the configured isolation level.
+func (u *UnitOfWork) WithTxOptions(ctx context.Context, opts sql.TxOptions, fn func(tx Repos) error) (err error) {
+	if opts.Isolation == sql.LevelDefault {
+		opts.Isolation = u.cfg.TxIsolation
+	}
+
+	// The transaction as a whole is not bound by the query timeout, only
+	// its statements are
+	ctx, done := conn{db: u.db}.begin(ctx, "tx")
+	defer done(&err)
+
+	retry := utils.RetryConfig{
+		MaxAttempts:  max(u.cfg.TxMaxAttempts, 1),
+		InitialDelay: u.cfg.TxInitialBackoff,
+		MaxDelay:     u.cfg.TxMaxBackoff,
+		Multiplier:   2.0,
+		Logger:       u.logger,
+	}
+
+	// Only retryable errors are handed back to Retry; any other outcome ends
+	// the retries and is returned as it is
+	var result error
+	attempts := 0
+	err = utils.Retry(ctx, retry, func() error {
+		attempts++
+		err := u.run(ctx, &opts, fn)
+		if retryable(err) {
+			return err
+		}
+		result = err
+		return nil
+	})
+
+	if span := opentracing.SpanFromContext(ctx); span != nil {
+		span.SetTag("db.isolation", opts.Isolation.String())
+		span.SetTag("db.attempts", attempts)
+	}
+	if err != nil {
+		return err
+	}
+	return result
+}
+
+// run makes one attempt at the transaction
+func (u *UnitOfWork) run(ctx context.Context, opts *sql.TxOptions, fn func(tx Repos) error) error {
+	tx, err := u.db.BeginTx(ctx, opts)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	c := conn{db: tx, timeout: u.cfg.QueryTimeout}
+	if err := fn(Repos{Users: &UserRepo{c}, Webhooks: &WebhookRepo{c}}); err != nil {
+		return err
+	}
+	return mapError(tx.Commit())
+}
+
+// retryable reports whether err aborted the transaction only because of
+// concurrent ones, so that running it again may succeed
+func retryable(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && (pqErr.Code == serializationFailure || pqErr.Code == deadlockDetected)
+}
@@ -0,0 +1,207 @@
+package repo_test
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"golang-boilerplate/main/config"
+	"golang-boilerplate/main/models"
+	"golang-boilerplate/main/repo"
+	"golang-boilerplate/main/service"
+
+	"github.com/lib/pq"
+	"go.uber.org/zap"
+)
+
+// txDriver is a database/sql driver that only counts transactions, for
+// exercising WithTx without a database
+type txDriver struct {
+	mu                         sync.Mutex
+	begins, commits, rollbacks int
+}
+
+func (d *txDriver) Open(string) (driver.Conn, error) { return &txConn{d}, nil }
+
+func (d *txDriver) counts() (begins, commits, rollbacks int) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.begins, d.commits, d.rollbacks
+}
+
+type txConn struct{ d *txDriver }
+
+func (c *txConn) Prepare(string) (driver.Stmt, error) { return nil, errors.New("not supported") }
+func (c *txConn) Close() error                        { return nil }
+func (c *txConn) Begin() (driver.Tx, error) {
+	return c.BeginTx(context.Background(), driver.TxOptions{})
+}
+
+func (c *txConn) BeginTx(context.Context, driver.TxOptions) (driver.Tx, error) {
+	c.d.mu.Lock()
+	defer c.d.mu.Unlock()
+	c.d.begins++
+	return c, nil
+}
+
+func (c *txConn) Commit() error {
+	c.d.mu.Lock()
+	defer c.d.mu.Unlock()
+	c.d.commits++
+	return nil
+}
+
+func (c *txConn) Rollback() error {
+	c.d.mu.Lock()
+	defer c.d.mu.Unlock()
+	c.d.rollbacks++
+	return nil
+}
+
+type txConnector struct{ d *txDriver }
+
+func (c txConnector) Connect(context.Context) (driver.Conn, error) { return c.d.Open("") }
+func (c txConnector) Driver() driver.Driver                        { return c.d }
+
+func TestUnitOfWorkRetries(t *testing.T) {
+	tests := []struct {
+		name string
+		// errs are what fn returns on each attempt, nil once they run out
+		errs []error
+		// failed is whether WithTx returns the last of errs
+		failed        bool
+		wantAttempts  int
+		wantCommits   int
+		wantRollbacks int
+	}{
+		{"SerializationFailure", []error{&pq.Error{Code: "40001"}, &pq.Error{Code: "40001"}}, false, 3, 1, 2},
+		{"Deadlock", []error{&pq.Error{Code: "40P01"}}, false, 2, 1, 1},
+		{"GivesUp", []error{&pq.Error{Code: "40001"}, &pq.Error{Code: "40P01"}, &pq.Error{Code: "40001"}}, true, 3, 0, 3},
+		{"NotRetryable", []error{&pq.Error{Code: "23505"}}, true, 1, 0, 1},
+		{"Failed", []error{errors.New("failed")}, true, 1, 0, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := &txDriver{}
+			db := sql.OpenDB(txConnector{d})
+			t.Cleanup(func() { db.Close() })
+
+			uow := repo.NewUnitOfWork(db, config.DatabaseConfig{
+				TxIsolation:      sql.LevelSerializable,
+				TxMaxAttempts:    3,
+				TxInitialBackoff: time.Millisecond,
+				TxMaxBackoff:     time.Millisecond,
+			}, zap.NewNop())
+
+			attempts := 0
+			err := uow.WithTx(t.Context(), func(tx repo.Repos) error {
+				attempts++
+				if attempts <= len(tt.errs) {
+					return tt.errs[attempts-1]
+				}
+				return nil
+			})
+
+			if tt.failed {
+				if last := tt.errs[len(tt.errs)-1]; !errors.Is(err, last) {
+					t.Fatalf("got error %v, want %v", err, last)
+				}
+			} else if err != nil {
+				t.Fatalf("got error %v", err)
+			}
+			if attempts != tt.wantAttempts {
+				t.Fatalf("got %d attempts, want %d", attempts, tt.wantAttempts)
+			}
+			begins, commits, rollbacks := d.counts()
+			if begins != tt.wantAttempts || commits != tt.wantCommits || rollbacks != tt.wantRollbacks {
+				t.Fatalf("got %d begins, %d commits and %d rollbacks, want %d, %d and %d",
+					begins, commits, rollbacks, tt.wantAttempts, tt.wantCommits, tt.wantRollbacks)
+			}
+		})
+	}
+}
+
+// TestPostgresUnitOfWork runs against the migrated database at
+// TEST_DATABASE_URL, whose users and webhooks it deletes
+func TestPostgresUnitOfWork(t *testing.T) {
+	url := os.Getenv("TEST_DATABASE_URL")
+	if url == "" {
+		t.Skip("TEST_DATABASE_URL not set")
+	}
+
+	db, err := service.OpenPostgres(config.DatabaseConfig{URL: url})
+	if err != nil {
+		t.Fatalf("could not connect to database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if _, err := db.Exec(`TRUNCATE users, outbox, webhook_subscriptions RESTART IDENTITY CASCADE`); err != nil {
+		t.Fatalf("could not reset database: %v", err)
+	}
+
+	uow := repo.NewUnitOfWork(db, config.DatabaseConfig{
+		TxIsolation:   sql.LevelSerializable,
+		TxMaxAttempts: 3,
+		QueryTimeout:  5 * time.Second,
+	}, zap.NewNop())
+	users := repo.NewUserRepo(db, 5*time.Second)
+	webhooks := repo.NewWebhookRepo(db, 5*time.Second)
+
+	create := func(name string) func(tx repo.Repos) error {
+		return func(tx repo.Repos) error {
+			now := time.Now()
+			user := &models.User{Username: name, Password: "hash", Role: models.RoleUser, CreatedAt: now, UpdatedAt: now}
+			if err := tx.Users.CreateUser(t.Context(), user); err != nil {
+				return err
+			}
+			sub := &models.WebhookSubscription{
+				URL: "https://example.com/" + name, Secret: "secret", EventTypes: []string{"user.registered"},
+				CreatedAt: now, UpdatedAt: now,
+			}
+			return tx.Webhooks.CreateSubscription(t.Context(), sub)
+		}
+	}
+
+	t.Run("Committed", func(t *testing.T) {
+		if err := uow.WithTx(t.Context(), create("committed")); err != nil {
+			t.Fatalf("WithTx: %v", err)
+		}
+		if _, err := users.GetUserByUsername(t.Context(), "committed"); err != nil {
+			t.Fatalf("user was not committed: %v", err)
+		}
+		subs, err := webhooks.ListSubscriptions(t.Context())
+		if err != nil {
+			t.Fatalf("ListSubscriptions: %v", err)
+		}
+		if len(subs) != 1 {
+			t.Fatalf("got %d subscriptions, want 1", len(subs))
+		}
+	})
+
+	t.Run("RolledBack", func(t *testing.T) {
+		failed := errors.New("failed")
+		err := uow.WithTx(t.Context(), func(tx repo.Repos) error {
+			if err := create("rolled-back")(tx); err != nil {
+				return err
+			}
+			return failed
+		})
+		if !errors.Is(err, failed) {
+			t.Fatalf("got error %v, want %v", err, failed)
+		}
+		if _, err := users.GetUserByUsername(t.Context(), "rolled-back"); !errors.Is(err, repo.ErrNotFound) {
+			t.Fatalf("got error %v for the rolled back user, want %v", err, repo.ErrNotFound)
+		}
+		subs, err := webhooks.ListSubscriptions(t.Context())
+		if err != nil {
+			t.Fatalf("ListSubscriptions: %v", err)
+		}
+		if len(subs) != 1 {
+			t.Fatalf("got %d subscriptions, want only the committed one", len(subs))
+		}
+	})
+}
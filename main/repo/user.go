@@ -1,28 +1,290 @@
 package repo
 
 import (
+	"context"
+	"database/sql"
 	"golang-boilerplate/main/models"
-	"golang-boilerplate/main/service"
+	"time"
+
+	"github.com/lib/pq"
 )
 
-type UserRepo struct{}
+// userColumns is the select list matching scanUser
+const userColumns = `id, username, COALESCE(email, ''), password, role, COALESCE(tenant, ''), COALESCE(avatar_key, ''), version, created_at, updated_at`
 
-func NewUserRepo() *UserRepo {
-	return &UserRepo{}
+// UserRepository stores users. Lookups of a missing user return
+// ErrNotFound, writes that would duplicate a username or email return
+// ErrConflict and guarded writes with a stale version return
+// ErrVersionConflict. Timestamps are stored as given by the caller. Every
+// method gives up once ctx ends, returning a *CanceledError.
+type UserRepository interface {
+	CreateUser(ctx context.Context, user *models.User) error
+	GetUserByUsername(ctx context.Context, username string) (*models.User, error)
+	GetUserByID(ctx context.Context, id uint) (*models.User, error)
+	GetUsersByIDs(ctx context.Context, ids []uint) ([]*models.User, error)
+	UpdateUser(ctx context.Context, user *models.User, expectedVersion int) error
+	DeleteUser(ctx context.Context, id uint, expectedVersion int) error
+	SetAvatarKey(ctx context.Context, user *models.User) (string, error)
+	FindTaken(ctx context.Context, usernames, emails []string) (map[string]bool, map[string]bool, error)
+	CopyUsers(ctx context.Context, users []*models.User) error
+	SearchUsers(ctx context.Context, q string, limit, offset int) ([]*models.UserSearchHit, int, error)
 }
 
-func (r *UserRepo) CreateUser(user *models.User) error {
-	query := `INSERT INTO users (username, password, created_at, updated_at) VALUES ($1, $2, $3, $4)`
-	_, err := service.DB.Exec(query, user.Username, user.Password, user.CreatedAt, user.UpdatedAt)
-	return err
+var _ UserRepository = (*UserRepo)(nil)
+
+// UserRepo is the Postgres UserRepository
+type UserRepo struct {
+	conn
 }
 
-func (r *UserRepo) GetUserByUsername(username string) (*models.User, error) {
+// NewUserRepo creates a UserRepo whose queries time out after queryTimeout,
+// or only when their context ends if it is zero
+func NewUserRepo(db *sql.DB, queryTimeout time.Duration) *UserRepo {
+	return &UserRepo{conn{db: db, timeout: queryTimeout}}
+}
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanUser(row rowScanner) (*models.User, error) {
 	var user models.User
-	query := `SELECT id, username, password, created_at, updated_at FROM users WHERE username = $1`
-	err := service.DB.QueryRow(query, username).Scan(&user.ID, &user.Username, &user.Password, &user.CreatedAt, &user.UpdatedAt)
+	err := row.Scan(&user.ID, &user.Username, &user.Email, &user.Password, &user.Role, &user.Tenant, &user.AvatarKey, &user.Version, &user.CreatedAt, &user.UpdatedAt)
 	if err != nil {
-		return nil, err
+		return nil, mapError(err)
 	}
 	return &user, nil
 }
+
+// CreateUser inserts the user and records a user.registered event in the
+// outbox in the same transaction, so the event is published if and only if
+// the user exists
+func (r *UserRepo) CreateUser(ctx context.Context, user *models.User) (err error) {
+	ctx, done := r.begin(ctx, "users.create")
+	defer done(&err)
+
+	return r.transact(ctx, func(tx querier) error {
+		query := `INSERT INTO users (username, email, password, role, tenant, created_at, updated_at) VALUES ($1, NULLIF($2, ''), $3, $4, NULLIF($5, ''), $6, $7) RETURNING id, version`
+		err := tx.QueryRowContext(ctx, query, user.Username, user.Email, user.Password, user.Role, user.Tenant, user.CreatedAt, user.UpdatedAt).Scan(&user.ID, &user.Version)
+		if err != nil {
+			return mapError(err)
+		}
+
+		msg, err := userOutboxMessage(models.EventUserRegistered, user)
+		if err != nil {
+			return err
+		}
+		return insertOutbox(ctx, tx, msg)
+	})
+}
+
+func (r *UserRepo) GetUserByUsername(ctx context.Context, username string) (_ *models.User, err error) {
+	ctx, done := r.begin(ctx, "users.get_by_username")
+	defer done(&err)
+
+	query := `SELECT ` + userColumns + ` FROM users WHERE username = $1`
+	return scanUser(r.db.QueryRowContext(ctx, query, username))
+}
+
+func (r *UserRepo) GetUserByID(ctx context.Context, id uint) (_ *models.User, err error) {
+	ctx, done := r.begin(ctx, "users.get_by_id")
+	defer done(&err)
+
+	query := `SELECT ` + userColumns + ` FROM users WHERE id = $1`
+	return scanUser(r.db.QueryRowContext(ctx, query, id))
+}
+
+func (r *UserRepo) GetUsersByIDs(ctx context.Context, ids []uint) (_ []*models.User, err error) {
+	ctx, done := r.begin(ctx, "users.get_by_ids")
+	defer done(&err)
+
+	keys := make([]int64, len(ids))
+	for i, id := range ids {
+		keys[i] = int64(id)
+	}
+
+	query := `SELECT ` + userColumns + ` FROM users WHERE id = ANY($1)`
+	rows, err := r.db.QueryContext(ctx, query, pq.Array(keys))
+	if err != nil {
+		return nil, mapError(err)
+	}
+	defer rows.Close()
+
+	var users []*models.User
+	for rows.Next() {
+		user, err := scanUser(rows)
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, user)
+	}
+	return users, rows.Err()
+}
+
+// UpdateUser saves the user's username, email and password if its version
+// still matches expectedVersion, then bumps the version. The user is updated
+// in place with the new version and timestamp.
+func (r *UserRepo) UpdateUser(ctx context.Context, user *models.User, expectedVersion int) (err error) {
+	ctx, done := r.begin(ctx, "users.update")
+	defer done(&err)
+
+	query := `UPDATE users SET username = $1, email = NULLIF($2, ''), password = $3, updated_at = $4, version = version + 1
+		WHERE id = $5 AND version = $6 RETURNING version`
+	err = r.db.QueryRowContext(ctx, query, user.Username, user.Email, user.Password, user.UpdatedAt, user.ID, expectedVersion).Scan(&user.Version)
+	if err == sql.ErrNoRows {
+		return r.versionMismatch(ctx, user.ID)
+	}
+	return mapError(err)
+}
+
+// DeleteUser removes the user if its version still matches expectedVersion
+func (r *UserRepo) DeleteUser(ctx context.Context, id uint, expectedVersion int) (err error) {
+	ctx, done := r.begin(ctx, "users.delete")
+	defer done(&err)
+
+	result, err := r.db.ExecContext(ctx, `DELETE FROM users WHERE id = $1 AND version = $2`, id, expectedVersion)
+	if err != nil {
+		return mapError(err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return r.versionMismatch(ctx, id)
+	}
+	return nil
+}
+
+// SetAvatarKey replaces the user's avatar key, an empty key removing it, and
+// bumps the version. It returns the previous key so its blobs can be deleted.
+func (r *UserRepo) SetAvatarKey(ctx context.Context, user *models.User) (_ string, err error) {
+	ctx, done := r.begin(ctx, "users.set_avatar_key")
+	defer done(&err)
+
+	query := `UPDATE users u SET avatar_key = NULLIF($1, ''), updated_at = $2, version = u.version + 1
+		FROM (SELECT id, COALESCE(avatar_key, '') AS avatar_key FROM users WHERE id = $3 FOR UPDATE) old
+		WHERE u.id = old.id RETURNING old.avatar_key, u.version`
+
+	var previous string
+	err = r.db.QueryRowContext(ctx, query, user.AvatarKey, user.UpdatedAt, user.ID).Scan(&previous, &user.Version)
+	return previous, mapError(err)
+}
+
+// versionMismatch tells a stale version apart from a missing row after a
+// guarded write matched nothing
+func (r *UserRepo) versionMismatch(ctx context.Context, id uint) error {
+	var exists bool
+	if err := r.db.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM users WHERE id = $1)`, id).Scan(&exists); err != nil {
+		return err
+	}
+	if !exists {
+		return ErrNotFound
+	}
+	return ErrVersionConflict
+}
+
+// FindTaken reports which of the given usernames and emails already belong
+// to a user
+func (r *UserRepo) FindTaken(ctx context.Context, usernames, emails []string) (_, _ map[string]bool, err error) {
+	ctx, done := r.begin(ctx, "users.find_taken")
+	defer done(&err)
+
+	query := `SELECT username, COALESCE(email, '') FROM users WHERE username = ANY($1) OR email = ANY($2)`
+	rows, err := r.db.QueryContext(ctx, query, pq.Array(usernames), pq.Array(emails))
+	if err != nil {
+		return nil, nil, mapError(err)
+	}
+	defer rows.Close()
+
+	takenUsernames := make(map[string]bool)
+	takenEmails := make(map[string]bool)
+	for rows.Next() {
+		var username, email string
+		if err := rows.Scan(&username, &email); err != nil {
+			return nil, nil, err
+		}
+		takenUsernames[username] = true
+		if email != "" {
+			takenEmails[email] = true
+		}
+	}
+	return takenUsernames, takenEmails, rows.Err()
+}
+
+// CopyUsers inserts users in a single COPY, which is much faster than
+// individual inserts for large batches. The batch is all or nothing: one
+// duplicate fails it with ErrConflict. Like CreateUser it records a
+// user.registered event per user in the same transaction, and fills in IDs.
+func (r *UserRepo) CopyUsers(ctx context.Context, users []*models.User) (err error) {
+	ctx, done := r.begin(ctx, "users.copy")
+	defer done(&err)
+
+	return r.transact(ctx, func(tx querier) error {
+		stmt, err := tx.PrepareContext(ctx, pq.CopyIn("users", "username", "email", "password", "role", "created_at", "updated_at"))
+		if err != nil {
+			return mapError(err)
+		}
+
+		for _, user := range users {
+			if _, err := stmt.ExecContext(ctx, user.Username, nullable(user.Email), user.Password, user.Role, user.CreatedAt, user.UpdatedAt); err != nil {
+				stmt.Close()
+				return mapError(err)
+			}
+		}
+
+		// The final Exec flushes the buffered rows to the server
+		if _, err := stmt.ExecContext(ctx); err != nil {
+			stmt.Close()
+			return mapError(err)
+		}
+		if err := stmt.Close(); err != nil {
+			return mapError(err)
+		}
+
+		if err := r.fillIDs(ctx, tx, users); err != nil {
+			return err
+		}
+		return insertUserOutbox(ctx, tx, models.EventUserRegistered, users)
+	})
+}
+
+// nullable stores an empty string as NULL, which unlike an empty string
+// does not collide with the unique index on the column
+func nullable(s string) interface{} {
+	if s == "" {
+		return nil
+	}
+	return s
+}
+
+// fillIDs looks up the IDs COPY assigned to users by their unique usernames
+func (r *UserRepo) fillIDs(ctx context.Context, tx querier, users []*models.User) error {
+	byUsername := make(map[string]*models.User, len(users))
+	usernames := make([]string, len(users))
+	for i, user := range users {
+		byUsername[user.Username] = user
+		usernames[i] = user.Username
+	}
+
+	rows, err := tx.QueryContext(ctx, `SELECT id, username, version FROM users WHERE username = ANY($1)`, pq.Array(usernames))
+	if err != nil {
+		return mapError(err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id uint
+		var username string
+		var version int
+		if err := rows.Scan(&id, &username, &version); err != nil {
+			return err
+		}
+		if user, ok := byUsername[username]; ok {
+			user.ID = id
+			user.Version = version
+		}
+	}
+	return rows.Err()
+}
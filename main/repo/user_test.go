@@ -0,0 +1,40 @@
+package repo_test
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"golang-boilerplate/main/config"
+	"golang-boilerplate/main/repo"
+	"golang-boilerplate/main/repo/repotest"
+	"golang-boilerplate/main/service"
+)
+
+func TestMemoryUserRepo(t *testing.T) {
+	repotest.UserRepository(t, func(t *testing.T) repo.UserRepository {
+		return repo.NewMemoryUserRepo()
+	})
+}
+
+// TestPostgresUserRepo runs against the migrated database at
+// TEST_DATABASE_URL, whose users are deleted before every subtest
+func TestPostgresUserRepo(t *testing.T) {
+	url := os.Getenv("TEST_DATABASE_URL")
+	if url == "" {
+		t.Skip("TEST_DATABASE_URL not set")
+	}
+
+	db, err := service.OpenPostgres(config.DatabaseConfig{URL: url})
+	if err != nil {
+		t.Fatalf("could not connect to database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	repotest.UserRepository(t, func(t *testing.T) repo.UserRepository {
+		if _, err := db.Exec(`TRUNCATE users, outbox RESTART IDENTITY CASCADE`); err != nil {
+			t.Fatalf("could not reset users: %v", err)
+		}
+		return repo.NewUserRepo(db, 5*time.Second)
+	})
+}
@@ -0,0 +1,259 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"golang-boilerplate/main/models"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+const subscriptionColumns = `id, url, secret, event_types, enabled, consecutive_failures, disabled_at, created_at, updated_at`
+
+const deliveryColumns = `id, subscription_id, event_id, event_type, payload, status, attempts, created_at, updated_at`
+
+type WebhookRepo struct {
+	conn
+}
+
+// NewWebhookRepo creates a WebhookRepo whose queries time out after
+// queryTimeout
+func NewWebhookRepo(db *sql.DB, queryTimeout time.Duration) *WebhookRepo {
+	return &WebhookRepo{conn{db: db, timeout: queryTimeout}}
+}
+
+func scanSubscription(row rowScanner) (*models.WebhookSubscription, error) {
+	var sub models.WebhookSubscription
+	var disabledAt sql.NullTime
+	err := row.Scan(&sub.ID, &sub.URL, &sub.Secret, pq.Array(&sub.EventTypes), &sub.Enabled,
+		&sub.ConsecutiveFailures, &disabledAt, &sub.CreatedAt, &sub.UpdatedAt)
+	if err != nil {
+		return nil, mapError(err)
+	}
+	if disabledAt.Valid {
+		sub.DisabledAt = &disabledAt.Time
+	}
+	return &sub, nil
+}
+
+func scanDelivery(row rowScanner) (*models.WebhookDelivery, error) {
+	var delivery models.WebhookDelivery
+	var payload []byte
+	err := row.Scan(&delivery.ID, &delivery.SubscriptionID, &delivery.EventID, &delivery.EventType,
+		&payload, &delivery.Status, &delivery.Attempts, &delivery.CreatedAt, &delivery.UpdatedAt)
+	if err != nil {
+		return nil, mapError(err)
+	}
+	delivery.Payload = payload
+	return &delivery, nil
+}
+
+func (r *WebhookRepo) CreateSubscription(ctx context.Context, sub *models.WebhookSubscription) (err error) {
+	ctx, done := r.begin(ctx, "webhooks.create_subscription")
+	defer done(&err)
+
+	query := `INSERT INTO webhook_subscriptions (url, secret, event_types, enabled, created_at, updated_at)
+		VALUES ($1, $2, $3, TRUE, $4, $5) RETURNING id, enabled`
+	err = r.db.QueryRowContext(ctx, query, sub.URL, sub.Secret, pq.Array(sub.EventTypes), sub.CreatedAt, sub.UpdatedAt).
+		Scan(&sub.ID, &sub.Enabled)
+	return mapError(err)
+}
+
+func (r *WebhookRepo) GetSubscription(ctx context.Context, id uint) (_ *models.WebhookSubscription, err error) {
+	ctx, done := r.begin(ctx, "webhooks.get_subscription")
+	defer done(&err)
+
+	query := `SELECT ` + subscriptionColumns + ` FROM webhook_subscriptions WHERE id = $1`
+	return scanSubscription(r.db.QueryRowContext(ctx, query, id))
+}
+
+func (r *WebhookRepo) ListSubscriptions(ctx context.Context) (_ []*models.WebhookSubscription, err error) {
+	ctx, done := r.begin(ctx, "webhooks.list_subscriptions")
+	defer done(&err)
+
+	query := `SELECT ` + subscriptionColumns + ` FROM webhook_subscriptions ORDER BY id`
+	return r.querySubscriptions(ctx, query)
+}
+
+// EnabledSubscriptionsFor returns the enabled subscriptions to an event type
+func (r *WebhookRepo) EnabledSubscriptionsFor(ctx context.Context, eventType string) (_ []*models.WebhookSubscription, err error) {
+	ctx, done := r.begin(ctx, "webhooks.enabled_subscriptions")
+	defer done(&err)
+
+	query := `SELECT ` + subscriptionColumns + ` FROM webhook_subscriptions
+		WHERE enabled AND event_types @> ARRAY[$1]::TEXT[] ORDER BY id`
+	return r.querySubscriptions(ctx, query, eventType)
+}
+
+func (r *WebhookRepo) querySubscriptions(ctx context.Context, query string, args ...interface{}) ([]*models.WebhookSubscription, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, mapError(err)
+	}
+	defer rows.Close()
+
+	subs := []*models.WebhookSubscription{}
+	for rows.Next() {
+		sub, err := scanSubscription(rows)
+		if err != nil {
+			return nil, err
+		}
+		subs = append(subs, sub)
+	}
+	return subs, rows.Err()
+}
+
+// SetSubscriptionEnabled enables or disables a subscription. Enabling it
+// also clears its failure count.
+func (r *WebhookRepo) SetSubscriptionEnabled(ctx context.Context, id uint, enabled bool) (err error) {
+	ctx, done := r.begin(ctx, "webhooks.set_subscription_enabled")
+	defer done(&err)
+
+	query := `UPDATE webhook_subscriptions SET enabled = $1,
+		consecutive_failures = CASE WHEN $1 THEN 0 ELSE consecutive_failures END,
+		disabled_at = CASE WHEN $1 THEN NULL ELSE NOW() END,
+		updated_at = NOW()
+		WHERE id = $2`
+	return r.execOne(ctx, query, enabled, id)
+}
+
+func (r *WebhookRepo) DeleteSubscription(ctx context.Context, id uint) (err error) {
+	ctx, done := r.begin(ctx, "webhooks.delete_subscription")
+	defer done(&err)
+
+	return r.execOne(ctx, `DELETE FROM webhook_subscriptions WHERE id = $1`, id)
+}
+
+// RecordSubscriptionResult resets the failure count after a successful
+// delivery, or increments it after a failed one and disables the
+// subscription once it reaches disableAfter. It reports whether this call
+// disabled the subscription.
+func (r *WebhookRepo) RecordSubscriptionResult(ctx context.Context, id uint, succeeded bool, disableAfter int) (_ bool, err error) {
+	ctx, done := r.begin(ctx, "webhooks.record_subscription_result")
+	defer done(&err)
+
+	if succeeded {
+		_, err := r.db.ExecContext(ctx, `UPDATE webhook_subscriptions SET consecutive_failures = 0 WHERE id = $1 AND consecutive_failures > 0`, id)
+		return false, mapError(err)
+	}
+
+	query := `WITH old AS (SELECT id, enabled FROM webhook_subscriptions WHERE id = $1 FOR UPDATE)
+		UPDATE webhook_subscriptions s SET consecutive_failures = s.consecutive_failures + 1,
+			enabled = s.enabled AND s.consecutive_failures + 1 < $2,
+			disabled_at = CASE WHEN s.enabled AND s.consecutive_failures + 1 >= $2 THEN NOW() ELSE s.disabled_at END,
+			updated_at = NOW()
+		FROM old WHERE s.id = old.id
+		RETURNING old.enabled AND NOT s.enabled`
+
+	var disabled bool
+	err = r.db.QueryRowContext(ctx, query, id, disableAfter).Scan(&disabled)
+	return disabled, mapError(err)
+}
+
+// CreateDelivery stores a delivery. It reports false, storing nothing, when
+// the subscription already has a delivery of the same event.
+func (r *WebhookRepo) CreateDelivery(ctx context.Context, delivery *models.WebhookDelivery) (_ bool, err error) {
+	ctx, done := r.begin(ctx, "webhooks.create_delivery")
+	defer done(&err)
+
+	query := `INSERT INTO webhook_deliveries (subscription_id, event_id, event_type, payload, status, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		ON CONFLICT (subscription_id, event_id) DO NOTHING RETURNING id`
+	err = r.db.QueryRowContext(ctx, query, delivery.SubscriptionID, delivery.EventID, delivery.EventType,
+		[]byte(delivery.Payload), delivery.Status, delivery.CreatedAt, delivery.UpdatedAt).Scan(&delivery.ID)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	return err == nil, mapError(err)
+}
+
+func (r *WebhookRepo) GetDelivery(ctx context.Context, id int64) (_ *models.WebhookDelivery, err error) {
+	ctx, done := r.begin(ctx, "webhooks.get_delivery")
+	defer done(&err)
+
+	query := `SELECT ` + deliveryColumns + ` FROM webhook_deliveries WHERE id = $1`
+	return scanDelivery(r.db.QueryRowContext(ctx, query, id))
+}
+
+// ListDeliveries returns a subscription's deliveries, newest first
+func (r *WebhookRepo) ListDeliveries(ctx context.Context, subscriptionID uint, limit, offset int) (_ []*models.WebhookDelivery, err error) {
+	ctx, done := r.begin(ctx, "webhooks.list_deliveries")
+	defer done(&err)
+
+	query := `SELECT ` + deliveryColumns + ` FROM webhook_deliveries
+		WHERE subscription_id = $1 ORDER BY id DESC LIMIT $2 OFFSET $3`
+	rows, err := r.db.QueryContext(ctx, query, subscriptionID, limit, offset)
+	if err != nil {
+		return nil, mapError(err)
+	}
+	defer rows.Close()
+
+	deliveries := []*models.WebhookDelivery{}
+	for rows.Next() {
+		delivery, err := scanDelivery(rows)
+		if err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, delivery)
+	}
+	return deliveries, rows.Err()
+}
+
+// SetDeliveryStatus updates the status of a delivery, e.g. back to pending
+// before it is redelivered
+func (r *WebhookRepo) SetDeliveryStatus(ctx context.Context, id int64, status string) (err error) {
+	ctx, done := r.begin(ctx, "webhooks.set_delivery_status")
+	defer done(&err)
+
+	return r.execOne(ctx, `UPDATE webhook_deliveries SET status = $1, updated_at = NOW() WHERE id = $2`, status, id)
+}
+
+// RecordAttempt logs one delivery attempt and bumps the delivery's attempt count
+func (r *WebhookRepo) RecordAttempt(ctx context.Context, attempt *models.WebhookAttempt) (err error) {
+	ctx, done := r.begin(ctx, "webhooks.record_attempt")
+	defer done(&err)
+
+	return r.transact(ctx, func(tx querier) error {
+		query := `INSERT INTO webhook_attempts (delivery_id, status_code, error, duration_ms, created_at)
+			VALUES ($1, $2, NULLIF($3, ''), $4, $5) RETURNING id`
+		err := tx.QueryRowContext(ctx, query, attempt.DeliveryID, attempt.StatusCode, attempt.Error, attempt.DurationMS, attempt.CreatedAt).
+			Scan(&attempt.ID)
+		if err != nil {
+			return mapError(err)
+		}
+
+		_, err = tx.ExecContext(ctx, `UPDATE webhook_deliveries SET attempts = attempts + 1, updated_at = $1 WHERE id = $2`,
+			attempt.CreatedAt, attempt.DeliveryID)
+		return mapError(err)
+	})
+}
+
+// ListAttempts returns a delivery's attempts, oldest first
+func (r *WebhookRepo) ListAttempts(ctx context.Context, deliveryID int64) (_ []*models.WebhookAttempt, err error) {
+	ctx, done := r.begin(ctx, "webhooks.list_attempts")
+	defer done(&err)
+
+	query := `SELECT id, delivery_id, status_code, COALESCE(error, ''), duration_ms, created_at
+		FROM webhook_attempts WHERE delivery_id = $1 ORDER BY id`
+	rows, err := r.db.QueryContext(ctx, query, deliveryID)
+	if err != nil {
+		return nil, mapError(err)
+	}
+	defer rows.Close()
+
+	attempts := []*models.WebhookAttempt{}
+	for rows.Next() {
+		var attempt models.WebhookAttempt
+		var statusCode sql.NullInt32
+		if err := rows.Scan(&attempt.ID, &attempt.DeliveryID, &statusCode, &attempt.Error, &attempt.DurationMS, &attempt.CreatedAt); err != nil {
+			return nil, err
+		}
+		if statusCode.Valid {
+			code := int(statusCode.Int32)
+			attempt.StatusCode = &code
+		}
+		attempts = append(attempts, &attempt)
+	}
+	return attempts, rows.Err()
+}
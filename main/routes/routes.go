@@ -1,58 +1,129 @@
 package routes
 
 import (
+	"golang-boilerplate/main/app"
 	"golang-boilerplate/main/handlers"
 	"golang-boilerplate/main/middleware"
+	"golang-boilerplate/main/models"
+	"golang-boilerplate/main/module"
+	"golang-boilerplate/main/openapi"
+	"golang-boilerplate/main/versioning"
+	"net/http"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"golang.org/x/time/rate"
 )
 
-// SetupRoutes configures all the routes for the application
-func SetupRoutes(router *gin.Engine) {
+// SetupRoutes configures the system routes on the services of a, then lets
+// the enabled modules add theirs. The returned handler serves router after
+// resolving the API version of the request.
+func SetupRoutes(router *gin.Engine, a *app.App, mods *module.Registry) http.Handler {
+	cfg := a.Config
+	h := handlers.New(handlers.Deps{
+		Config:      cfg,
+		DB:          a.DB,
+		Redis:       a.Redis,
+		Blobs:       a.Blobs,
+		Users:       a.Users,
+		Auth:        a.Auth,
+		UserService: a.UserService,
+		Avatars:     a.Avatars,
+		Hub:         a.Hub,
+		Flags:       a.Flags,
+
+		HealthChecks: mods.HealthChecks(),
+	})
+
+	idempotency := middleware.IdempotencyMiddleware(a.Redis, cfg.Idempotency.TTL, a.Logger)
+	// Each router limits its own callers, 1 request a second after a burst
+	// of 5, and shares a circuit breaker per service across its modules
+	limiter := middleware.NewRateLimiter(rate.Every(time.Second), 5)
+	breakers := middleware.NewBreakers()
+
 	// Global middlewares
-	router.Use(middleware.CORSMiddleware())
+	router.Use(middleware.CORSMiddleware(cfg.CORS))
 	router.Use(middleware.SecurityHeadersMiddleware())
-	router.Use(middleware.TracingMiddleware())
-	router.Use(middleware.LoggingMiddleware())
-	router.Use(middleware.RateLimitMiddleware())
+	router.Use(middleware.LocaleMiddleware())
+	router.Use(middleware.TracingMiddleware(a.Tracer))
+	router.Use(middleware.LoggingMiddleware(a.Logger))
+	router.Use(middleware.RateLimitMiddleware(limiter))
+	router.Use(middleware.CompressionMiddleware(cfg.Compression))
 
 	// Metrics endpoint
 	router.GET("/metrics", gin.WrapH(promhttp.Handler()))
 
-	// API v1 routes
-	v1 := router.Group("/api/v1")
-	{
-		// Public routes
-		public := v1.Group("")
-		{
-			public.GET("/ping", handlers.PingHandler)
-			public.GET("/health", handlers.HealthHandler)
-			public.POST("/login", handlers.LoginHandler)
-			public.POST("/register", handlers.RegisterHandler)
-		}
+	spec := openapi.New("golang-boilerplate API", "1.0.0")
 
-		// Protected routes
-		protected := v1.Group("")
-		protected.Use(middleware.AuthMiddleware())
+	// The legacy unversioned /api routes are deprecated in favour of v1.
+	// Unversioned requests can also select a version with the Accept header.
+	api := versioning.New(router, "/api",
+		&versioning.Version{Name: "v1", Path: "/api/v1"},
+		&versioning.Version{
+			Name:       "legacy",
+			Path:       "/api",
+			Deprecated: cfg.API.LegacyDeprecatedAt,
+			Sunset:     cfg.API.LegacySunset,
+			Successor:  "v1",
+			Policy:     cfg.API.DeprecationPolicy,
+		},
+	)
+	api.CountClients(cfg.API.Clients...)
+
+	v1 := api.Group("v1")
+	if cfg.OpenAPI.ValidateRequests {
+		spec.ValidateRequests(v1.BasePath())
+	}
+
+	// Routes every version has
+	api.Each(func(group *gin.RouterGroup, v *versioning.Version) {
+		deprecated := v.IsDeprecated()
+
+		public := group.Group("")
+		public.Use(middleware.ETagMiddleware(), idempotency)
 		{
-			protected.GET("/protected", handlers.ProtectedHandler)
+			spec.Handle(public, http.MethodGet, "/ping", openapi.Route{
+				Summary:    "Liveness check",
+				Tags:       []string{"system"},
+				Negotiated: true,
+				Deprecated: deprecated,
+				Responses:  map[int]interface{}{http.StatusOK: handlers.MessageResponse{}},
+			}, h.PingHandler)
+			spec.Handle(public, http.MethodGet, "/health", openapi.Route{
+				Summary:    "Health of the service and its dependencies",
+				Tags:       []string{"system"},
+				Negotiated: true,
+				Deprecated: deprecated,
+				Responses: map[int]interface{}{
+					http.StatusOK:                 handlers.HealthResponse{},
+					http.StatusServiceUnavailable: handlers.HealthResponse{},
+				},
+			}, h.HealthHandler)
 		}
-	}
+	})
 
-	// Legacy API routes (for backward compatibility)
-	public := router.Group("/api")
+	// Routes only v1 has
 	{
-		public.GET("/ping", handlers.PingHandler)
-		public.GET("/health", handlers.HealthHandler)
-		public.POST("/login", handlers.LoginHandler)
-		public.POST("/register", handlers.RegisterHandler)
-	}
+		v1.GET("/openapi.json", spec.Handler())
+		v1.GET("/docs", openapi.SwaggerUIHandler())
+		v1.GET("/swagger-init.js", openapi.SwaggerInitHandler())
 
-	// Protected routes
-	protected := router.Group("/api")
-	protected.Use(middleware.AuthMiddleware())
-	{
-		protected.GET("/protected", handlers.ProtectedHandler)
+		// Signed downloads for the local and memory blob stores
+		v1.GET("/blobs/*key", h.BlobHandler)
 	}
+
+	mods.Routes(&module.Router{
+		API:         api,
+		Spec:        spec,
+		Handlers:    h,
+		Auth:        middleware.AuthMiddleware(a.Auth),
+		Admin:       middleware.RequireRole(a.UserService, a.Logger, models.RoleAdmin),
+		Idempotency: idempotency,
+		CircuitBreaker: func(service string) gin.HandlerFunc {
+			return middleware.CircuitBreakerMiddleware(breakers, service, a.Logger)
+		},
+	})
+
+	return api.Handler()
 }
@@ -0,0 +1,77 @@
+package routes_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"golang-boilerplate/main/app"
+	"golang-boilerplate/main/config"
+	"golang-boilerplate/main/flags"
+	"golang-boilerplate/main/module"
+	"golang-boilerplate/main/modules"
+	"golang-boilerplate/main/routes"
+	"golang-boilerplate/pkg/blob"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// probe is a module other than flags that checks a flag in its handler
+type probe struct {
+	module.Base
+}
+
+func (probe) Name() string {
+	return "probe"
+}
+
+func (probe) Routes(r *module.Router) {
+	r.API.Group("v1").GET("/probe", func(c *gin.Context) {
+		c.String(http.StatusOK, strconv.FormatBool(r.Handlers.Flags.Enabled(c, "beta")))
+	})
+}
+
+func TestFlagsInOtherModules(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name     string
+		disabled []string
+		want     string
+	}{
+		{"Enabled", nil, "true"},
+		{"Disabled", []string{"flags"}, "false"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := config.Load()
+			if err != nil {
+				t.Fatalf("could not load config: %v", err)
+			}
+			cfg.Modules.Disabled = tt.disabled
+
+			logger := zap.NewNop()
+			a := app.New(cfg, logger, nil, nil, blob.NewMemoryStore(blob.NewSigner("secret", "/api/v1/blobs")))
+			mods, err := module.NewRegistry(cfg.Modules, logger, modules.NewFlags(a), probe{})
+			if err != nil {
+				t.Fatalf("could not load modules: %v", err)
+			}
+			// The store is not loaded from Redis with the module disabled,
+			// so the flag only exists while the module runs
+			if tt.disabled == nil {
+				if err := a.Flags.Put(t.Context(), &flags.Flag{Key: "beta", Kind: flags.KindBoolean, Enabled: true}); err != nil {
+					t.Fatalf("could not put flag: %v", err)
+				}
+			}
+
+			handler := routes.SetupRoutes(gin.New(), a, mods)
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/probe", nil))
+			if w.Code != http.StatusOK || w.Body.String() != tt.want {
+				t.Fatalf("got %d %q, want 200 %q", w.Code, w.Body.String(), tt.want)
+			}
+		})
+	}
+}
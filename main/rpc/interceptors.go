@@ -0,0 +1,141 @@
+package rpc
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"golang-boilerplate/main/core"
+	"golang-boilerplate/main/rpc/pb"
+	"golang-boilerplate/pkg/metrics"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/ext"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// publicMethods can be called without a bearer token, like the public HTTP routes
+var publicMethods = map[string]bool{
+	pb.AuthService_Register_FullMethodName: true,
+	pb.AuthService_Login_FullMethodName:    true,
+	"/grpc.health.v1.Health/Check":         true,
+	"/grpc.health.v1.Health/Watch":         true,
+}
+
+// authenticate is the gRPC equivalent of AuthMiddleware: it verifies the
+// bearer token in the "authorization" metadata and stores the user ID
+func authenticate(ctx context.Context, auth *core.AuthService, method string) (context.Context, error) {
+	if publicMethods[method] || strings.HasPrefix(method, "/grpc.reflection.") {
+		return ctx, nil
+	}
+
+	md, _ := metadata.FromIncomingContext(ctx)
+	values := md.Get("authorization")
+	if len(values) == 0 {
+		return nil, status.Error(codes.Unauthenticated, "authorization metadata required")
+	}
+
+	tokenString := strings.TrimPrefix(values[0], "Bearer ")
+	if tokenString == values[0] {
+		return nil, status.Error(codes.Unauthenticated, "bearer token required")
+	}
+
+	userID, err := auth.ParseToken(tokenString)
+	if err != nil {
+		return nil, status.Error(codes.Unauthenticated, "invalid token")
+	}
+
+	return core.WithUserID(ctx, userID), nil
+}
+
+// startSpan continues the caller's trace from the request metadata
+func startSpan(ctx context.Context, tracer opentracing.Tracer, method string) (context.Context, opentracing.Span) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	carrier := opentracing.TextMapCarrier{}
+	for key, values := range md {
+		if len(values) > 0 {
+			carrier[key] = values[0]
+		}
+	}
+
+	spanCtx, _ := tracer.Extract(opentracing.TextMap, carrier)
+	span := tracer.StartSpan(method, ext.RPCServerOption(spanCtx))
+	ext.Component.Set(span, "grpc")
+	return opentracing.ContextWithSpan(ctx, span), span
+}
+
+// observe records metrics, logs and span status for a finished call
+func observe(ctx context.Context, logger *zap.Logger, span opentracing.Span, method string, start time.Time, err error) {
+	latency := time.Since(start)
+	code := status.Code(err)
+
+	metrics.GRPCRequestDuration.WithLabelValues(method, code.String()).Observe(latency.Seconds())
+	metrics.GRPCRequestTotal.WithLabelValues(method, code.String()).Inc()
+
+	span.SetTag("grpc.code", code.String())
+	if err != nil {
+		ext.Error.Set(span, true)
+	}
+
+	fields := []zap.Field{
+		zap.String("method", method),
+		zap.String("code", code.String()),
+		zap.Duration("latency", latency),
+	}
+	if p, ok := peer.FromContext(ctx); ok {
+		fields = append(fields, zap.String("ip", p.Addr.String()))
+	}
+	if err != nil {
+		fields = append(fields, zap.Error(err))
+	}
+	logger.Info("rpc completed", fields...)
+}
+
+// UnaryInterceptor traces, authenticates, logs and measures unary calls
+func UnaryInterceptor(auth *core.AuthService, tracer opentracing.Tracer, logger *zap.Logger) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp interface{}, err error) {
+		start := time.Now()
+		ctx, span := startSpan(ctx, tracer, info.FullMethod)
+		defer span.Finish()
+		defer func() { observe(ctx, logger, span, info.FullMethod, start, err) }()
+
+		authCtx, err := authenticate(ctx, auth, info.FullMethod)
+		if err != nil {
+			return nil, err
+		}
+
+		return handler(authCtx, req)
+	}
+}
+
+// StreamInterceptor is the streaming counterpart of UnaryInterceptor
+func StreamInterceptor(auth *core.AuthService, tracer opentracing.Tracer, logger *zap.Logger) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) (err error) {
+		start := time.Now()
+		ctx, span := startSpan(ss.Context(), tracer, info.FullMethod)
+		defer span.Finish()
+		defer func() { observe(ctx, logger, span, info.FullMethod, start, err) }()
+
+		authCtx, err := authenticate(ctx, auth, info.FullMethod)
+		if err != nil {
+			return err
+		}
+
+		return handler(srv, &contextStream{ServerStream: ss, ctx: authCtx})
+	}
+}
+
+// contextStream overrides the stream context with the authenticated one
+type contextStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *contextStream) Context() context.Context {
+	return s.ctx
+}
@@ -0,0 +1,52 @@
+package rpc
+
+import (
+	"golang-boilerplate/main/core"
+	"golang-boilerplate/main/rpc/pb"
+	"log"
+	"net"
+
+	"github.com/opentracing/opentracing-go"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/reflection"
+)
+
+// Server serves the gRPC API next to the HTTP server, sharing its service layer
+type Server struct {
+	server *grpc.Server
+	addr   string
+}
+
+// NewServer creates a server listening on addr
+func NewServer(addr string, auth *core.AuthService, users *core.UserService, tracer opentracing.Tracer, logger *zap.Logger) *Server {
+	server := grpc.NewServer(
+		grpc.ChainUnaryInterceptor(UnaryInterceptor(auth, tracer, logger)),
+		grpc.ChainStreamInterceptor(StreamInterceptor(auth, tracer, logger)),
+	)
+	pb.RegisterAuthServiceServer(server, &authServer{auth: auth, logger: logger})
+	pb.RegisterUserServiceServer(server, &userServer{users: users, logger: logger})
+	healthpb.RegisterHealthServer(server, health.NewServer())
+	reflection.Register(server)
+
+	return &Server{
+		server: server,
+		addr:   addr,
+	}
+}
+
+func (s *Server) Start() error {
+	listener, err := net.Listen("tcp", s.addr)
+	if err != nil {
+		return err
+	}
+
+	log.Printf("Starting gRPC server on %s", s.addr)
+	return s.server.Serve(listener)
+}
+
+func (s *Server) Shutdown() {
+	s.server.GracefulStop()
+}
@@ -0,0 +1,138 @@
+package rpc
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"golang-boilerplate/main/core"
+	"golang-boilerplate/main/i18n"
+	"golang-boilerplate/main/models"
+	"golang-boilerplate/main/rpc/pb"
+	"golang-boilerplate/main/validation"
+
+	"github.com/go-playground/validator/v10"
+	"go.uber.org/zap"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+type authServer struct {
+	pb.UnimplementedAuthServiceServer
+	auth   *core.AuthService
+	logger *zap.Logger
+}
+
+func (s *authServer) Register(ctx context.Context, req *pb.RegisterRequest) (*pb.RegisterResponse, error) {
+	user, err := s.auth.Register(ctx, core.RegisterInput{
+		Username: req.GetUsername(),
+		Email:    req.GetEmail(),
+		Password: req.GetPassword(),
+	})
+	if err != nil {
+		return nil, toStatus(ctx, s.logger, err)
+	}
+	return &pb.RegisterResponse{User: toProtoUser(user, user.ID)}, nil
+}
+
+func (s *authServer) Login(ctx context.Context, req *pb.LoginRequest) (*pb.LoginResponse, error) {
+	if req.GetUsername() == "" || req.GetPassword() == "" {
+		return nil, status.Error(codes.InvalidArgument, "username and password are required")
+	}
+
+	token, err := s.auth.Login(ctx, req.GetUsername(), req.GetPassword())
+	if err != nil {
+		return nil, toStatus(ctx, s.logger, err)
+	}
+	return &pb.LoginResponse{Token: token}, nil
+}
+
+type userServer struct {
+	pb.UnimplementedUserServiceServer
+	users  *core.UserService
+	logger *zap.Logger
+}
+
+func (s *userServer) GetMe(ctx context.Context, _ *pb.GetMeRequest) (*pb.GetMeResponse, error) {
+	userID, ok := core.UserIDFromContext(ctx)
+	if !ok {
+		return nil, status.Error(codes.Unauthenticated, "user not authenticated")
+	}
+
+	user, err := s.users.GetUser(ctx, userID)
+	if err != nil {
+		return nil, toStatus(ctx, s.logger, err)
+	}
+	return &pb.GetMeResponse{User: toProtoUser(user, userID)}, nil
+}
+
+func (s *userServer) GetUser(ctx context.Context, req *pb.GetUserRequest) (*pb.GetUserResponse, error) {
+	user, err := s.users.GetUser(ctx, uint(req.GetId()))
+	if err != nil {
+		return nil, toStatus(ctx, s.logger, err)
+	}
+	viewerID, _ := core.UserIDFromContext(ctx)
+	return &pb.GetUserResponse{User: toProtoUser(user, viewerID)}, nil
+}
+
+// toProtoUser converts a user for the viewer with the given ID. Like the
+// HTTP API, the email is only included for the user themselves.
+func toProtoUser(user *models.User, viewerID uint) *pb.User {
+	pbUser := &pb.User{
+		Id:        uint64(user.ID),
+		Username:  user.Username,
+		CreatedAt: user.CreatedAt.Unix(),
+		UpdatedAt: user.UpdatedAt.Unix(),
+	}
+	if viewerID == user.ID {
+		pbUser.Email = user.Email
+	}
+	return pbUser
+}
+
+// toStatus maps service errors onto gRPC status codes. Validation failures
+// carry the same field-level details the HTTP API returns.
+func toStatus(ctx context.Context, logger *zap.Logger, err error) error {
+	switch {
+	case errors.Is(err, core.ErrInvalidCredentials):
+		return status.Error(codes.Unauthenticated, "invalid credentials")
+	case errors.Is(err, core.ErrInvalidToken):
+		return status.Error(codes.Unauthenticated, "invalid token")
+	case errors.Is(err, core.ErrUserExists):
+		return status.Error(codes.AlreadyExists, "user already exists")
+	case errors.Is(err, core.ErrUserNotFound):
+		return status.Error(codes.NotFound, "user not found")
+	case errors.Is(err, context.DeadlineExceeded):
+		return status.Error(codes.DeadlineExceeded, "query timed out")
+	case errors.Is(err, context.Canceled):
+		return status.Error(codes.Canceled, "request canceled")
+	}
+
+	var validationErrs validator.ValidationErrors
+	if errors.As(err, &validationErrs) {
+		fieldErrs := validation.Errors(err, locale(ctx))
+		st := status.New(codes.InvalidArgument, "validation failed")
+		badRequest := &errdetails.BadRequest{}
+		for _, fe := range fieldErrs {
+			badRequest.FieldViolations = append(badRequest.FieldViolations, &errdetails.BadRequest_FieldViolation{
+				Field:       fe.Field,
+				Description: fe.Message,
+			})
+		}
+		if detailed, detailErr := st.WithDetails(badRequest); detailErr == nil {
+			st = detailed
+		}
+		return st.Err()
+	}
+
+	logger.Error("rpc failed", zap.Error(err))
+	return status.Error(codes.Internal, "internal error")
+}
+
+// locale negotiates the message language from "accept-language" metadata
+func locale(ctx context.Context) string {
+	md, _ := metadata.FromIncomingContext(ctx)
+	return i18n.Negotiate(strings.Join(md.Get("accept-language"), ","))
+}
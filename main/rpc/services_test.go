@@ -0,0 +1,66 @@
+package rpc
+
+import (
+	"context"
+	"testing"
+
+	"golang-boilerplate/main/core"
+	"golang-boilerplate/main/models"
+	"golang-boilerplate/main/repo"
+	"golang-boilerplate/main/rpc/pb"
+
+	"go.uber.org/zap"
+)
+
+func TestGetUserEmailOnlyForThemselves(t *testing.T) {
+	users := repo.NewMemoryUserRepo()
+	alice := &models.User{Username: "alice", Email: "alice@example.com", Role: models.RoleUser}
+	bob := &models.User{Username: "bob", Email: "bob@example.com", Role: models.RoleUser}
+	for _, user := range []*models.User{alice, bob} {
+		if err := users.CreateUser(t.Context(), user); err != nil {
+			t.Fatalf("CreateUser(%q): %v", user.Username, err)
+		}
+	}
+	s := &userServer{users: core.NewUserService(users, nil), logger: zap.NewNop()}
+
+	tests := []struct {
+		name      string
+		ctx       context.Context
+		wantEmail string
+	}{
+		{"Themselves", core.WithUserID(t.Context(), alice.ID), "alice@example.com"},
+		{"OtherUser", core.WithUserID(t.Context(), bob.ID), ""},
+		{"Anonymous", t.Context(), ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := s.GetUser(tt.ctx, &pb.GetUserRequest{Id: uint64(alice.ID)})
+			if err != nil {
+				t.Fatalf("GetUser: %v", err)
+			}
+			if resp.GetUser().GetUsername() != "alice" {
+				t.Fatalf("got user %q, want alice", resp.GetUser().GetUsername())
+			}
+			if got := resp.GetUser().GetEmail(); got != tt.wantEmail {
+				t.Fatalf("got email %q, want %q", got, tt.wantEmail)
+			}
+		})
+	}
+}
+
+func TestGetMeIncludesEmail(t *testing.T) {
+	users := repo.NewMemoryUserRepo()
+	alice := &models.User{Username: "alice", Email: "alice@example.com", Role: models.RoleUser}
+	if err := users.CreateUser(t.Context(), alice); err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	s := &userServer{users: core.NewUserService(users, nil), logger: zap.NewNop()}
+
+	resp, err := s.GetMe(core.WithUserID(t.Context(), alice.ID), &pb.GetMeRequest{})
+	if err != nil {
+		t.Fatalf("GetMe: %v", err)
+	}
+	if got := resp.GetUser().GetEmail(); got != "alice@example.com" {
+		t.Fatalf("got email %q, want alice@example.com", got)
+	}
+}
@@ -3,7 +3,8 @@ package server
 import (
 	"context"
 	"fmt"
-	"golang-boilerplate/main/config"
+	"golang-boilerplate/main/app"
+	"golang-boilerplate/main/module"
 	"golang-boilerplate/main/routes"
 	"log"
 	"net/http"
@@ -17,15 +18,17 @@ type Server struct {
 	server *http.Server
 }
 
-func NewServer() *Server {
+// NewServer creates the HTTP server for the services of a and the routes of
+// the enabled modules
+func NewServer(a *app.App, mods *module.Registry) *Server {
 	router := gin.Default()
-	routes.SetupRoutes(router)
+	handler := routes.SetupRoutes(router, a, mods)
 
 	return &Server{
 		router: router,
 		server: &http.Server{
-			Addr:    fmt.Sprintf("%s:%s", config.AppConfig.Server.Host, config.AppConfig.Server.Port),
-			Handler: router,
+			Addr:    fmt.Sprintf("%s:%s", a.Config.Server.Host, a.Config.Server.Port),
+			Handler: handler,
 		},
 	}
 }
@@ -0,0 +1,35 @@
+package service
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"golang-boilerplate/main/config"
+	"golang-boilerplate/pkg/blob"
+)
+
+// NewBlobStore creates the configured blob store. When storage has no
+// signing secret of its own, signed URLs use a key derived from jwtSecret,
+// so a leaked URL signature never helps forge a token or the other way
+// round. config validate requires the secret for the drivers that sign.
+func NewBlobStore(cfg config.StorageConfig, jwtSecret string) (blob.Store, error) {
+	secret := cfg.SigningSecret
+	if secret == "" {
+		mac := hmac.New(sha256.New, []byte(jwtSecret))
+		mac.Write([]byte("blob-url"))
+		secret = hex.EncodeToString(mac.Sum(nil))
+	}
+	signer := blob.NewSigner(secret, cfg.PublicURL)
+
+	switch cfg.Driver {
+	case "local":
+		return blob.NewLocalStore(cfg.LocalPath, signer)
+	case "s3":
+		return blob.NewS3Store(blob.S3Config(cfg.S3))
+	case "memory":
+		return blob.NewMemoryStore(signer), nil
+	default:
+		return nil, fmt.Errorf("unknown storage driver %q", cfg.Driver)
+	}
+}
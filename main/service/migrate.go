@@ -0,0 +1,229 @@
+package service
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/golang-migrate/migrate/v4"
+	"github.com/golang-migrate/migrate/v4/database/postgres"
+	"github.com/golang-migrate/migrate/v4/source/iofs"
+)
+
+// MigrationsDir is where the migrations live, relative to the working
+// directory of the server
+const MigrationsDir = "migrations"
+
+// migrationFile matches the up or down file of a migration, e.g.
+// 000001_create_users_table.up.sql
+var migrationFile = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+
+// migrationName is the form of the name given to a new migration
+var migrationName = regexp.MustCompile(`^\w+$`)
+
+// Migration is a migration and whether it has run
+type Migration struct {
+	Version uint
+	Name    string
+	Applied bool
+}
+
+// MigrationStatus is where a set of migrations stands
+type MigrationStatus struct {
+	Version    uint // 0 when none has run
+	Dirty      bool // the last migration failed halfway and needs a force
+	Migrations []Migration
+}
+
+// Migrator applies and rolls back one set of migrations
+type Migrator struct {
+	m      *migrate.Migrate
+	source fs.FS
+}
+
+// NewMigrator manages the core migrations in dir
+func NewMigrator(db *sql.DB, dir string) (*Migrator, error) {
+	return newMigrator(db, &postgres.Config{}, os.DirFS(dir))
+}
+
+// NewModuleMigrator manages the migrations a module brings along, tracked
+// in a table of the module's own so their versions never clash with the
+// core migrations or another module's
+func NewModuleMigrator(db *sql.DB, module string, migrations fs.FS) (*Migrator, error) {
+	return newMigrator(db, &postgres.Config{MigrationsTable: "schema_migrations_" + module}, migrations)
+}
+
+func newMigrator(db *sql.DB, cfg *postgres.Config, migrations fs.FS) (*Migrator, error) {
+	if db == nil {
+		return nil, fmt.Errorf("database connection not initialized")
+	}
+
+	driver, err := postgres.WithInstance(db, cfg)
+	if err != nil {
+		return nil, fmt.Errorf("could not create postgres driver: %w", err)
+	}
+
+	source, err := iofs.New(migrations, ".")
+	if err != nil {
+		return nil, fmt.Errorf("could not read migrations: %w", err)
+	}
+
+	m, err := migrate.NewWithInstance("iofs", source, "postgres", driver)
+	if err != nil {
+		return nil, fmt.Errorf("could not create migrate instance: %w", err)
+	}
+	return &Migrator{m: m, source: migrations}, nil
+}
+
+// Up applies the migrations that have not run yet
+func (m *Migrator) Up() error {
+	if err := m.m.Up(); err != nil && !errors.Is(err, migrate.ErrNoChange) {
+		return fmt.Errorf("could not run migrations: %w", err)
+	}
+	return nil
+}
+
+// Down rolls back the last steps migrations, or as many as have run if
+// that is fewer
+func (m *Migrator) Down(steps int) error {
+	if steps < 1 {
+		return fmt.Errorf("invalid number of steps %d", steps)
+	}
+	err := m.m.Steps(-steps)
+	var short migrate.ErrShortLimit
+	switch {
+	case err == nil, errors.Is(err, migrate.ErrNoChange), errors.As(err, &short):
+		return nil
+	case errors.Is(err, os.ErrNotExist):
+		// Nothing had run
+		return nil
+	}
+	return fmt.Errorf("could not roll back migrations: %w", err)
+}
+
+// Force records version as the current one without running anything, to
+// recover from a dirty migration once the schema has been fixed by hand. A
+// version of -1 records that no migration has run.
+func (m *Migrator) Force(version int) error {
+	if err := m.m.Force(version); err != nil {
+		return fmt.Errorf("could not force version %d: %w", version, err)
+	}
+	return nil
+}
+
+// Status reports the current version and every migration in the source
+func (m *Migrator) Status() (*MigrationStatus, error) {
+	version, dirty, err := m.m.Version()
+	if err != nil && !errors.Is(err, migrate.ErrNilVersion) {
+		return nil, fmt.Errorf("could not read migration version: %w", err)
+	}
+
+	migrations, err := listMigrations(m.source)
+	if err != nil {
+		return nil, err
+	}
+	for i := range migrations {
+		migrations[i].Applied = migrations[i].Version < version || migrations[i].Version == version && !dirty
+	}
+	return &MigrationStatus{Version: version, Dirty: dirty, Migrations: migrations}, nil
+}
+
+// listMigrations returns the migrations in fsys that have an up file, in
+// order
+func listMigrations(fsys fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, fmt.Errorf("could not read migrations: %w", err)
+	}
+
+	var migrations []Migration
+	for _, entry := range entries {
+		m := migrationFile.FindStringSubmatch(entry.Name())
+		if m == nil || m[3] != "up" {
+			continue
+		}
+		version, err := strconv.ParseUint(m[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid migration version in %s: %w", entry.Name(), err)
+		}
+		migrations = append(migrations, Migration{Version: uint(version), Name: m[2]})
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+// RunMigrations applies the migrations in dir that have not run yet
+func RunMigrations(db *sql.DB, dir string) error {
+	m, err := NewMigrator(db, dir)
+	if err != nil {
+		return err
+	}
+	return m.Up()
+}
+
+// RunModuleMigrations applies the migrations of a module that have not run
+// yet
+func RunModuleMigrations(db *sql.DB, module string, migrations fs.FS) error {
+	m, err := NewModuleMigrator(db, module, migrations)
+	if err != nil {
+		return err
+	}
+	return m.Up()
+}
+
+// NextMigrationVersion returns the version after the highest one in dir
+func NextMigrationVersion(dir string) (uint, error) {
+	migrations, err := listMigrations(os.DirFS(dir))
+	if err != nil {
+		return 0, err
+	}
+	if len(migrations) == 0 {
+		return 1, nil
+	}
+	return migrations[len(migrations)-1].Version + 1, nil
+}
+
+// CreateMigration writes empty up and down files for a new migration in
+// dir, numbered after the last one, and returns their paths
+func CreateMigration(dir, name string) (up, down string, err error) {
+	name = strings.ToLower(strings.Join(strings.Fields(name), "_"))
+	if !migrationName.MatchString(name) {
+		return "", "", fmt.Errorf("invalid migration name %q: use letters, digits and underscores", name)
+	}
+
+	version, err := NextMigrationVersion(dir)
+	if err != nil {
+		return "", "", err
+	}
+
+	base := filepath.Join(dir, fmt.Sprintf("%06d_%s", version, name))
+	up, down = base+".up.sql", base+".down.sql"
+	if err := createFile(up, "-- +migrate Up\n"); err != nil {
+		return "", "", err
+	}
+	if err := createFile(down, "-- +migrate Down\n"); err != nil {
+		os.Remove(up)
+		return "", "", err
+	}
+	return up, down, nil
+}
+
+// createFile writes a file that must not exist yet
+func createFile(path, content string) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+	if err != nil {
+		return err
+	}
+	_, err = f.WriteString(content)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
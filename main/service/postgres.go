@@ -2,69 +2,28 @@ package service
 
 import (
 	"database/sql"
-	"fmt"
 	"golang-boilerplate/main/config"
 	"time"
 
-	"github.com/golang-migrate/migrate/v4"
-	"github.com/golang-migrate/migrate/v4/database/postgres"
-	_ "github.com/golang-migrate/migrate/v4/source/file"
 	_ "github.com/lib/pq"
 )
 
-var DB *sql.DB
-
-func InitPostgres() error {
-	var err error
-	DB, err = sql.Open("postgres", config.AppConfig.Database.URL)
+// OpenPostgres connects to the database and checks that it answers
+func OpenPostgres(cfg config.DatabaseConfig) (*sql.DB, error) {
+	db, err := sql.Open("postgres", cfg.URL)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	// Set connection pool settings
-	DB.SetMaxOpenConns(25)
-	DB.SetMaxIdleConns(25)
-	DB.SetConnMaxLifetime(5 * time.Minute)
-
-	if err := DB.Ping(); err != nil {
-		return err
-	}
-
-	return nil
-}
-
-// RunMigrations runs database migrations
-func RunMigrations() error {
-	if DB == nil {
-		return fmt.Errorf("database connection not initialized")
-	}
-
-	driver, err := postgres.WithInstance(DB, &postgres.Config{})
-	if err != nil {
-		return fmt.Errorf("could not create postgres driver: %w", err)
-	}
-
-	m, err := migrate.NewWithDatabaseInstance(
-		"file://migrations",
-		"postgres",
-		driver,
-	)
-	if err != nil {
-		return fmt.Errorf("could not create migrate instance: %w", err)
-	}
+	db.SetMaxOpenConns(25)
+	db.SetMaxIdleConns(25)
+	db.SetConnMaxLifetime(5 * time.Minute)
 
-	if err := m.Up(); err != nil && err != migrate.ErrNoChange {
-		return fmt.Errorf("could not run migrations: %w", err)
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, err
 	}
 
-	return nil
-}
-
-func ClosePostgres() {
-	if DB != nil {
-		err := DB.Close()
-		if err != nil {
-			return
-		}
-	}
+	return db, nil
 }
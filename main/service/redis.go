@@ -6,23 +6,11 @@ import (
 	"github.com/redis/go-redis/v9"
 )
 
-var RedisClient *redis.Client
-
-func InitRedis() error {
-	RedisClient = redis.NewClient(&redis.Options{
-		Addr:     config.AppConfig.Redis.URL,
+// NewRedis creates a Redis client. It connects lazily, on first use.
+func NewRedis(cfg config.RedisConfig) *redis.Client {
+	return redis.NewClient(&redis.Options{
+		Addr:     cfg.URL,
 		Password: "", // no password set
 		DB:       0,  // use default DB
 	})
-
-	return nil
-}
-
-func CloseRedis() {
-	if RedisClient != nil {
-		err := RedisClient.Close()
-		if err != nil {
-			return
-		}
-	}
 }
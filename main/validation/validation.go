@@ -0,0 +1,214 @@
+package validation
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/mail"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"golang-boilerplate/main/i18n"
+
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+)
+
+// FieldError describes a single rule that a request field failed
+type FieldError struct {
+	Field   string `json:"field"`
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+// Limits enforced by the custom rules, exported so API docs can describe them
+const (
+	UsernameMinLength = 3
+	UsernameMaxLength = 32
+	UsernamePattern   = `^[a-zA-Z0-9][a-zA-Z0-9_.-]*$`
+	PasswordMinLength = 8
+	PasswordMaxLength = 72 // bcrypt ignores anything beyond 72 bytes
+	FlagKeyMaxLength  = 64
+	FlagKeyPattern    = `^[a-z0-9][a-z0-9_.-]*$`
+)
+
+var (
+	usernamePattern = regexp.MustCompile(UsernamePattern)
+	flagKeyPattern  = regexp.MustCompile(FlagKeyPattern)
+
+	reservedUsernames = map[string]struct{}{
+		"admin":         {},
+		"administrator": {},
+		"api":           {},
+		"me":            {},
+		"null":          {},
+		"root":          {},
+		"support":       {},
+		"system":        {},
+	}
+)
+
+func init() {
+	if err := Register(binding.Validator.Engine().(*validator.Validate)); err != nil {
+		panic(err)
+	}
+}
+
+// Register adds the custom rules to a validator engine. Gin uses the same
+// engine for JSON bodies, query strings and path params, so the rules work
+// with binding tags on any of them.
+func Register(v *validator.Validate) error {
+	v.RegisterTagNameFunc(fieldName)
+
+	rules := map[string]validator.Func{
+		"username":      validateUsername,
+		"email_address": validateEmail,
+		"password":      validatePassword,
+		"flag_key":      validateFlagKey,
+	}
+	for tag, fn := range rules {
+		if err := v.RegisterValidation(tag, fn); err != nil {
+			return fmt.Errorf("could not register %q validator: %w", tag, err)
+		}
+	}
+
+	return nil
+}
+
+// fieldName reports fields by the name the client used to send them
+func fieldName(field reflect.StructField) string {
+	for _, tag := range []string{"json", "form", "uri"} {
+		name := strings.SplitN(field.Tag.Get(tag), ",", 2)[0]
+		if name == "-" {
+			return ""
+		}
+		if name != "" {
+			return name
+		}
+	}
+	return field.Name
+}
+
+func validateUsername(fl validator.FieldLevel) bool {
+	username := fl.Field().String()
+	if len(username) < UsernameMinLength || len(username) > UsernameMaxLength {
+		return false
+	}
+	if !usernamePattern.MatchString(username) {
+		return false
+	}
+	_, reserved := reservedUsernames[strings.ToLower(username)]
+	return !reserved
+}
+
+func validateEmail(fl validator.FieldLevel) bool {
+	email := fl.Field().String()
+	addr, err := mail.ParseAddress(email)
+	if err != nil || addr.Address != email || addr.Name != "" {
+		return false
+	}
+
+	at := strings.LastIndex(email, "@")
+	domain := email[at+1:]
+	return at > 0 && strings.Contains(domain, ".") && !strings.HasSuffix(domain, ".")
+}
+
+func validatePassword(fl validator.FieldLevel) bool {
+	password := fl.Field().String()
+	if len(password) < PasswordMinLength || len(password) > PasswordMaxLength {
+		return false
+	}
+
+	var upper, lower, digit, symbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsDigit(r):
+			digit = true
+		case unicode.IsPunct(r) || unicode.IsSymbol(r):
+			symbol = true
+		}
+	}
+
+	// Require at least three of the four character classes
+	classes := 0
+	for _, present := range []bool{upper, lower, digit, symbol} {
+		if present {
+			classes++
+		}
+	}
+	return classes >= 3
+}
+
+func validateFlagKey(fl validator.FieldLevel) bool {
+	key := fl.Field().String()
+	return len(key) <= FlagKeyMaxLength && flagKeyPattern.MatchString(key)
+}
+
+// Errors converts a binding error into a list of field errors with messages
+// in the given locale. Errors that are not validation failures (malformed
+// JSON, wrong types) are reported too, so handlers can always respond with
+// the same shape.
+func Errors(err error, locale string) []FieldError {
+	if err == nil {
+		return nil
+	}
+
+	var validationErrs validator.ValidationErrors
+	if errors.As(err, &validationErrs) {
+		fieldErrs := make([]FieldError, 0, len(validationErrs))
+		for _, fe := range validationErrs {
+			fieldErrs = append(fieldErrs, NewFieldError(locale, fieldPath(fe), fe.Tag(), fe.Param()))
+		}
+		return fieldErrs
+	}
+
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) {
+		return []FieldError{NewFieldError(locale, typeErr.Field, "type", typeErr.Type.String())}
+	}
+
+	var numErr *strconv.NumError
+	if errors.As(err, &numErr) {
+		return []FieldError{NewFieldError(locale, "", "type", "number")}
+	}
+
+	return []FieldError{NewFieldError(locale, "", "malformed", "")}
+}
+
+// fieldPath drops the top-level struct name from the validator namespace,
+// so nested fields come back as "address.city" rather than "Request.address.city"
+func fieldPath(fe validator.FieldError) string {
+	namespace := fe.Namespace()
+	if i := strings.Index(namespace, "."); i >= 0 {
+		return namespace[i+1:]
+	}
+	return fe.Field()
+}
+
+// NewFieldError builds a field error with a message in the given locale
+func NewFieldError(locale, field, rule, param string) FieldError {
+	args := map[string]string{"rule": rule, "param": param}
+	switch rule {
+	case "username":
+		args["min"], args["max"] = strconv.Itoa(UsernameMinLength), strconv.Itoa(UsernameMaxLength)
+	case "password":
+		args["min"], args["max"] = strconv.Itoa(PasswordMinLength), strconv.Itoa(PasswordMaxLength)
+	case "flag_key":
+		args["max"] = strconv.Itoa(FlagKeyMaxLength)
+	}
+
+	code := "validation." + rule
+	message := i18n.T(locale, code, args)
+	if message == code {
+		message = i18n.T(locale, "validation.default", args)
+	}
+
+	return FieldError{Field: field, Rule: rule, Message: message}
+}
@@ -0,0 +1,235 @@
+package versioning
+
+import (
+	"encoding/json"
+	"mime"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang-boilerplate/main/i18n"
+	"golang-boilerplate/pkg/metrics"
+
+	"github.com/gin-gonic/gin"
+)
+
+// HeaderVersion names the version that served a response
+const HeaderVersion = "API-Version"
+
+// Version is one version of the API, mounted under its own path
+type Version struct {
+	Name string // e.g. "v1"; the Accept version parameter selects it with or without the "v"
+	Path string // e.g. "/api/v1"
+
+	// Deprecated is when the version was deprecated; zero while it is current
+	Deprecated time.Time
+	// Sunset is when the version will be removed; zero if not planned
+	Sunset time.Time
+	// Successor is the name of the version clients should move to
+	Successor string
+	// Policy is a URL documenting the deprecation
+	Policy string
+}
+
+// IsDeprecated reports whether the version is deprecated
+func (v *Version) IsDeprecated() bool {
+	return !v.Deprecated.IsZero()
+}
+
+// API mounts routes on every version that has them. Requests to
+// unversioned paths under the base path, i.e. outside every version's own
+// path, may pick a version with the version parameter of the Accept media
+// type, as in "Accept: application/json; version=1".
+type API struct {
+	engine   *gin.Engine
+	base     string
+	versions []*Version
+	groups   map[string]*gin.RouterGroup
+	clients  map[string]bool
+}
+
+// New creates a group per version. A version mounted at base itself serves
+// unversioned requests that do not ask for a version.
+func New(engine *gin.Engine, base string, versions ...*Version) *API {
+	api := &API{engine: engine, base: base, versions: versions, groups: make(map[string]*gin.RouterGroup)}
+	for _, v := range versions {
+		group := engine.Group(v.Path)
+		group.Use(api.versionMiddleware(v))
+		api.groups[v.Name] = group
+	}
+	return api
+}
+
+// CountClients sets the client names the deprecation metric reports by
+// name; every other client is reported as "other", which keeps the label's
+// cardinality bounded whatever callers send. Names are case-insensitive.
+func (a *API) CountClients(names ...string) {
+	a.clients = make(map[string]bool, len(names))
+	for _, name := range names {
+		a.clients[strings.ToLower(name)] = true
+	}
+}
+
+// Group returns the router group of a version. It panics for unknown
+// names, which are programming errors.
+func (a *API) Group(name string) *gin.RouterGroup {
+	group, ok := a.groups[name]
+	if !ok {
+		panic("versioning: unknown API version " + name)
+	}
+	return group
+}
+
+// Each registers the same routes on several versions, or on every version
+// when no names are given
+func (a *API) Each(register func(group *gin.RouterGroup, v *Version), names ...string) {
+	for _, v := range a.versions {
+		if len(names) == 0 || contains(names, v.Name) {
+			register(a.groups[v.Name], v)
+		}
+	}
+}
+
+// Handler serves the engine, first routing unversioned requests to the
+// version named in their Accept header
+func (a *API) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if a.unversioned(r.URL.Path) {
+			w.Header().Add("Vary", "Accept")
+
+			if name, ok := requestedVersion(r.Header.Get("Accept")); ok {
+				v := a.lookup(name)
+				if v == nil {
+					a.notAcceptable(w, r)
+					return
+				}
+				r.URL.Path = v.Path + strings.TrimPrefix(r.URL.Path, a.base)
+				r.URL.RawPath = ""
+			}
+		}
+		a.engine.ServeHTTP(w, r)
+	})
+}
+
+// unversioned reports whether the path is under the base path but not
+// under the path of a version other than one mounted at base
+func (a *API) unversioned(path string) bool {
+	if !hasPathPrefix(path, a.base) {
+		return false
+	}
+	for _, v := range a.versions {
+		if v.Path != a.base && hasPathPrefix(path, v.Path) {
+			return false
+		}
+	}
+	return true
+}
+
+func (a *API) lookup(name string) *Version {
+	for _, v := range a.versions {
+		if v.Name == name || v.Name == "v"+name {
+			return v
+		}
+	}
+	return nil
+}
+
+func (a *API) notAcceptable(w http.ResponseWriter, r *http.Request) {
+	var names []string
+	for _, v := range a.versions {
+		names = append(names, v.Name)
+	}
+	sort.Strings(names)
+
+	locale := i18n.Negotiate(r.Header.Get("Accept-Language"))
+	code := "api.unsupported_version"
+	body, _ := json.Marshal(gin.H{
+		"error": i18n.T(locale, code, map[string]string{"versions": strings.Join(names, ", ")}),
+		"code":  code,
+	})
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.Header().Set("Content-Language", locale)
+	w.WriteHeader(http.StatusNotAcceptable)
+	_, _ = w.Write(body)
+}
+
+// versionMiddleware names the version in every response and, once the
+// version is deprecated, adds the Deprecation (RFC 9745), Sunset (RFC 8594)
+// and Link headers and counts the request per client
+func (a *API) versionMiddleware(v *Version) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Header(HeaderVersion, v.Name)
+
+		if v.IsDeprecated() {
+			header := c.Writer.Header()
+			header.Set("Deprecation", "@"+strconv.FormatInt(v.Deprecated.Unix(), 10))
+			if !v.Sunset.IsZero() {
+				header.Set("Sunset", v.Sunset.UTC().Format(http.TimeFormat))
+			}
+			if successor := a.lookup(v.Successor); successor != nil {
+				path := successor.Path + strings.TrimPrefix(c.Request.URL.Path, v.Path)
+				header.Add("Link", "<"+path+`>; rel="successor-version"`)
+			}
+			if v.Policy != "" {
+				header.Add("Link", "<"+v.Policy+`>; rel="deprecation"; type="text/html"`)
+			}
+
+			metrics.DeprecatedAPIRequests.WithLabelValues(v.Name, c.FullPath(), a.clientName(c.Request)).Inc()
+		}
+
+		c.Next()
+	}
+}
+
+// requestedVersion returns the version parameter of the first Accept media
+// range that has one
+func requestedVersion(accept string) (string, bool) {
+	for _, mediaRange := range strings.Split(accept, ",") {
+		_, params, err := mime.ParseMediaType(strings.TrimSpace(mediaRange))
+		if err != nil {
+			continue
+		}
+		if version, ok := params["version"]; ok {
+			return version, true
+		}
+	}
+	return "", false
+}
+
+// clientName identifies the caller for metrics: the X-Client-Name header if
+// set, else the product of the User-Agent, e.g. "okhttp" for
+// "okhttp/4.12.0". Clients not given to CountClients are reported as
+// "other", and requests naming none as "unknown".
+func (a *API) clientName(r *http.Request) string {
+	name := r.Header.Get("X-Client-Name")
+	if name == "" {
+		name = r.UserAgent()
+		if i := strings.IndexAny(name, "/ "); i >= 0 {
+			name = name[:i]
+		}
+	}
+	if name == "" {
+		return "unknown"
+	}
+	name = strings.ToLower(name)
+	if !a.clients[name] {
+		return "other"
+	}
+	return name
+}
+
+func hasPathPrefix(path, prefix string) bool {
+	return path == prefix || strings.HasPrefix(path, strings.TrimSuffix(prefix, "/")+"/")
+}
+
+func contains(names []string, name string) bool {
+	for _, n := range names {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
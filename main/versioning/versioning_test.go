@@ -0,0 +1,39 @@
+package versioning
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestClientName(t *testing.T) {
+	api := &API{}
+	api.CountClients("web", "okhttp", "Go-http-client")
+
+	tests := []struct {
+		name       string
+		clientName string
+		userAgent  string
+		want       string
+	}{
+		{"Header", "web", "okhttp/4.12.0", "web"},
+		{"HeaderCase", "WEB", "", "web"},
+		{"UserAgentProduct", "", "okhttp/4.12.0", "okhttp"},
+		{"ConfiguredCase", "", "Go-http-client/1.1", "go-http-client"},
+		{"UnlistedHeader", "build-4711", "", "other"},
+		{"UnlistedUserAgent", "", "curl/8.5.0", "other"},
+		{"RandomPerRequest", "", "x7f3a9c2e/1.0", "other"},
+		{"None", "", "", "unknown"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/api/users", nil)
+			if tt.clientName != "" {
+				r.Header.Set("X-Client-Name", tt.clientName)
+			}
+			r.Header.Set("User-Agent", tt.userAgent)
+			if got := api.clientName(r); got != tt.want {
+				t.Fatalf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,328 @@
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"golang-boilerplate/main/config"
+	"golang-boilerplate/main/models"
+	"golang-boilerplate/main/repo"
+	"golang-boilerplate/pkg/async"
+	"golang-boilerplate/pkg/utils"
+
+	"go.uber.org/zap"
+)
+
+// Headers sent with every delivery. The signature header has the form
+// "t=<unix timestamp>,v1=<hex HMAC-SHA256 of "<timestamp>.<body>">", so
+// receivers can reject replays of old payloads.
+const (
+	HeaderEvent     = "X-Webhook-Event"
+	HeaderDelivery  = "X-Webhook-Delivery"
+	HeaderSignature = "X-Webhook-Signature"
+)
+
+// maxResponseBody bounds how much of a receiver's response is read
+const maxResponseBody = 4 << 10
+
+// ErrNotRunning is returned when events are published before Start or after Stop
+var ErrNotRunning = errors.New("webhook dispatcher is not running")
+
+// Event is the JSON body of a delivery
+type Event struct {
+	ID        string      `json:"id"`
+	Type      string      `json:"type"`
+	CreatedAt time.Time   `json:"created_at"`
+	Data      interface{} `json:"data"`
+}
+
+// Dispatcher fans events out to subscribed endpoints. Each delivery is
+// stored before it is attempted, every attempt is logged, and failed
+// attempts are retried with exponential backoff. Subscriptions that fail
+// too many deliveries in a row are disabled.
+type Dispatcher struct {
+	webhooks *repo.WebhookRepo
+	client   *http.Client
+	cfg      config.WebhooksConfig
+	logger   *zap.Logger
+
+	mu     sync.Mutex
+	pool   *async.WorkerPool
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+func NewDispatcher(webhooks *repo.WebhookRepo, cfg config.WebhooksConfig, logger *zap.Logger) *Dispatcher {
+	return &Dispatcher{
+		webhooks: webhooks,
+		cfg:      cfg,
+		logger:   logger,
+		client: &http.Client{
+			Timeout: cfg.Timeout,
+			// A redirect would send the signed payload somewhere the
+			// subscriber did not register
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+	}
+}
+
+// Start sizes the worker pool from config and begins delivering
+func (d *Dispatcher) Start() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.pool = async.NewWorkerPool(d.cfg.Workers)
+	d.pool.Start()
+	d.ctx, d.cancel = context.WithCancel(context.Background())
+}
+
+// Stop abandons retries in progress and stops the pool. Deliveries that were
+// interrupted stay pending and can be redelivered.
+func (d *Dispatcher) Stop() {
+	d.mu.Lock()
+	if d.cancel == nil {
+		d.mu.Unlock()
+		return
+	}
+	d.cancel()
+	d.cancel = nil
+	d.mu.Unlock()
+
+	d.wg.Wait()
+	d.pool.Stop()
+}
+
+// Publish records a delivery for every enabled subscription to eventType
+// and queues them. It returns once the deliveries are stored; sending
+// happens in the background.
+func (d *Dispatcher) Publish(ctx context.Context, eventType string, data interface{}) error {
+	return d.publish(ctx, Event{ID: newEventID(), Type: eventType, CreatedAt: time.Now().UTC(), Data: data})
+}
+
+// PublishOutbox publishes an event relayed from the outbox. The event ID is
+// derived from the message, so a message relayed twice is delivered once.
+func (d *Dispatcher) PublishOutbox(ctx context.Context, msg *models.OutboxMessage) error {
+	return d.publish(ctx, Event{
+		ID:        "evt_outbox_" + strconv.FormatInt(msg.ID, 10),
+		Type:      msg.EventType,
+		CreatedAt: msg.CreatedAt.UTC(),
+		Data:      msg.Payload,
+	})
+}
+
+func (d *Dispatcher) publish(ctx context.Context, event Event) error {
+	if !d.running() {
+		return ErrNotRunning
+	}
+
+	subs, err := d.webhooks.EnabledSubscriptionsFor(ctx, event.Type)
+	if err != nil {
+		return fmt.Errorf("could not load webhook subscriptions: %w", err)
+	}
+	if len(subs) == 0 {
+		return nil
+	}
+
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("could not encode webhook event: %w", err)
+	}
+
+	for _, sub := range subs {
+		delivery := &models.WebhookDelivery{
+			SubscriptionID: sub.ID,
+			EventID:        event.ID,
+			EventType:      event.Type,
+			Payload:        payload,
+			Status:         models.DeliveryPending,
+			CreatedAt:      event.CreatedAt,
+			UpdatedAt:      event.CreatedAt,
+		}
+		created, err := d.webhooks.CreateDelivery(ctx, delivery)
+		if err != nil {
+			return fmt.Errorf("could not store webhook delivery: %w", err)
+		}
+		if created {
+			d.enqueue(sub, delivery)
+		}
+	}
+	return nil
+}
+
+// Redeliver sends a stored delivery again with a fresh round of retries
+func (d *Dispatcher) Redeliver(ctx context.Context, deliveryID int64) (*models.WebhookDelivery, error) {
+	if !d.running() {
+		return nil, ErrNotRunning
+	}
+
+	delivery, err := d.webhooks.GetDelivery(ctx, deliveryID)
+	if err != nil {
+		return nil, err
+	}
+	sub, err := d.webhooks.GetSubscription(ctx, delivery.SubscriptionID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := d.webhooks.SetDeliveryStatus(ctx, delivery.ID, models.DeliveryPending); err != nil {
+		return nil, err
+	}
+	delivery.Status = models.DeliveryPending
+
+	d.enqueue(sub, delivery)
+	return delivery, nil
+}
+
+func (d *Dispatcher) running() bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.cancel != nil
+}
+
+// enqueue hands the delivery to the pool without blocking the caller
+func (d *Dispatcher) enqueue(sub *models.WebhookSubscription, delivery *models.WebhookDelivery) {
+	d.mu.Lock()
+	if d.cancel == nil {
+		d.mu.Unlock()
+		return
+	}
+	d.wg.Add(1)
+	d.mu.Unlock()
+
+	go func() {
+		defer d.wg.Done()
+		err := d.pool.SubmitWait(d.ctx, func(ctx context.Context) error {
+			d.deliver(ctx, sub, delivery)
+			return nil
+		})
+		if err != nil {
+			d.logger.Warn("webhook delivery not queued", zap.Int64("delivery_id", delivery.ID), zap.Error(err))
+		}
+	}()
+}
+
+// deliver attempts a delivery until it succeeds or retries run out, then
+// records the outcome on the delivery and the subscription
+func (d *Dispatcher) deliver(ctx context.Context, sub *models.WebhookSubscription, delivery *models.WebhookDelivery) {
+	cfg := d.cfg
+	retry := utils.RetryConfig{
+		MaxAttempts:  cfg.MaxAttempts,
+		InitialDelay: cfg.InitialBackoff,
+		MaxDelay:     cfg.MaxBackoff,
+		Multiplier:   2.0,
+		Logger:       d.logger,
+	}
+
+	err := utils.Retry(ctx, retry, func() error {
+		return d.attempt(ctx, sub, delivery)
+	})
+	if errors.Is(err, context.Canceled) {
+		// Shutting down; leave the delivery pending
+		return
+	}
+
+	status := models.DeliverySucceeded
+	if err != nil {
+		status = models.DeliveryFailed
+	}
+	if err := d.webhooks.SetDeliveryStatus(ctx, delivery.ID, status); err != nil {
+		d.logger.Error("could not update webhook delivery", zap.Int64("delivery_id", delivery.ID), zap.Error(err))
+	}
+
+	disabled, err := d.webhooks.RecordSubscriptionResult(ctx, sub.ID, status == models.DeliverySucceeded, cfg.DisableAfter)
+	if err != nil {
+		d.logger.Error("could not update webhook subscription", zap.Uint("subscription_id", sub.ID), zap.Error(err))
+	}
+	if disabled {
+		d.logger.Warn("webhook subscription disabled after repeated failures",
+			zap.Uint("subscription_id", sub.ID), zap.String("url", sub.URL))
+	}
+}
+
+// attempt sends the delivery once and logs the attempt
+func (d *Dispatcher) attempt(ctx context.Context, sub *models.WebhookSubscription, delivery *models.WebhookDelivery) error {
+	started := time.Now()
+	statusCode, err := d.send(ctx, sub, delivery)
+
+	attempt := &models.WebhookAttempt{
+		DeliveryID: delivery.ID,
+		DurationMS: int(time.Since(started) / time.Millisecond),
+		CreatedAt:  started,
+	}
+	if statusCode != 0 {
+		attempt.StatusCode = &statusCode
+	}
+	if err != nil {
+		attempt.Error = err.Error()
+	}
+	// Record the attempt even if shutdown interrupted it
+	if recordErr := d.webhooks.RecordAttempt(context.WithoutCancel(ctx), attempt); recordErr != nil {
+		d.logger.Error("could not record webhook attempt", zap.Int64("delivery_id", delivery.ID), zap.Error(recordErr))
+	}
+
+	return err
+}
+
+func (d *Dispatcher) send(ctx context.Context, sub *models.WebhookSubscription, delivery *models.WebhookDelivery) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, sub.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return 0, err
+	}
+
+	timestamp := time.Now().Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "golang-boilerplate-webhooks/1.0")
+	req.Header.Set(HeaderEvent, delivery.EventType)
+	req.Header.Set(HeaderDelivery, delivery.EventID)
+	req.Header.Set(HeaderSignature, Sign(sub.Secret, timestamp, delivery.Payload))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, maxResponseBody))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("endpoint responded %s", resp.Status)
+	}
+	return resp.StatusCode, nil
+}
+
+// Sign computes the signature header for a payload sent at timestamp
+func Sign(secret string, timestamp int64, payload []byte) string {
+	ts := strconv.FormatInt(timestamp, 10)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(ts))
+	mac.Write([]byte{'.'})
+	mac.Write(payload)
+	return "t=" + ts + ",v1=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// NewSecret generates a signing secret for a subscription
+func NewSecret() string {
+	b := make([]byte, 32)
+	_, _ = rand.Read(b)
+	return "whsec_" + hex.EncodeToString(b)
+}
+
+func newEventID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return "evt_" + hex.EncodeToString(b)
+}
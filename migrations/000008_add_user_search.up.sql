@@ -0,0 +1,15 @@
+-- +migrate Up
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+-- Usernames and emails are split on '@', '.', '_' and '-' so that each part
+-- can be found on its own, e.g. "jane" in "jane.doe@example.com"
+ALTER TABLE users ADD COLUMN IF NOT EXISTS search_vector tsvector GENERATED ALWAYS AS (
+    setweight(to_tsvector('simple', regexp_replace(username, '[@._-]+', ' ', 'g')), 'A') ||
+    setweight(to_tsvector('simple', regexp_replace(COALESCE(email, ''), '[@._-]+', ' ', 'g')), 'B')
+) STORED;
+
+CREATE INDEX IF NOT EXISTS idx_users_search_vector ON users USING GIN (search_vector);
+
+-- Trigram indexes serve the fuzzy fallback and substring matches
+CREATE INDEX IF NOT EXISTS idx_users_username_trgm ON users USING GIN (username gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS idx_users_email_trgm ON users USING GIN (email gin_trgm_ops);
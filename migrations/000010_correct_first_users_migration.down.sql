@@ -0,0 +1,3 @@
+-- +migrate Down
+-- The corrected schema is the one 000001 creates now, so there is nothing
+-- to undo
@@ -0,0 +1,15 @@
+-- +migrate Up
+-- Databases that ran 000001 before it was corrected have password_hash
+-- instead of password and a required email. Fresh ones are left as they are.
+DO $$
+BEGIN
+    IF EXISTS (SELECT 1 FROM information_schema.columns
+               WHERE table_schema = current_schema() AND table_name = 'users' AND column_name = 'password_hash')
+       AND NOT EXISTS (SELECT 1 FROM information_schema.columns
+               WHERE table_schema = current_schema() AND table_name = 'users' AND column_name = 'password') THEN
+        ALTER TABLE users RENAME COLUMN password_hash TO password;
+    END IF;
+END $$;
+
+ALTER TABLE users ALTER COLUMN email DROP NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_users_username ON users(username);
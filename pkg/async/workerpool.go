@@ -45,8 +45,11 @@ func (wp *WorkerPool) worker(id int) {
 
 	for {
 		select {
-		case task := <-wp.taskQueue:
-			wp.logger.Info("worker executing task", zap.Int("worker_id", id))
+		case task, ok := <-wp.taskQueue:
+			if !ok {
+				return
+			}
+			wp.logger.Debug("worker executing task", zap.Int("worker_id", id))
 			if err := task(wp.ctx); err != nil {
 				wp.logger.Error("task execution failed",
 					zap.Int("worker_id", id),
@@ -68,6 +71,20 @@ func (wp *WorkerPool) Submit(task Task) {
 	}
 }
 
+// SubmitWait queues a task, waiting for room in the queue instead of
+// dropping it. It fails if ctx is cancelled or the pool is stopping; it must
+// not be called once Stop has returned.
+func (wp *WorkerPool) SubmitWait(ctx context.Context, task Task) error {
+	select {
+	case wp.taskQueue <- task:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-wp.ctx.Done():
+		return wp.ctx.Err()
+	}
+}
+
 func (wp *WorkerPool) Stop() {
 	wp.cancel()
 	close(wp.taskQueue)
@@ -0,0 +1,50 @@
+package blob
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"time"
+)
+
+// ErrNotFound is returned when no object exists under a key
+var ErrNotFound = errors.New("blob not found")
+
+// Info describes a stored object
+type Info struct {
+	Key         string
+	Size        int64
+	ContentType string
+}
+
+// Store keeps binary objects under slash-separated keys such as
+// "avatars/42/abc.jpg"
+type Store interface {
+	// Put stores size bytes from r under key, replacing any existing object
+	Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error
+	// Get opens the object for reading; the caller must close it
+	Get(ctx context.Context, key string) (io.ReadCloser, *Info, error)
+	// Delete removes the object. Deleting a missing key is not an error.
+	Delete(ctx context.Context, key string) error
+	// SignedURL returns a URL that downloads the object until ttl has passed
+	SignedURL(ctx context.Context, key string, ttl time.Duration) (string, error)
+}
+
+// ErrInvalidKey is returned for keys that are empty, absolute or contain
+// empty, "." or ".." segments
+var ErrInvalidKey = errors.New("invalid blob key")
+
+// ValidateKey checks that a key is a clean relative path, so it cannot
+// escape the root of a filesystem store
+func ValidateKey(key string) error {
+	if key == "" || strings.ContainsRune(key, '\\') {
+		return ErrInvalidKey
+	}
+	for _, segment := range strings.Split(key, "/") {
+		if segment == "" || segment == "." || segment == ".." {
+			return ErrInvalidKey
+		}
+	}
+	return nil
+}
@@ -0,0 +1,68 @@
+package blob
+
+import (
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// params splits a signed URL into its key, expires and signature
+func params(t *testing.T, signed string) (key, expires, signature string) {
+	t.Helper()
+	u, err := url.Parse(signed)
+	if err != nil {
+		t.Fatalf("could not parse %q: %v", signed, err)
+	}
+	return strings.TrimPrefix(u.Path, "/blobs/"), u.Query().Get("expires"), u.Query().Get("signature")
+}
+
+func TestSigner(t *testing.T) {
+	signer := NewSigner("secret", "/blobs/")
+
+	signed := signer.URL("avatars/1/a b.png", time.Minute)
+	if !strings.HasPrefix(signed, "/blobs/avatars/1/a%20b.png?") {
+		t.Fatalf("got %q, want the escaped key below the base URL", signed)
+	}
+	key, expires, signature := params(t, signed)
+	tampered := []byte(signature)
+	tampered[0] ^= 1
+
+	tests := []struct {
+		name                    string
+		key, expires, signature string
+		signer                  *Signer
+		want                    bool
+	}{
+		{"Valid", key, expires, signature, signer, true},
+		{"OtherKey", "avatars/2/a b.png", expires, signature, signer, false},
+		{"ExtendedExpiry", key, expires + "0", signature, signer, false},
+		{"TamperedSignature", key, expires, string(tampered), signer, false},
+		{"MissingSignature", key, expires, "", signer, false},
+		{"MalformedExpiry", key, "soon", signature, signer, false},
+		{"OtherSecret", key, expires, signature, NewSigner("other", "/blobs"), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.signer.Verify(tt.key, tt.expires, tt.signature); got != tt.want {
+				t.Fatalf("Verify: got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSignerExpiry(t *testing.T) {
+	signer := NewSigner("secret", "/blobs")
+
+	key, expires, signature := params(t, signer.URL("a.png", -time.Second))
+	if signer.Verify(key, expires, signature) {
+		t.Fatal("expired URL verified")
+	}
+
+	// A correctly signed URL whose expiry has passed is refused too
+	past := time.Now().Add(-time.Minute).Unix()
+	if signer.Verify("a.png", strconv.FormatInt(past, 10), signer.signature("a.png", past)) {
+		t.Fatal("URL signed with a past expiry verified")
+	}
+}
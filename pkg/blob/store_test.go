@@ -0,0 +1,111 @@
+package blob
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestStores(t *testing.T) {
+	stores := []struct {
+		name     string
+		newStore func(t *testing.T) Store
+	}{
+		{"Memory", func(t *testing.T) Store {
+			return NewMemoryStore(NewSigner("secret", "/blobs"))
+		}},
+		{"Local", func(t *testing.T) Store {
+			store, err := NewLocalStore(t.TempDir(), NewSigner("secret", "/blobs"))
+			if err != nil {
+				t.Fatalf("NewLocalStore: %v", err)
+			}
+			return store
+		}},
+	}
+	for _, st := range stores {
+		t.Run(st.name, func(t *testing.T) {
+			t.Run("PutGetDelete", func(t *testing.T) {
+				store := st.newStore(t)
+				put(t, store, "avatars/1/a.png", "first")
+				put(t, store, "avatars/1/a.png", "second")
+
+				if got := get(t, store, "avatars/1/a.png"); got != "second" {
+					t.Fatalf("got %q, want the replacing object", got)
+				}
+
+				if err := store.Delete(t.Context(), "avatars/1/a.png"); err != nil {
+					t.Fatalf("Delete: %v", err)
+				}
+				if _, _, err := store.Get(t.Context(), "avatars/1/a.png"); !errors.Is(err, ErrNotFound) {
+					t.Fatalf("Get after Delete: got error %v, want ErrNotFound", err)
+				}
+			})
+
+			t.Run("Info", func(t *testing.T) {
+				store := st.newStore(t)
+				put(t, store, "avatars/1/a.png", "12345")
+
+				r, info, err := store.Get(t.Context(), "avatars/1/a.png")
+				if err != nil {
+					t.Fatalf("Get: %v", err)
+				}
+				r.Close()
+				if info.Key != "avatars/1/a.png" || info.Size != 5 || info.ContentType != "image/png" {
+					t.Fatalf("got %+v, want the key, size 5 and image/png", info)
+				}
+			})
+
+			t.Run("DeleteMissing", func(t *testing.T) {
+				if err := st.newStore(t).Delete(t.Context(), "avatars/1/missing.png"); err != nil {
+					t.Fatalf("Delete: %v", err)
+				}
+			})
+
+			t.Run("InvalidKeys", func(t *testing.T) {
+				store := st.newStore(t)
+				for _, key := range []string{"", "/etc/passwd", "../outside", "a//b", "a/./b", `a\b`} {
+					err := store.Put(t.Context(), key, strings.NewReader("x"), 1, "text/plain")
+					if !errors.Is(err, ErrInvalidKey) {
+						t.Fatalf("Put(%q): got error %v, want ErrInvalidKey", key, err)
+					}
+				}
+			})
+		})
+	}
+}
+
+func TestLocalStoreShortWrite(t *testing.T) {
+	store, err := NewLocalStore(t.TempDir(), NewSigner("secret", "/blobs"))
+	if err != nil {
+		t.Fatalf("NewLocalStore: %v", err)
+	}
+
+	if err := store.Put(t.Context(), "a.txt", strings.NewReader("abc"), 10, "text/plain"); err == nil {
+		t.Fatal("Put of fewer bytes than announced succeeded")
+	}
+	if _, _, err := store.Get(t.Context(), "a.txt"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("got error %v, want the partial object discarded", err)
+	}
+}
+
+func put(t *testing.T, store Store, key, content string) {
+	t.Helper()
+	if err := store.Put(t.Context(), key, strings.NewReader(content), int64(len(content)), "image/png"); err != nil {
+		t.Fatalf("Put(%q): %v", key, err)
+	}
+}
+
+func get(t *testing.T, store Store, key string) string {
+	t.Helper()
+	r, _, err := store.Get(t.Context(), key)
+	if err != nil {
+		t.Fatalf("Get(%q): %v", key, err)
+	}
+	defer r.Close()
+	data, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatalf("could not read %q: %v", key, err)
+	}
+	return string(data)
+}
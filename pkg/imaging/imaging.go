@@ -0,0 +1,107 @@
+package imaging
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	_ "image/gif" // registers the GIF decoder
+	"image/jpeg"
+	"image/png"
+	"net/http"
+
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp" // registers the WebP decoder
+)
+
+var (
+	// ErrUnsupportedType is returned for data that is not a JPEG, PNG, GIF or WebP image
+	ErrUnsupportedType = errors.New("unsupported image type")
+	// ErrTooManyPixels is returned for images larger than the caller allows,
+	// before they are decoded into memory
+	ErrTooManyPixels = errors.New("image has too many pixels")
+)
+
+// allowedTypes are the sniffed content types Decode accepts
+var allowedTypes = map[string]bool{
+	"image/jpeg": true,
+	"image/png":  true,
+	"image/gif":  true,
+	"image/webp": true,
+}
+
+// DetectType sniffs the content type of data, ignoring whatever the client
+// claimed, and reports whether it is an image Decode accepts
+func DetectType(data []byte) (string, bool) {
+	contentType := http.DetectContentType(data)
+	return contentType, allowedTypes[contentType]
+}
+
+// Decode decodes an image after checking its type and that its dimensions
+// stay within maxPixels. Only the first frame of an animated GIF is used.
+func Decode(data []byte, maxPixels int) (image.Image, error) {
+	if _, ok := DetectType(data); !ok {
+		return nil, ErrUnsupportedType
+	}
+
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, ErrUnsupportedType
+	}
+	if cfg.Width <= 0 || cfg.Height <= 0 || cfg.Width*cfg.Height > maxPixels {
+		return nil, ErrTooManyPixels
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, ErrUnsupportedType
+	}
+	return img, nil
+}
+
+// Square crops the centre square of img and scales it down to size pixels
+// per side. Images smaller than size are cropped but not enlarged.
+func Square(img image.Image, size int) image.Image {
+	bounds := img.Bounds()
+	side := bounds.Dx()
+	if bounds.Dy() < side {
+		side = bounds.Dy()
+	}
+	if size > side {
+		size = side
+	}
+
+	x := bounds.Min.X + (bounds.Dx()-side)/2
+	y := bounds.Min.Y + (bounds.Dy()-side)/2
+	crop := image.Rect(x, y, x+side, y+side)
+
+	dst := image.NewRGBA(image.Rect(0, 0, size, size))
+	draw.CatmullRom.Scale(dst, dst.Bounds(), img, crop, draw.Src, nil)
+	return dst
+}
+
+// ContentTypeFor picks JPEG for fully opaque images and PNG for images with
+// transparency
+func ContentTypeFor(img image.Image) string {
+	if opaque, ok := img.(interface{ Opaque() bool }); ok && opaque.Opaque() {
+		return "image/jpeg"
+	}
+	return "image/png"
+}
+
+// Encode writes img as image/jpeg or image/png
+func Encode(img image.Image, contentType string) ([]byte, error) {
+	var buf bytes.Buffer
+	var err error
+	switch contentType {
+	case "image/jpeg":
+		err = jpeg.Encode(&buf, img, &jpeg.Options{Quality: 85})
+	case "image/png":
+		err = png.Encode(&buf, img)
+	default:
+		err = ErrUnsupportedType
+	}
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
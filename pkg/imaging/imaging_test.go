@@ -0,0 +1,121 @@
+package imaging
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"testing"
+)
+
+// newImage returns a w×h image, opaque unless alpha is below 255
+func newImage(w, h int, alpha uint8) *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			img.Set(x, y, color.NRGBA{R: uint8(x), G: uint8(y), B: 128, A: alpha})
+		}
+	}
+	return img
+}
+
+func encodePNG(t *testing.T, img image.Image) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatalf("could not encode PNG: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func TestDecode(t *testing.T) {
+	var jpg, gifData bytes.Buffer
+	if err := jpeg.Encode(&jpg, newImage(20, 10, 255), nil); err != nil {
+		t.Fatalf("could not encode JPEG: %v", err)
+	}
+	if err := gif.Encode(&gifData, newImage(20, 10, 255), nil); err != nil {
+		t.Fatalf("could not encode GIF: %v", err)
+	}
+	pngData := encodePNG(t, newImage(20, 10, 255))
+
+	tests := []struct {
+		name      string
+		data      []byte
+		maxPixels int
+		wantErr   error
+	}{
+		{"PNG", pngData, 200, nil},
+		{"JPEG", jpg.Bytes(), 200, nil},
+		{"GIF", gifData.Bytes(), 200, nil},
+		{"TooManyPixels", pngData, 199, ErrTooManyPixels},
+		{"Text", []byte("definitely not an image"), 200, ErrUnsupportedType},
+		{"SVG", []byte(`<svg xmlns="http://www.w3.org/2000/svg" width="20" height="10"/>`), 200, ErrUnsupportedType},
+		{"BMP", append([]byte("BM"), make([]byte, 64)...), 200, ErrUnsupportedType},
+		{"TruncatedPNG", pngData[:len(pngData)/2], 200, ErrUnsupportedType},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			img, err := Decode(tt.data, tt.maxPixels)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("got error %v, want %v", err, tt.wantErr)
+			}
+			if err == nil && (img.Bounds().Dx() != 20 || img.Bounds().Dy() != 10) {
+				t.Fatalf("got bounds %v, want 20x10", img.Bounds())
+			}
+		})
+	}
+}
+
+func TestSquare(t *testing.T) {
+	tests := []struct {
+		name       string
+		w, h, size int
+		want       int
+	}{
+		{"Landscape", 300, 200, 64, 64},
+		{"Portrait", 200, 300, 64, 64},
+		{"NotEnlarged", 40, 30, 64, 30},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Square(newImage(tt.w, tt.h, 255), tt.size).Bounds()
+			if got.Dx() != tt.want || got.Dy() != tt.want {
+				t.Fatalf("got %v, want %dx%[2]d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEncode(t *testing.T) {
+	for _, tt := range []struct {
+		name  string
+		alpha uint8
+		want  string
+	}{
+		{"Opaque", 255, "image/jpeg"},
+		{"Transparent", 100, "image/png"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			img := newImage(8, 8, tt.alpha)
+			contentType := ContentTypeFor(img)
+			if contentType != tt.want {
+				t.Fatalf("got content type %s, want %s", contentType, tt.want)
+			}
+
+			data, err := Encode(img, contentType)
+			if err != nil {
+				t.Fatalf("Encode: %v", err)
+			}
+			if got, ok := DetectType(data); !ok || got != tt.want {
+				t.Fatalf("encoded data sniffs as %s, want %s", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := Encode(newImage(8, 8, 255), "image/gif"); !errors.Is(err, ErrUnsupportedType) {
+		t.Fatalf("Encode as GIF: got error %v, want ErrUnsupportedType", err)
+	}
+}
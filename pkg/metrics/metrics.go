@@ -23,6 +23,46 @@ var (
 		[]string{"method", "endpoint", "status"},
 	)
 
+	DeprecatedAPIRequests = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "http_deprecated_requests_total",
+			Help: "Total number of requests to deprecated API versions",
+		},
+		[]string{"version", "endpoint", "client"},
+	)
+
+	GRPCRequestDuration = promauto.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "grpc_request_duration_seconds",
+			Help:    "Duration of gRPC requests in seconds",
+			Buckets: prometheus.DefBuckets,
+		},
+		[]string{"method", "code"},
+	)
+
+	GRPCRequestTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "grpc_requests_total",
+			Help: "Total number of gRPC requests",
+		},
+		[]string{"method", "code"},
+	)
+
+	RealtimeConnections = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "realtime_connections_active",
+			Help: "Number of open realtime connections",
+		},
+		[]string{"transport"},
+	)
+
+	RealtimeEventsDropped = promauto.NewCounter(
+		prometheus.CounterOpts{
+			Name: "realtime_events_dropped_total",
+			Help: "Total number of realtime events dropped for slow clients",
+		},
+	)
+
 	DatabaseConnections = promauto.NewGauge(
 		prometheus.GaugeOpts{
 			Name: "database_connections_active",
@@ -0,0 +1,44 @@
+package utils
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+)
+
+// WeakETag builds a weak entity tag from an opaque value
+func WeakETag(value string) string {
+	return `W/"` + value + `"`
+}
+
+// ContentETag builds a weak entity tag from a response body
+func ContentETag(body []byte) string {
+	sum := sha256.Sum256(body)
+	return WeakETag(hex.EncodeToString(sum[:16]))
+}
+
+// ETagMatches reports whether a conditional header (If-Match or
+// If-None-Match) lists the given tag. Tags are compared weakly, ignoring the
+// W/ prefix: the API only issues weak tags, so If-Match uses the same
+// comparison even though RFC 9110 asks for a strong one there.
+func ETagMatches(header, etag string) bool {
+	header = strings.TrimSpace(header)
+	if header == "" {
+		return false
+	}
+	if header == "*" {
+		return true
+	}
+
+	want := opaqueTag(etag)
+	for _, candidate := range strings.Split(header, ",") {
+		if opaqueTag(strings.TrimSpace(candidate)) == want {
+			return true
+		}
+	}
+	return false
+}
+
+func opaqueTag(etag string) string {
+	return strings.TrimPrefix(etag, "W/")
+}
@@ -0,0 +1,136 @@
+//go:build integration
+
+package tests
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"golang-boilerplate/main/config"
+	"golang-boilerplate/main/handlers"
+	"golang-boilerplate/main/models"
+)
+
+func TestRegister(t *testing.T) {
+	h := NewHarness(t)
+	client := h.NewClient()
+
+	t.Run("Created", func(t *testing.T) {
+		client.Post("/api/v1/register", NewAccount()).Expect(t, http.StatusCreated, nil)
+	})
+
+	t.Run("DuplicateUsername", func(t *testing.T) {
+		account := NewAccount()
+		client.Post("/api/v1/register", account).Expect(t, http.StatusCreated, nil)
+
+		account.Email = NewAccount().Email
+		client.Post("/api/v1/register", account).ExpectError(t, http.StatusConflict, "user.exists")
+	})
+
+	t.Run("InvalidFields", func(t *testing.T) {
+		account := NewAccount()
+		account.Password = "short"
+
+		var body handlers.ValidationErrorResponse
+		client.Post("/api/v1/register", account).Expect(t, http.StatusBadRequest, &body)
+		if len(body.Details) != 1 || body.Details[0].Field != "password" {
+			t.Fatalf("got field errors %+v, want one for password", body.Details)
+		}
+	})
+}
+
+func TestLogin(t *testing.T) {
+	h := NewHarness(t)
+	client := h.NewClient()
+
+	account := NewAccount()
+	client.Post("/api/v1/register", account).Expect(t, http.StatusCreated, nil)
+
+	t.Run("ValidCredentials", func(t *testing.T) {
+		var body handlers.TokenResponse
+		client.Post("/api/v1/login", handlers.LoginRequest{Username: account.Username, Password: account.Password}).
+			Expect(t, http.StatusOK, &body)
+		if body.Token == "" {
+			t.Fatal("got no token")
+		}
+	})
+
+	t.Run("WrongPassword", func(t *testing.T) {
+		client.Post("/api/v1/login", handlers.LoginRequest{Username: account.Username, Password: account.Password + "x"}).
+			ExpectError(t, http.StatusUnauthorized, "auth.invalid_credentials")
+	})
+
+	t.Run("UnknownUser", func(t *testing.T) {
+		client.Post("/api/v1/login", handlers.LoginRequest{Username: NewAccount().Username, Password: testPassword}).
+			ExpectError(t, http.StatusUnauthorized, "auth.invalid_credentials")
+	})
+}
+
+func TestProtected(t *testing.T) {
+	h := NewHarness(t)
+
+	t.Run("WithoutToken", func(t *testing.T) {
+		h.NewClient().Get("/api/v1/protected").Expect(t, http.StatusUnauthorized, nil)
+	})
+
+	t.Run("InvalidToken", func(t *testing.T) {
+		client := h.NewClient()
+		client.Token = "not-a-token"
+		client.Get("/api/v1/protected").Expect(t, http.StatusUnauthorized, nil)
+	})
+
+	t.Run("TokenFromLogin", func(t *testing.T) {
+		client := h.NewClient()
+		account := NewAccount()
+		client.Post("/api/v1/register", account).Expect(t, http.StatusCreated, nil)
+
+		var token handlers.TokenResponse
+		client.Post("/api/v1/login", handlers.LoginRequest{Username: account.Username, Password: account.Password}).
+			Expect(t, http.StatusOK, &token)
+		client.Token = token.Token
+
+		client.Get("/api/v1/protected").Expect(t, http.StatusOK, nil)
+	})
+
+	t.Run("UserID", func(t *testing.T) {
+		user := h.CreateUser(t, models.RoleUser)
+
+		var body handlers.ProtectedResponse
+		h.ClientFor(t, user).Get("/api/v1/protected").Expect(t, http.StatusOK, &body)
+		if id, ok := body.UserID.(float64); !ok || uint(id) != user.ID {
+			t.Fatalf("got user_id %v, want %d", body.UserID, user.ID)
+		}
+	})
+
+	t.Run("AdminRouteNeedsRole", func(t *testing.T) {
+		h.ClientFor(t, h.CreateUser(t, models.RoleUser)).Get("/api/v1/admin/flags").Expect(t, http.StatusForbidden, nil)
+		h.ClientFor(t, h.CreateUser(t, models.RoleAdmin)).Get("/api/v1/admin/flags").Expect(t, http.StatusOK, nil)
+	})
+}
+
+// TestRequestValidation checks that the spec validates a request only once
+// it is authenticated and authorized, so the status never reveals the
+// shape of a route to a caller who may not use it
+func TestRequestValidation(t *testing.T) {
+	h := NewHarness(t, func(cfg *config.Config) {
+		cfg.OpenAPI.ValidateRequests = true
+	})
+	invalid := map[string]interface{}{"email": "not-an-email"}
+
+	t.Run("WithoutToken", func(t *testing.T) {
+		h.NewClient().Do(http.MethodPatch, "/api/v1/users/1", invalid).Expect(t, http.StatusUnauthorized, nil)
+	})
+
+	t.Run("WithoutRole", func(t *testing.T) {
+		h.ClientFor(t, h.CreateUser(t, models.RoleUser)).
+			Do(http.MethodPut, "/api/v1/admin/flags/checkout", map[string]interface{}{"enabled": "yes"}).
+			Expect(t, http.StatusForbidden, nil)
+	})
+
+	t.Run("Authenticated", func(t *testing.T) {
+		user := h.CreateUser(t, models.RoleUser)
+		h.ClientFor(t, user).Do(http.MethodPatch, fmt.Sprintf("/api/v1/users/%d", user.ID), invalid).
+			ExpectError(t, http.StatusBadRequest, "validation.failed")
+	})
+}
@@ -0,0 +1,82 @@
+//go:build integration
+
+package tests
+
+import (
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"golang-boilerplate/main/core"
+	"golang-boilerplate/main/handlers"
+	"golang-boilerplate/main/models"
+)
+
+// testPassword satisfies the password policy
+const testPassword = "Corr3ct-horse"
+
+// accounts numbers the generated accounts so their usernames never collide
+var accounts atomic.Uint32
+
+// NewAccount returns the registration of a user nobody has taken yet
+func NewAccount() handlers.RegisterRequest {
+	n := accounts.Add(1)
+	return handlers.RegisterRequest{
+		Username: fmt.Sprintf("user%d", n),
+		Email:    fmt.Sprintf("user%d@example.com", n),
+		Password: testPassword,
+	}
+}
+
+// TestUser is a stored user along with its plain password
+type TestUser struct {
+	*models.User
+	Password string
+}
+
+// CreateUser stores a new user with role straight through the repository,
+// for scenarios about something other than registration
+func (h *Harness) CreateUser(t *testing.T, role string) *TestUser {
+	t.Helper()
+	return h.CreateTenantUser(t, role, "")
+}
+
+// CreateTenantUser is CreateUser for a user of tenant
+func (h *Harness) CreateTenantUser(t *testing.T, role, tenant string) *TestUser {
+	t.Helper()
+
+	account := NewAccount()
+	hashed, err := core.HashPassword(account.Password)
+	if err != nil {
+		t.Fatalf("could not hash password: %v", err)
+	}
+
+	now := time.Now()
+	user := &models.User{
+		Username:  account.Username,
+		Email:     account.Email,
+		Password:  hashed,
+		Role:      role,
+		Tenant:    tenant,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if err := h.App.Users.CreateUser(t.Context(), user); err != nil {
+		t.Fatalf("could not create user %s: %v", user.Username, err)
+	}
+	return &TestUser{User: user, Password: account.Password}
+}
+
+// ClientFor returns a client holding a token for user
+func (h *Harness) ClientFor(t *testing.T, user *TestUser) *Client {
+	t.Helper()
+
+	token, err := h.App.Auth.IssueToken(user.ID)
+	if err != nil {
+		t.Fatalf("could not issue token: %v", err)
+	}
+	client := h.NewClient()
+	client.Token = token
+	return client
+}
@@ -0,0 +1,95 @@
+//go:build integration
+
+package tests
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"golang-boilerplate/main/core"
+	"golang-boilerplate/main/flags"
+	"golang-boilerplate/main/handlers"
+	"golang-boilerplate/main/models"
+	"golang-boilerplate/main/negotiate"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestFlags(t *testing.T) {
+	h := NewHarness(t)
+	admin := h.ClientFor(t, h.CreateUser(t, models.RoleAdmin))
+
+	admin.Do(http.MethodPut, "/api/v1/admin/flags/beta", handlers.PutFlagRequest{
+		Kind:  string(flags.KindBoolean),
+		Rules: []handlers.FlagRuleRequest{{Attribute: flags.AttributeRole, Values: []string{models.RoleAdmin}, Enabled: true}},
+	}).Expect(t, http.StatusOK, nil)
+
+	t.Run("TenantRules", func(t *testing.T) {
+		// Rolled out to nobody but acme
+		admin := h.ClientFor(t, h.CreateUser(t, models.RoleAdmin))
+		admin.Do(http.MethodPut, "/api/v1/admin/flags/acme-only", handlers.PutFlagRequest{
+			Kind:    string(flags.KindPercentage),
+			Enabled: true,
+			Rules:   []handlers.FlagRuleRequest{{Attribute: flags.AttributeTenant, Values: []string{"acme"}, Enabled: true}},
+		}).Expect(t, http.StatusOK, nil)
+
+		var evaluation flags.Evaluation
+		admin.Do(http.MethodPost, "/api/v1/admin/flags/acme-only/evaluate", handlers.EvaluateFlagRequest{Tenant: "acme"}).
+			Expect(t, http.StatusOK, &evaluation)
+		if !evaluation.Enabled || evaluation.Reason != flags.ReasonRule {
+			t.Fatalf("got %+v, want the flag on by rule for acme", evaluation)
+		}
+
+		// The tenant of a request is the one on the caller's record, whatever
+		// the X-Tenant-ID header says
+		tests := []struct {
+			name   string
+			tenant string
+			header string
+			want   bool
+		}{
+			{"Claimed", "acme", "", true},
+			{"ClaimedDespiteHeader", "acme", "globex", true},
+			{"Spoofed", "globex", "acme", false},
+			{"SpoofedWithoutTenant", "", "acme", false},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				user := h.CreateTenantUser(t, models.RoleUser, tt.tenant)
+				req := httptest.NewRequest(http.MethodGet, "/", nil)
+				req = req.WithContext(core.WithUserID(req.Context(), user.ID))
+				if tt.header != "" {
+					req.Header.Set("X-Tenant-ID", tt.header)
+				}
+				c, _ := gin.CreateTestContext(httptest.NewRecorder())
+				c.Request = req
+
+				if got := h.App.Flags.Enabled(c, "acme-only"); got != tt.want {
+					t.Fatalf("got the flag enabled %v, want %v", got, tt.want)
+				}
+			})
+		}
+	})
+
+	t.Run("TenantHeaderIgnored", func(t *testing.T) {
+		var evaluation flags.Evaluation
+		admin.Do(http.MethodPost, "/api/v1/admin/flags/beta/evaluate", handlers.EvaluateFlagRequest{Role: models.RoleUser},
+			"X-Tenant-ID", "acme").Expect(t, http.StatusOK, &evaluation)
+		if evaluation.Enabled {
+			t.Fatalf("got %+v, want the flag off for a user", evaluation)
+		}
+	})
+
+	t.Run("Negotiated", func(t *testing.T) {
+		resp := admin.Do(http.MethodGet, "/api/v1/admin/flags/beta", nil, "Accept", negotiate.MIMEMsgPack)
+		resp.Expect(t, http.StatusOK, nil)
+		if got := resp.Header.Get("Content-Type"); !strings.HasPrefix(got, negotiate.MIMEMsgPack) {
+			t.Fatalf("got Content-Type %q, want %q", got, negotiate.MIMEMsgPack)
+		}
+
+		admin.Do(http.MethodPost, "/api/v1/admin/flags/beta/evaluate", handlers.EvaluateFlagRequest{},
+			"Accept", "application/xml").ExpectError(t, http.StatusNotAcceptable, "request.not_acceptable")
+	})
+}
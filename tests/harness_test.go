@@ -0,0 +1,218 @@
+//go:build integration
+
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"golang-boilerplate/main/app"
+	"golang-boilerplate/main/config"
+	"golang-boilerplate/main/module"
+	"golang-boilerplate/main/modules"
+	"golang-boilerplate/main/routes"
+	"golang-boilerplate/main/service"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest"
+)
+
+// testSecret signs the tokens of every harness
+const testSecret = "integration-test-secret"
+
+// Harness serves the routes of a fresh App and its modules from an httptest
+// server. The background workers are not started; scenarios that need one
+// start it on App themselves.
+type Harness struct {
+	App     *app.App
+	Modules *module.Registry
+	Server  *httptest.Server
+	Redis   *miniredis.Miniredis
+
+	// clients numbers the clients, which the rate limiter tells apart by address
+	clients atomic.Uint32
+}
+
+// NewHarness builds an App on an empty database and Redis and serves it
+// until the test ends. The configure functions may adjust the configuration
+// first.
+func NewHarness(t *testing.T, configure ...func(*config.Config)) *Harness {
+	t.Helper()
+
+	cfg, err := config.Load()
+	if err != nil {
+		t.Fatalf("could not load config: %v", err)
+	}
+	cfg.JWT.Secret = testSecret
+	cfg.Storage.Driver = "memory"
+	cfg.Outbox.Sink = "memory"
+	cfg.Database.QueryTimeout = 5 * time.Second
+	for _, fn := range configure {
+		fn(cfg)
+	}
+
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { rdb.Close() })
+
+	blobs, err := service.NewBlobStore(cfg.Storage, cfg.JWT.Secret)
+	if err != nil {
+		t.Fatalf("could not create blob store: %v", err)
+	}
+
+	if db != nil {
+		if _, err := db.Exec(`TRUNCATE users, outbox, webhook_subscriptions RESTART IDENTITY CASCADE`); err != nil {
+			t.Fatalf("could not reset database: %v", err)
+		}
+	}
+
+	logger := zaptest.NewLogger(t, zaptest.Level(zap.WarnLevel))
+	a := app.New(cfg, logger, db, rdb, blobs)
+
+	mods, err := newRegistry(cfg.Modules, a, logger)
+	if err != nil {
+		t.Fatalf("could not load modules: %v", err)
+	}
+
+	router := gin.New()
+	router.Use(gin.Recovery())
+	server := httptest.NewServer(routes.SetupRoutes(router, a, mods))
+	t.Cleanup(server.Close)
+
+	return &Harness{App: a, Modules: mods, Server: server, Redis: mr}
+}
+
+// newRegistry composes the modules of cmd/main.go. With a nil a the modules
+// only have their names and migrations.
+func newRegistry(cfg config.ModulesConfig, a *app.App, logger *zap.Logger) (*module.Registry, error) {
+	webhooks := modules.NewWebhooks(a)
+	return module.NewRegistry(cfg, logger,
+		modules.NewAuth(),
+		modules.NewUsers(a),
+		webhooks,
+		modules.NewFlags(a),
+		modules.NewRealtime(a),
+		modules.NewOutbox(a, webhooks),
+	)
+}
+
+// Client sends requests from its own address, with its token if it has one.
+// Like any client it may burst 5 requests, then 1 a second; scenarios making
+// more use several clients.
+type Client struct {
+	h     *Harness
+	addr  string
+	Token string
+}
+
+// NewClient returns an anonymous client
+func (h *Harness) NewClient() *Client {
+	n := h.clients.Add(1)
+	return &Client{h: h, addr: fmt.Sprintf("10.%d.%d.%d", n>>16&0xff, n>>8&0xff, n&0xff)}
+}
+
+// Response is a response read in full, or the error that prevented it
+type Response struct {
+	StatusCode int
+	Header     http.Header
+	Body       []byte
+	Err        error
+}
+
+// Do sends a request to path, with body encoded as JSON unless it is nil.
+// Each header is a name followed by its value.
+func (c *Client) Do(method, path string, body interface{}, header ...string) *Response {
+	var reader io.Reader
+	if body != nil {
+		encoded, err := json.Marshal(body)
+		if err != nil {
+			return &Response{Err: fmt.Errorf("could not encode request body: %w", err)}
+		}
+		reader = bytes.NewReader(encoded)
+	}
+
+	req, err := http.NewRequest(method, c.h.Server.URL+path, reader)
+	if err != nil {
+		return &Response{Err: err}
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	req.Header.Set("Accept", "application/json")
+	// The router trusts forwarded addresses, which is what the rate limiter keys on
+	req.Header.Set("X-Forwarded-For", c.addr)
+	if c.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.Token)
+	}
+	for i := 0; i+1 < len(header); i += 2 {
+		req.Header.Set(header[i], header[i+1])
+	}
+	return c.Send(req)
+}
+
+// Send sends a request built by the caller as it is
+func (c *Client) Send(req *http.Request) *Response {
+	resp, err := c.h.Server.Client().Do(req)
+	if err != nil {
+		return &Response{Err: err}
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return &Response{Err: fmt.Errorf("%s %s: could not read response: %w", req.Method, req.URL.Path, err)}
+	}
+	return &Response{StatusCode: resp.StatusCode, Header: resp.Header, Body: body}
+}
+
+// Get is Do for a GET request
+func (c *Client) Get(path string) *Response {
+	return c.Do(http.MethodGet, path, nil)
+}
+
+// Post is Do for a POST request
+func (c *Client) Post(path string, body interface{}) *Response {
+	return c.Do(http.MethodPost, path, body)
+}
+
+// Expect fails the test unless the response has the status, then decodes
+// the body into v if it is not nil
+func (r *Response) Expect(t *testing.T, status int, v interface{}) {
+	t.Helper()
+
+	if r.Err != nil {
+		t.Fatal(r.Err)
+	}
+	if r.StatusCode != status {
+		t.Fatalf("got status %d, want %d: %s", r.StatusCode, status, r.Body)
+	}
+	if v != nil {
+		if err := json.Unmarshal(r.Body, v); err != nil {
+			t.Fatalf("could not decode response %s: %v", r.Body, err)
+		}
+	}
+}
+
+// ExpectError fails the test unless the response is an error with the
+// status and code
+func (r *Response) ExpectError(t *testing.T, status int, code string) {
+	t.Helper()
+
+	var body struct {
+		Code string `json:"code"`
+	}
+	r.Expect(t, status, &body)
+	if body.Code != code {
+		t.Fatalf("got error code %q, want %q: %s", body.Code, code, r.Body)
+	}
+}
@@ -0,0 +1,58 @@
+//go:build integration
+
+package tests
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"golang-boilerplate/main/handlers"
+)
+
+func TestIdempotency(t *testing.T) {
+	h := NewHarness(t)
+
+	t.Run("Replayed", func(t *testing.T) {
+		client := h.NewClient()
+		account := NewAccount()
+		client.Do(http.MethodPost, "/api/v1/register", account, "Idempotency-Key", "register-1").
+			Expect(t, http.StatusCreated, nil)
+
+		resp := client.Do(http.MethodPost, "/api/v1/register", account, "Idempotency-Key", "register-1")
+		resp.Expect(t, http.StatusCreated, nil)
+		if resp.Header.Get("Idempotent-Replayed") != "true" {
+			t.Fatal("repeat was not replayed")
+		}
+	})
+
+	t.Run("AnonymousCallersApart", func(t *testing.T) {
+		h.NewClient().Do(http.MethodPost, "/api/v1/register", NewAccount(), "Idempotency-Key", "shared").
+			Expect(t, http.StatusCreated, nil)
+		// Another caller picking the same key is not told it is reused
+		h.NewClient().Do(http.MethodPost, "/api/v1/register", NewAccount(), "Idempotency-Key", "shared").
+			Expect(t, http.StatusCreated, nil)
+	})
+
+	t.Run("LoginNotStored", func(t *testing.T) {
+		client := h.NewClient()
+		account := NewAccount()
+		client.Post("/api/v1/register", account).Expect(t, http.StatusCreated, nil)
+
+		login := handlers.LoginRequest{Username: account.Username, Password: account.Password}
+		for range 2 {
+			resp := client.Do(http.MethodPost, "/api/v1/login", login, "Idempotency-Key", "login-1")
+			resp.Expect(t, http.StatusOK, nil)
+			if resp.Header.Get("Idempotent-Replayed") != "" {
+				t.Fatal("login response was replayed")
+			}
+		}
+		for _, key := range h.Redis.Keys() {
+			if strings.HasPrefix(key, "idempotency:") {
+				if value, _ := h.Redis.Get(key); strings.Contains(value, `"status":200`) {
+					t.Fatalf("login response stored under %s", key)
+				}
+			}
+		}
+	})
+}
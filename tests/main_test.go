@@ -0,0 +1,64 @@
+//go:build integration
+
+// Package tests holds end-to-end scenarios that drive the real router over
+// HTTP. Run them with make test-integration. They use Postgres when
+// TEST_DATABASE_URL points at a database they may wipe, and the in-memory
+// user repository otherwise; Redis is always an in-process stand-in.
+package tests
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"golang-boilerplate/main/config"
+	"golang-boilerplate/main/service"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// db is the shared test database, nil when the scenarios run in memory
+var db *sql.DB
+
+func TestMain(m *testing.M) {
+	gin.SetMode(gin.TestMode)
+
+	if url := os.Getenv("TEST_DATABASE_URL"); url != "" {
+		var err error
+		if db, err = openDatabase(url); err != nil {
+			fmt.Fprintf(os.Stderr, "could not prepare test database: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	code := m.Run()
+	if db != nil {
+		db.Close()
+	}
+	os.Exit(code)
+}
+
+// openDatabase connects to url and brings its schema up to date
+func openDatabase(url string) (*sql.DB, error) {
+	db, err := service.OpenPostgres(config.DatabaseConfig{URL: url})
+	if err != nil {
+		return nil, err
+	}
+	// Tests run in this directory, one below the migrations
+	if err := service.RunMigrations(db, filepath.Join("..", service.MigrationsDir)); err != nil {
+		db.Close()
+		return nil, err
+	}
+	mods, err := newRegistry(config.ModulesConfig{}, nil, zap.NewNop())
+	if err == nil {
+		err = mods.Migrate(db)
+	}
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	return db, nil
+}
@@ -0,0 +1,53 @@
+//go:build integration
+
+package tests
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"golang-boilerplate/main/models"
+)
+
+// TestNotAcceptable checks that a request whose Accept header allows no
+// format of the response is refused before the handler changes anything
+func TestNotAcceptable(t *testing.T) {
+	h := NewHarness(t)
+
+	t.Run("Register", func(t *testing.T) {
+		client := h.NewClient()
+		account := NewAccount()
+		client.Do(http.MethodPost, "/api/v1/register", account, "Accept", "application/xml").
+			ExpectError(t, http.StatusNotAcceptable, "request.not_acceptable")
+
+		if _, err := h.App.Users.GetUserByUsername(t.Context(), account.Username); err == nil {
+			t.Fatal("user was created")
+		}
+		client.Post("/api/v1/register", account).Expect(t, http.StatusCreated, nil)
+	})
+
+	t.Run("Update", func(t *testing.T) {
+		user := h.CreateUser(t, models.RoleUser)
+		client := h.ClientFor(t, user)
+		path := fmt.Sprintf("/api/v1/users/%d", user.ID)
+		etag := client.Get(path).Header.Get("ETag")
+
+		client.Do(http.MethodPatch, path, map[string]string{"username": user.Username + "x"},
+			"Accept", "application/xml", "If-Match", etag).
+			ExpectError(t, http.StatusNotAcceptable, "request.not_acceptable")
+
+		stored, err := h.App.Users.GetUserByID(t.Context(), user.ID)
+		if err != nil {
+			t.Fatalf("GetUserByID: %v", err)
+		}
+		if stored.Version != user.Version || stored.Username != user.Username {
+			t.Fatalf("got version %d and username %q, want the user unchanged", stored.Version, stored.Username)
+		}
+	})
+
+	t.Run("ErrorsStillJSON", func(t *testing.T) {
+		h.NewClient().Do(http.MethodGet, "/api/v1/users/1", nil, "Accept", "application/xml").
+			Expect(t, http.StatusUnauthorized, nil)
+	})
+}
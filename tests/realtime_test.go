@@ -0,0 +1,56 @@
+//go:build integration
+
+package tests
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	"golang-boilerplate/main/config"
+	"golang-boilerplate/main/models"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestWebSocketOrigin(t *testing.T) {
+	const listed = "https://app.example.com"
+	h := NewHarness(t, func(cfg *config.Config) {
+		cfg.CORS.AllowedOrigins = []string{"*", listed}
+	})
+
+	tests := []struct {
+		name   string
+		origin string
+		want   int
+	}{
+		{"NoOrigin", "", http.StatusSwitchingProtocols},
+		{"SameOrigin", h.Server.URL, http.StatusSwitchingProtocols},
+		{"Listed", listed, http.StatusSwitchingProtocols},
+		{"CrossSite", "https://evil.example.com", http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := h.ClientFor(t, h.CreateUser(t, models.RoleUser))
+
+			query := url.Values{"access_token": {client.Token}}
+			wsURL := "ws" + strings.TrimPrefix(h.Server.URL, "http") + "/api/v1/events/ws?" + query.Encode()
+			header := http.Header{"X-Forwarded-For": {client.addr}}
+			if tt.origin != "" {
+				header.Set("Origin", tt.origin)
+			}
+
+			conn, resp, err := websocket.DefaultDialer.Dial(wsURL, header)
+			if conn != nil {
+				conn.Close()
+			}
+			if resp == nil {
+				t.Fatalf("could not dial: %v", err)
+			}
+			if resp.StatusCode != tt.want {
+				t.Fatalf("got status %d, want %d", resp.StatusCode, tt.want)
+			}
+		})
+	}
+}
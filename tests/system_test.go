@@ -0,0 +1,74 @@
+//go:build integration
+
+package tests
+
+import (
+	"net/http"
+	"testing"
+
+	"golang-boilerplate/main/config"
+	"golang-boilerplate/main/handlers"
+	"golang-boilerplate/main/models"
+)
+
+func TestPing(t *testing.T) {
+	h := NewHarness(t)
+
+	var body handlers.MessageResponse
+	h.NewClient().Get("/api/v1/ping").Expect(t, http.StatusOK, &body)
+	if body.Message != "pong" {
+		t.Fatalf("got message %q, want pong", body.Message)
+	}
+}
+
+func TestHealth(t *testing.T) {
+	h := NewHarness(t)
+	client := h.NewClient()
+
+	wantDatabase := "not initialized"
+	if db != nil {
+		wantDatabase = "ok"
+	}
+
+	t.Run("Healthy", func(t *testing.T) {
+		var body handlers.HealthResponse
+		client.Get("/api/v1/health").Expect(t, http.StatusOK, &body)
+		if body.Status != "ok" || body.Checks["redis"] != "ok" || body.Checks["database"] != wantDatabase {
+			t.Fatalf("got %+v, want ok with redis ok and database %s", body, wantDatabase)
+		}
+	})
+
+	t.Run("RedisDown", func(t *testing.T) {
+		h.Redis.Close()
+
+		var body handlers.HealthResponse
+		client.Get("/api/v1/health").Expect(t, http.StatusServiceUnavailable, &body)
+		if body.Status != "degraded" || body.Checks["redis"] != "error" {
+			t.Fatalf("got %+v, want degraded with redis error", body)
+		}
+	})
+}
+
+func TestRateLimit(t *testing.T) {
+	h := NewHarness(t)
+	client := h.NewClient()
+
+	// A client may burst 5 requests, then 1 a second
+	for i := 0; i < 5; i++ {
+		client.Get("/api/v1/ping").Expect(t, http.StatusOK, nil)
+	}
+	client.Get("/api/v1/ping").ExpectError(t, http.StatusTooManyRequests, "rate_limit.exceeded")
+
+	// Other clients have limits of their own
+	h.NewClient().Get("/api/v1/ping").Expect(t, http.StatusOK, nil)
+}
+
+func TestDisabledModule(t *testing.T) {
+	h := NewHarness(t, func(cfg *config.Config) {
+		cfg.Modules.Disabled = []string{"webhooks"}
+	})
+	admin := h.ClientFor(t, h.CreateUser(t, models.RoleAdmin))
+
+	admin.Get("/api/v1/admin/webhooks").Expect(t, http.StatusNotFound, nil)
+	admin.Get("/api/v1/admin/flags").Expect(t, http.StatusOK, nil)
+}